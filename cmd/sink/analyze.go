@@ -4,19 +4,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/dwrtz/sink/internal/analyzer"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/gitinfo"
 	"github.com/dwrtz/sink/internal/processor"
 	"github.com/dwrtz/sink/internal/tokens"
 	"github.com/spf13/cobra"
 )
 
 type analyzeFlags struct {
-	format          string
-	filterPatterns  []string
-	excludePatterns []string
-	caseSensitive   bool
-	showTokens      bool
+	format           string
+	output           string
+	filterPatterns   []string
+	excludePatterns  []string
+	prunePatterns    []string
+	caseSensitive    bool
+	includeGenerated bool
+	noTests          bool
+	testsOnly        bool
+	showTokens       bool
+	top              int
+	largest          int
+	todos            bool
+	complexity       bool
+	complexityLimit  int
+	churn            int
+	churnDays        int
+	comments         bool
 }
 
 func newAnalyzeCmd() *cobra.Command {
@@ -34,9 +50,27 @@ func newAnalyzeCmd() *cobra.Command {
 			if cmd.Flags().Changed("exclude") {
 				cfg.ExcludePatterns = flags.excludePatterns
 			}
+			if cmd.Flags().Changed("prune") {
+				cfg.PrunePatterns = flags.prunePatterns
+			}
 			if cmd.Flags().Changed("case-sensitive") {
 				cfg.CaseSensitive = flags.caseSensitive
 			}
+			if cmd.Flags().Changed("include-generated") {
+				cfg.IncludeGenerated = flags.includeGenerated
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			if cmd.Flags().Changed("tests-only") {
+				cfg.TestsOnly = flags.testsOnly
+				if cfg.TestsOnly {
+					cfg.NoTests = false
+				}
+			}
 			if cmd.Flags().Changed("tokens") {
 				cfg.ShowTokens = flags.showTokens
 			}
@@ -56,13 +90,23 @@ func newAnalyzeCmd() *cobra.Command {
 				return fmt.Errorf("failed to get absolute path: %w", err)
 			}
 
+			reporter := generator.NewProgress(cfg)
+			reporter.Start()
+			defer reporter.Stop()
+
 			// Create file processor using the global config
 			fp, err := processor.NewFileProcessor(processor.Config{
-				RepoRoot:        absPath,
-				FilterPatterns:  cfg.FilterPatterns,
-				ExcludePatterns: cfg.ExcludePatterns,
-				CaseSensitive:   cfg.CaseSensitive,
-				SyntaxMap:       cfg.SyntaxMap,
+				RepoRoot:          absPath,
+				FilterPatterns:    cfg.FilterPatterns,
+				ExcludePatterns:   cfg.ExcludePatterns,
+				PrunePatterns:     cfg.PrunePatterns,
+				CaseSensitive:     cfg.CaseSensitive,
+				SyntaxMap:         cfg.SyntaxMap,
+				LanguageOverrides: cfg.LanguageOverrides,
+				IncludeGenerated:  cfg.IncludeGenerated,
+				NoTests:           cfg.NoTests,
+				TestsOnly:         cfg.TestsOnly,
+				Progress:          reporter,
 			})
 			if err != nil {
 				return fmt.Errorf("failed to create file processor: %w", err)
@@ -74,42 +118,101 @@ func newAnalyzeCmd() *cobra.Command {
 				return fmt.Errorf("failed to process files: %w", err)
 			}
 
-			// Convert FileInfo to paths for analyzer
-			var paths []string
-			for _, f := range files {
-				paths = append(paths, f.Path)
-			}
-
 			// Create and run analyzer
 			a := analyzer.New()
-			stats, err := a.Analyze(paths)
+			stats, err := a.Analyze(files)
 			if err != nil {
 				return fmt.Errorf("failed to analyze codebase: %w", err)
 			}
 
+			// Count tokens per file if enabled so the extension table can
+			// include token totals; the largest files report ranks by tokens too
+			if cfg.ShowTokens || flags.largest > 0 {
+				counter, err := tokens.NewCounter(cfg.TokenEncoding)
+				if err != nil {
+					return fmt.Errorf("failed to create token counter: %w", err)
+				}
+				reporter.CountingTokens(len(files))
+				for _, file := range files {
+					count, err := counter.Count(file.Content)
+					if err != nil {
+						return fmt.Errorf("failed to count tokens: %w", err)
+					}
+					a.AddTokens(stats, file.Path, count)
+					reporter.Counted(count)
+				}
+			}
+			reporter.Stop()
+
+			findings := a.DetectGenerated(files, stats)
+
+			if flags.output != "" {
+				if err := writeAnalysisCSV(a, stats, absPath, flags.output); err != nil {
+					return err
+				}
+			}
+
 			// Output results based on format
 			if flags.format == "flat" {
 				fmt.Println(a.FormatFlat(stats))
 			} else if flags.format == "tree" {
-				fmt.Println(a.FormatFlat(stats)) // TODO: implement a.FormatTree
+				fmt.Println(a.FormatTree(stats, absPath))
+			} else if flags.format == "json" {
+				out, err := a.FormatJSON(stats, absPath, findings)
+				if err != nil {
+					return fmt.Errorf("failed to format analysis: %w", err)
+				}
+				fmt.Println(out)
+				return nil
 			} else {
-				return fmt.Errorf("invalid format: %s (must be 'flat' or 'tree')", flags.format)
+				return fmt.Errorf("invalid format: %s (must be 'flat', 'tree' or 'json')", flags.format)
 			}
 
 			// Print extension list
 			fmt.Printf("\nExtensions: %s\n", a.GetExtensionList(stats))
 
-			// Add token counting if enabled
+			if languages := a.FormatLanguages(stats); languages != "" {
+				fmt.Printf("\n%s\n", languages)
+			}
+
+			// Report vendored and generated content with suggested excludes
+			if report := a.FormatGenerated(findings, stats); report != "" {
+				fmt.Printf("\n%s\n", report)
+			}
+
+			if flags.todos {
+				fmt.Printf("\n%s\n", a.FormatTodos(a.FindTodos(files)))
+			}
+
+			if flags.complexity {
+				fmt.Printf("\n%s\n", a.FormatComplexity(a.GoComplexity(files), flags.complexityLimit))
+			}
+
+			if flags.comments {
+				fmt.Printf("\n%s\n", a.FormatComments(a.CommentRatios(files)))
+			}
+
+			if flags.churn > 0 {
+				var since time.Time
+				if flags.churnDays > 0 {
+					since = time.Now().AddDate(0, 0, -flags.churnDays)
+				}
+				churn, err := gitinfo.FileChurn(absPath, since)
+				if err != nil {
+					return fmt.Errorf("failed to compute churn: %w", err)
+				}
+				fmt.Printf("\n%s\n", a.FormatChurn(churn, files, flags.churn))
+			}
+
+			if report := a.FormatLargest(stats, absPath, flags.largest); report != "" {
+				fmt.Printf("\n%s\n", report)
+			}
+
 			if cfg.ShowTokens {
-				totalTokens := 0
-				for _, file := range files {
-					tokens, err := countFileTokens(file.Content, cfg.TokenEncoding)
-					if err != nil {
-						return fmt.Errorf("failed to count tokens: %w", err)
-					}
-					totalTokens += tokens
+				if table := a.FormatTopFiles(stats, absPath, flags.top); table != "" {
+					fmt.Printf("\n%s\n", table)
 				}
-				fmt.Printf("\nTotal tokens in codebase: %d\n", totalTokens)
+				fmt.Printf("\nTotal tokens in codebase: %d\n", stats.TotalTokens)
 			}
 
 			return nil
@@ -117,20 +220,44 @@ func newAnalyzeCmd() *cobra.Command {
 	}
 
 	// Add flags bound to the local flags struct
-	cmd.Flags().StringVarP(&flags.format, "format", "f", "flat", "Output format (flat or tree)")
+	cmd.Flags().StringVarP(&flags.format, "format", "f", "flat", "Output format (flat, tree or json)")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Write per-directory and per-extension statistics to this CSV file")
 	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "i", nil, "Filter patterns to include files")
 	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().StringSliceVar(&flags.prunePatterns, "prune", nil, "Patterns of directories to skip entirely")
 	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.includeGenerated, "include-generated", false, "Include files marked linguist-generated or linguist-vendored in .gitattributes")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files (e.g. *_test.go, test_*.py, *.spec.ts, __tests__/)")
+	cmd.Flags().BoolVar(&flags.testsOnly, "tests-only", false, "Include only test files")
+	cmd.MarkFlagsMutuallyExclusive("no-tests", "tests-only")
 	cmd.Flags().BoolVar(&flags.showTokens, "tokens", false, "Show total token count")
+	cmd.Flags().BoolVar(&flags.todos, "todos", false, "List TODO, FIXME, HACK and XXX markers by file")
+	cmd.Flags().BoolVar(&flags.complexity, "complexity", false, "Report the cyclomatic complexity of Go functions")
+	cmd.Flags().IntVar(&flags.complexityLimit, "complexity-threshold", 10, "Flag Go functions whose complexity exceeds this value")
+	cmd.Flags().BoolVar(&flags.comments, "comments", false, "Report the share of comment lines by language and directory")
+	cmd.Flags().IntVar(&flags.churn, "churn", 0, "List the N files changed by the most commits")
+	cmd.Flags().IntVar(&flags.churnDays, "churn-days", 0, "Only count commits from the last N days for --churn (0 = all history)")
+	cmd.Flags().IntVar(&flags.largest, "largest", 0, "List the N largest files by bytes and by tokens")
+	cmd.Flags().IntVar(&flags.top, "top", 10, "Number of files to list by token count when --tokens is set (0 to disable)")
 
 	return cmd
 }
 
-// countFileTokens helper function to count tokens in a file
-func countFileTokens(content, encoding string) (int, error) {
-	counter, err := tokens.NewCounter(encoding)
+// writeAnalysisCSV writes the per-directory and per-extension statistics to a
+// CSV file
+func writeAnalysisCSV(a *analyzer.Analyzer, stats *analyzer.Stats, root, path string) error {
+	f, err := os.Create(path)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer f.Close()
+
+	if err := a.WriteCSV(f, stats, root); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
 	}
-	return counter.Count(content)
+	fmt.Fprintf(os.Stderr, "Statistics written to: %s\n", path)
+	return nil
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/llm"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/manifest"
+	"github.com/dwrtz/sink/internal/processor/messages"
+	"github.com/dwrtz/sink/internal/tokens"
+	"github.com/spf13/cobra"
+)
+
+type askFlags struct {
+	provider        string
+	model           string
+	filterPatterns  []string
+	excludePatterns []string
+	noTests         bool
+	maxTokens       int
+	answerTokens    int
+	systemPrompt    string
+	transcript      string
+}
+
+func newAskCmd() *cobra.Command {
+	flags := &askFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "ask question [path]",
+		Short: "Ask a model a question about the codebase",
+		Long: `Generate the context for path (the current directory by default), append the
+question and stream the model's answer to stdout. The API key is read from
+OPENAI_API_KEY or ANTHROPIC_API_KEY; OPENAI_BASE_URL and ANTHROPIC_BASE_URL
+point at compatible endpoints.`,
+		Args: cobra.RangeArgs(1, 2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("provider") {
+				cfg.Provider = flags.provider
+			}
+			if cmd.Flags().Changed("model") {
+				cfg.Model = flags.model
+			}
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			if cmd.Flags().Changed("max-tokens") {
+				cfg.MaxTokens = flags.maxTokens
+			}
+			if cmd.Flags().Changed("system-prompt") {
+				cfg.SystemPrompt = flags.systemPrompt
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			question := args[0]
+			path := "."
+			if len(args) > 1 {
+				path = args[1]
+			}
+
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			provider, err := llm.New(cfg.Provider)
+			if err != nil {
+				return err
+			}
+			model := askModel(cmd.Flags().Changed("model"))
+
+			// The context is a single markdown document, whatever outputs
+			// are configured for generate
+			askCfg := *cfg
+			askCfg.Outputs = nil
+			askCfg.Output = ""
+			askCfg.Format = ""
+			askCfg.SplitTokens = 0
+			askCfg.Changelog = false
+			// Count tokens the way the asked model does, when sink knows its
+			// encoding
+			if cfg.EncodingForModel == "" {
+				if encoding, err := tokens.EncodingForModel(model); err == nil {
+					askCfg.TokenEncoding = encoding
+				}
+			}
+			docs, err := generator.Generate(&askCfg, absPath)
+			if err != nil {
+				return fmt.Errorf("failed to generate context: %w", err)
+			}
+
+			system := cfg.SystemPrompt
+			if system == "" {
+				system = messages.DefaultSystemPrompt
+			}
+			prompt := docs[0].Content + "\n\n" + question
+			promptTokens, err := countPrompt(askCfg.TokenEncoding, system, prompt)
+			if err != nil {
+				return err
+			}
+			req := llm.Request{
+				Model:  model,
+				System: system,
+				Messages: []llm.Message{
+					{Role: "user", Content: prompt},
+				},
+				MaxTokens:    flags.answerTokens,
+				PromptTokens: promptTokens,
+			}
+			logging.Info("asking", "provider", cfg.Provider, "model", model, "files", docs[0].Files, "tokens", promptTokens)
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer cancel()
+
+			var answer strings.Builder
+			err = provider.Stream(ctx, req, func(text string) error {
+				answer.WriteString(text)
+				_, err := fmt.Fprint(os.Stdout, text)
+				return err
+			})
+			if answer.Len() > 0 {
+				fmt.Println()
+			}
+			if err != nil {
+				return err
+			}
+
+			if flags.transcript == "" {
+				return nil
+			}
+			return writeTranscript(flags.transcript, transcript{
+				Time:     time.Now(),
+				Provider: cfg.Provider,
+				Model:    model,
+				Path:     absPath,
+				Files:    docs[0].Files,
+				Tokens:   promptTokens,
+				Hash:     manifest.Hash(docs[0].Content),
+				Question: question,
+				Answer:   answer.String(),
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.provider, "provider", "openai", "Provider to ask ("+strings.Join(llm.Providers(), ", ")+")")
+	cmd.Flags().StringVar(&flags.model, "model", "", "Model to ask (defaults to the configured model, or the provider's default)")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files")
+	cmd.Flags().IntVar(&flags.maxTokens, "max-tokens", 0, "Drop or truncate the lowest-priority files so the context fits this many tokens")
+	cmd.Flags().IntVar(&flags.answerTokens, "answer-tokens", 0, "Most tokens the answer may have (0 = the provider's default)")
+	cmd.Flags().StringVar(&flags.systemPrompt, "system-prompt", "", "System message sent with the question")
+	cmd.Flags().StringVar(&flags.transcript, "save-transcript", "", "Save the question, a hash of the context and the answer to this markdown file")
+
+	return cmd
+}
+
+// askModel returns the model to ask: the one given with --model, the
+// configured model if the provider offers it, or the provider's default
+func askModel(explicit bool) string {
+	if explicit {
+		return cfg.Model
+	}
+	if pricing, err := tokens.LoadPricing(cfg.Pricing); err == nil && pricing.Has(cfg.Provider, cfg.Model) {
+		return cfg.Model
+	}
+	return llm.DefaultModels[cfg.Provider]
+}
+
+// countPrompt counts the tokens of the system prompt and question
+func countPrompt(encoding, system, prompt string) (int, error) {
+	counter, err := tokens.NewCounter(encoding)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token counter: %w", err)
+	}
+	count, err := counter.Count(system + "\n" + prompt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return count, nil
+}
+
+// transcript records what context produced an answer
+type transcript struct {
+	Time     time.Time
+	Provider string
+	Model    string
+	Path     string
+	Files    int
+	Tokens   int
+	Hash     string // SHA-256 of the context, without the question
+	Question string
+	Answer   string
+}
+
+// writeTranscript saves t as a markdown document at path
+func writeTranscript(path string, t transcript) error {
+	var b strings.Builder
+	b.WriteString("# sink ask transcript\n\n")
+	fmt.Fprintf(&b, "- Date: %s\n", t.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Provider: %s\n", t.Provider)
+	fmt.Fprintf(&b, "- Model: %s\n", t.Model)
+	fmt.Fprintf(&b, "- Path: %s\n", t.Path)
+	fmt.Fprintf(&b, "- Files: %d\n", t.Files)
+	fmt.Fprintf(&b, "- Prompt tokens: %d\n", t.Tokens)
+	fmt.Fprintf(&b, "- Context SHA-256: %s\n\n", t.Hash)
+	fmt.Fprintf(&b, "## Question\n\n%s\n\n", strings.TrimSpace(t.Question))
+	fmt.Fprintf(&b, "## Answer\n\n%s\n", strings.TrimSpace(t.Answer))
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create transcript directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	logging.Info("saved transcript", "path", path)
+	return nil
+}
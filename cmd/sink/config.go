@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and validate configuration",
+	}
+
+	cmd.AddCommand(newConfigValidateCmd())
+
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Validate configuration files",
+		Long: `Check every configuration layer for syntax errors and unknown keys, validate
+the merged settings, parse templates, and report patterns that match no files
+in the repository.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "."
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			// Validate path
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+
+			// Make path absolute
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			return printResults(doctor.Validate(absPath, cfgFile, cfg))
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwrtz/sink/internal/manifest"
+	"github.com/dwrtz/sink/internal/watcher"
+	"github.com/spf13/cobra"
+)
+
+// daemonEnv marks the detached watch process started by --daemon
+const daemonEnv = "SINK_WATCH_DAEMON"
+
+// pidFile and logFile return where a daemon watching root keeps its
+// process ID and output
+func pidFile(root string) string {
+	return filepath.Join(root, manifest.Dir, "watch.pid")
+}
+
+func logFile(root string) string {
+	return filepath.Join(root, manifest.Dir, "watch.log")
+}
+
+// startDaemon runs the current watch command again without --daemon as a
+// detached process logging to .sink/watch.log, and records its PID
+func startDaemon(root string) error {
+	if pid, err := readPID(root); err == nil && processAlive(pid) {
+		return fmt.Errorf("a watcher is already running for %s (pid %d); stop it with sink watch stop", root, pid)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find sink executable: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, manifest.Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", manifest.Dir, err)
+	}
+	log, err := os.OpenFile(logFile(root), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(executable, withoutDaemonFlag(os.Args[1:])...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start watcher: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	if err := os.WriteFile(pidFile(root), []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+
+	// Catch watchers that fail right away, such as on a bad flag
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		os.Remove(pidFile(root))
+		return fmt.Errorf("watcher exited during startup (%v); see %s", err, logFile(root))
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	fmt.Printf("Watching %s in the background (pid %d)\n", root, pid)
+	fmt.Printf("Logging to %s\n", logFile(root))
+	return nil
+}
+
+// withoutDaemonFlag removes --daemon from the command line arguments
+func withoutDaemonFlag(args []string) []string {
+	var kept []string
+	for _, arg := range args {
+		if arg == "--daemon" || strings.HasPrefix(arg, "--daemon=") {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+// readPID returns the PID recorded for a daemon watching root
+func readPID(root string) (int, error) {
+	data, err := os.ReadFile(pidFile(root))
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID file %s: %w", pidFile(root), err)
+	}
+	return pid, nil
+}
+
+// removeOwnPIDFile removes the PID file when it still names this process,
+// so a daemon exiting on its own doesn't leave it behind
+func removeOwnPIDFile(root string) {
+	if pid, err := readPID(root); err == nil && pid == os.Getpid() {
+		os.Remove(pidFile(root))
+	}
+}
+
+func newWatchStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop [path]",
+		Short: "Stop a watcher started with --daemon",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := daemonRoot(args)
+			if err != nil {
+				return err
+			}
+			pid, err := readPID(root)
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Printf("No watcher running for %s\n", root)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !processAlive(pid) {
+				os.Remove(pidFile(root))
+				fmt.Printf("No watcher running for %s (removed stale PID file)\n", root)
+				return nil
+			}
+
+			if err := stopProcess(pid); err != nil {
+				return fmt.Errorf("failed to stop watcher (pid %d): %w", pid, err)
+			}
+			deadline := time.Now().Add(10 * time.Second)
+			for processAlive(pid) {
+				if time.Now().After(deadline) {
+					return fmt.Errorf("watcher (pid %d) did not exit within 10s", pid)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			os.Remove(pidFile(root))
+			fmt.Printf("Stopped watcher for %s (pid %d)\n", root, pid)
+			return nil
+		},
+	}
+}
+
+func newWatchStatusCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "status [path]",
+		Short: "Show whether a watcher is running and how its last regeneration went",
+		Long: `Show whether a watcher is running and the outcome of its last
+regeneration, read from the .sink/watch-status.json file every watcher
+writes. Works for watchers started with --daemon and in a terminal.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := daemonRoot(args)
+			if err != nil {
+				return err
+			}
+
+			status, err := watcher.ReadStatus(root)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			daemon := true
+			pid, err := readPID(root)
+			if errors.Is(err, os.ErrNotExist) {
+				// Watchers in a terminal only leave their status file
+				daemon = false
+				if status != nil {
+					pid = status.PID
+				}
+			} else if err != nil {
+				return err
+			}
+			running := pid != 0 && processAlive(pid)
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(struct {
+					Running bool `json:"running"`
+					*watcher.Status
+				}{running, status})
+			}
+
+			switch {
+			case running:
+				fmt.Printf("Watcher running for %s (pid %d)\n", root, pid)
+				if daemon {
+					fmt.Printf("Log: %s\n", logFile(root))
+				}
+			case daemon:
+				fmt.Printf("No watcher running for %s (stale PID file for pid %d)\n", root, pid)
+			default:
+				fmt.Printf("No watcher running for %s\n", root)
+			}
+			if status != nil {
+				printWatchStatus(status)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+
+	return cmd
+}
+
+// printWatchStatus prints the last regeneration recorded in a status file
+func printWatchStatus(status *watcher.Status) {
+	fmt.Printf("Last run: %s (took %dms)\n", status.LastRun.Local().Format(time.DateTime), status.DurationMs)
+	if status.LastSuccess != nil {
+		fmt.Printf("Last success: %s\n", status.LastSuccess.Local().Format(time.DateTime))
+		fmt.Printf("Files: %d\n", status.Files)
+		fmt.Printf("Tokens: %d\n", status.Tokens)
+		fmt.Printf("Outputs: %s\n", strings.Join(status.Outputs, ", "))
+	}
+	fmt.Printf("Regenerations: %d (%d failed)\n", status.Regenerations, status.Failures)
+	if status.LastErrorAt != nil {
+		fmt.Printf("Last error: %s (%s)\n", status.LastError, status.LastErrorAt.Local().Format(time.DateTime))
+	}
+}
+
+// daemonRoot resolves the watched directory of stop and status, which
+// defaults to the current one
+func daemonRoot(args []string) (string, error) {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve absolute path: %w", err)
+	}
+	return root, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [path]",
+		Short: "Diagnose configuration and environment problems",
+		Long: `Check configuration files in every layer, templates, gitignore loading,
+inotify limits, tokenizer data, provider API keys and plugins, and print
+suggested fixes.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "."
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			// Validate path
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+
+			// Make path absolute
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			return printResults(doctor.Run(absPath, cfgFile, cfg))
+		},
+	}
+
+	return cmd
+}
+
+// printResults prints check results with their suggested fixes and returns
+// an error if any check failed
+func printResults(results []doctor.Result) error {
+	for _, r := range results {
+		fmt.Printf("[%-4s] %s: %s\n", r.Status, r.Name, r.Message)
+		if r.Fix != "" {
+			fmt.Printf("       fix: %s\n", r.Fix)
+		}
+	}
+
+	if doctor.Failed(results) {
+		return fmt.Errorf("some checks failed")
+	}
+	return nil
+}
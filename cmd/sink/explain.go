@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+type explainFlags struct {
+	root             string
+	filterPatterns   []string
+	excludePatterns  []string
+	prunePatterns    []string
+	caseSensitive    bool
+	includeGenerated bool
+	maxFileSize      string
+	noTests          bool
+	testsOnly        bool
+	since            string
+}
+
+func newExplainCmd() *cobra.Command {
+	flags := &explainFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "explain <file>...",
+		Short: "Explain why files are included or excluded",
+		Long: `Report each stage of the decision whether a file is included: parent
+directories, binary detection, the gitignore pattern that matched (and the
+file it came from), gitattributes markers, test selection, and the filter and
+exclude patterns that matched.`,
+		Args: cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("prune") {
+				cfg.PrunePatterns = flags.prunePatterns
+			}
+			if cmd.Flags().Changed("case-sensitive") {
+				cfg.CaseSensitive = flags.caseSensitive
+			}
+			if cmd.Flags().Changed("include-generated") {
+				cfg.IncludeGenerated = flags.includeGenerated
+			}
+			if cmd.Flags().Changed("max-file-size") {
+				cfg.MaxFileSize = flags.maxFileSize
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			if cmd.Flags().Changed("tests-only") {
+				cfg.TestsOnly = flags.testsOnly
+				if cfg.TestsOnly {
+					cfg.NoTests = false
+				}
+			}
+			if cmd.Flags().Changed("since") {
+				cfg.Since = flags.since
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Validate path
+			if _, err := os.Stat(flags.root); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", flags.root, err)
+			}
+
+			// Make path absolute
+			absRoot, err := filepath.Abs(flags.root)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			fp, err := generator.NewFileProcessor(cfg, absRoot)
+			if err != nil {
+				return err
+			}
+
+			for i, path := range args {
+				if i > 0 {
+					fmt.Println()
+				}
+
+				// Paths that don't exist relative to the working directory
+				// are resolved against the repository root
+				target := path
+				if !filepath.IsAbs(target) {
+					if _, err := os.Stat(target); err != nil {
+						target = filepath.Join(absRoot, target)
+					}
+				}
+
+				steps, included, err := fp.Explain(target)
+				if err != nil {
+					return fmt.Errorf("failed to explain %s: %w", path, err)
+				}
+
+				verdict := "excluded"
+				if included {
+					verdict = "included"
+				}
+				fmt.Printf("%s: %s\n", path, verdict)
+				for _, step := range steps {
+					status := "pass"
+					if !step.Passed {
+						status = "skip"
+					}
+					fmt.Printf("  [%s] %s: %s\n", status, step.Stage, step.Detail)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.root, "root", ".", "Repository root the files are resolved against")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().StringSliceVar(&flags.prunePatterns, "prune", nil, "Patterns of directories to skip entirely")
+	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.includeGenerated, "include-generated", false, "Include files marked linguist-generated or linguist-vendored in .gitattributes")
+	cmd.Flags().StringVar(&flags.maxFileSize, "max-file-size", "", "Skip files larger than this size (e.g. 512kb, 2mb)")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files")
+	cmd.Flags().BoolVar(&flags.testsOnly, "tests-only", false, "Include only test files")
+	cmd.MarkFlagsMutuallyExclusive("no-tests", "tests-only")
+	cmd.Flags().StringVar(&flags.since, "since", "", "Only include files changed between this git ref and HEAD")
+
+	return cmd
+}
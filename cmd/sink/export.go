@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/export"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/spf13/cobra"
+)
+
+type exportFlags struct {
+	format            string
+	output            string
+	filterPatterns    []string
+	excludePatterns   []string
+	caseSensitive     bool
+	redact            bool
+	chunk             string
+	systemTemplate    string
+	userTemplate      string
+	assistantTemplate string
+}
+
+func newExportCmd() *cobra.Command {
+	flags := &exportFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "export [path]",
+		Short: "Export code as a fine-tuning dataset",
+		Long: `Export the selected files as a JSONL chat dataset for fine-tuning or evals.
+Each file (or each function with --chunk function) becomes one example whose
+system, user and assistant messages are rendered from Go templates with the
+fields .Repo, .Path, .RelPath, .Language, .Name, .StartLine, .EndLine and .Content.
+
+Examples:
+  sink export . --format openai-ft -o dataset.jsonl
+  sink export . -f "*.go" --chunk function --user "What does {{.Name}} do?"`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("case-sensitive") {
+				cfg.CaseSensitive = flags.caseSensitive
+			}
+			if cmd.Flags().Changed("redact") {
+				cfg.Redact = flags.redact
+			}
+			if cmd.Flags().Changed("chunk") {
+				cfg.Export.Chunk = flags.chunk
+			}
+			if cmd.Flags().Changed("system") {
+				cfg.Export.SystemTemplate = flags.systemTemplate
+			}
+			if cmd.Flags().Changed("user") {
+				cfg.Export.UserTemplate = flags.userTemplate
+			}
+			if cmd.Flags().Changed("assistant") {
+				cfg.Export.AssistantTemplate = flags.assistantTemplate
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format != "openai-ft" {
+				return fmt.Errorf("invalid format: %s (must be 'openai-ft')", flags.format)
+			}
+
+			path := args[0]
+
+			// Validate path
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+
+			// Make path absolute
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			fp, err := processor.NewFileProcessor(processor.Config{
+				RepoRoot:          absPath,
+				FilterPatterns:    cfg.FilterPatterns,
+				ExcludePatterns:   cfg.ExcludePatterns,
+				PrunePatterns:     cfg.PrunePatterns,
+				CaseSensitive:     cfg.CaseSensitive,
+				SyntaxMap:         cfg.SyntaxMap,
+				LanguageOverrides: cfg.LanguageOverrides,
+				IncludeGenerated:  cfg.IncludeGenerated,
+				NoTests:           cfg.NoTests,
+				TestsOnly:         cfg.TestsOnly,
+				Redact:            cfg.Redact,
+				RedactPatterns:    cfg.RedactPatterns,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create file processor: %w", err)
+			}
+
+			files, err := fp.Process()
+			if err != nil {
+				return fmt.Errorf("failed to process files: %w", err)
+			}
+
+			chunks, err := export.Chunks(files, cfg.Export.Chunk, filepath.Base(absPath))
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if flags.output != "" {
+				f, err := os.Create(flags.output)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			err = export.WriteOpenAIFineTuning(w, chunks, export.Templates{
+				System:    cfg.Export.SystemTemplate,
+				User:      cfg.Export.UserTemplate,
+				Assistant: cfg.Export.AssistantTemplate,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to export dataset: %w", err)
+			}
+
+			if flags.output != "" {
+				fmt.Fprintf(os.Stderr, "Exported %d examples to %s\n", len(chunks), flags.output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.format, "format", "openai-ft", "Dataset format (openai-ft)")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output file path (default stdout)")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.redact, "redact", false, "Replace API keys, tokens, private keys and other secrets with [REDACTED]")
+	cmd.Flags().StringVar(&flags.chunk, "chunk", "file", "Example granularity (file or function)")
+	cmd.Flags().StringVar(&flags.systemTemplate, "system", "", "Template for the system message")
+	cmd.Flags().StringVar(&flags.userTemplate, "user", "", "Template for the user message")
+	cmd.Flags().StringVar(&flags.assistantTemplate, "assistant", "", "Template for the assistant message")
+
+	return cmd
+}
@@ -2,15 +2,26 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/plugins"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	cfg     *config.Config
+	cfgFile    string
+	profile    string
+	verbose    bool
+	quiet      bool
+	logLevel   string
+	logFormat  string
+	allowHooks bool
+	noPlugins  bool
+	cfg        *config.Config
 )
 
 // rootCmd represents the base command
@@ -25,20 +36,80 @@ Example usage:
   sink analyze . --format flat
   sink generate . --tokens --price --model gpt-4`,
 	Version: "0.1.0",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initLogging(cmd)
+	},
 }
 
 func initConfig() error {
 	var err error
-	cfg, err = config.LoadConfig(cfgFile)
+	cfg, err = config.LoadConfig(cfgFile, profile, allowHooks)
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 	return nil
 }
 
+// initPlugins loads the WebAssembly plugins in the user's plugin directory
+// and registers their formats and transforms, unless --no-plugins is set.
+// Plugins that fail to load are skipped with a warning; sink doctor
+// reports them too.
+func initPlugins() {
+	if noPlugins {
+		return
+	}
+	loaded, errs := plugins.Load(config.PluginDir())
+	errs = append(errs, generator.RegisterPlugins(loaded)...)
+	for _, err := range errs {
+		logging.Warn("skipping plugin", "error", err)
+	}
+	for _, p := range loaded {
+		logging.Debug("loaded plugin", "name", p.Name, "transform", p.Transform, "formats", p.Formats)
+	}
+}
+
+// initLogging sets the log level and format from the flags, falling back to
+// the config
+func initLogging(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("quiet") {
+		cfg.Quiet = quiet
+	}
+	if cmd.Flags().Changed("log-level") {
+		cfg.LogLevel = logLevel
+	}
+	if cmd.Flags().Changed("log-format") {
+		cfg.LogFormat = logFormat
+	}
+	if err := logging.SetFormat(cfg.LogFormat); err != nil {
+		return err
+	}
+
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	// An explicit level wins over --verbose and --quiet
+	if cfg.LogLevel == "" {
+		if verbose {
+			level = slog.LevelDebug
+		} else if cfg.Quiet {
+			level = slog.LevelWarn
+		}
+	}
+	logging.SetLevel(level)
+	return nil
+}
+
 func initialize() {
 	// Add persistent flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file path")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "named profile from the config's profiles section")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log debug messages")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "hide progress and status messages, and only log warnings and errors")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "minimum level of log messages (debug, info, warn or error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "log as key=value text or as JSON objects (text or json)")
+	rootCmd.PersistentFlags().BoolVar(&noPlugins, "no-plugins", false, "do not load WebAssembly plugins from the user's plugin directory")
+	rootCmd.PersistentFlags().BoolVar(&allowHooks, "allow-hooks", false, "run pre-hook and post-hook commands and use notify-webhook from the local sink-config.yaml and --config file")
 
 	// Disable default completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
@@ -49,12 +120,23 @@ func initialize() {
 			fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
 			os.Exit(1)
 		}
+		initPlugins()
 	})
 
 	// Add subcommands after config initialization
 	rootCmd.AddCommand(newGenerateCmd())
+	rootCmd.AddCommand(newCopyCmd())
+	rootCmd.AddCommand(newLsCmd())
+	rootCmd.AddCommand(newMapCmd())
 	rootCmd.AddCommand(newAnalyzeCmd())
 	rootCmd.AddCommand(newWatchCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newExplainCmd())
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newAskCmd())
+	rootCmd.AddCommand(newSearchCmd())
 }
 
 func main() {
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/repomap"
+	"github.com/dwrtz/sink/internal/tokens"
+	"github.com/spf13/cobra"
+)
+
+type mapFlags struct {
+	output           string
+	filterPatterns   []string
+	excludePatterns  []string
+	prunePatterns    []string
+	caseSensitive    bool
+	includeGenerated bool
+	noTests          bool
+	testsOnly        bool
+	maxTokens        int
+}
+
+func newMapCmd() *cobra.Command {
+	flags := &mapFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "map [path]",
+		Short: "Print a compressed map of the repository's files and symbols",
+		Long: `Print the repository's files grouped by directory, each with the signatures
+of the functions, types and classes it declares. Files whose symbols are
+referenced most by other files are kept first when the map is trimmed to
+--max-tokens.`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("prune") {
+				cfg.PrunePatterns = flags.prunePatterns
+			}
+			if cmd.Flags().Changed("case-sensitive") {
+				cfg.CaseSensitive = flags.caseSensitive
+			}
+			if cmd.Flags().Changed("include-generated") {
+				cfg.IncludeGenerated = flags.includeGenerated
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			if cmd.Flags().Changed("tests-only") {
+				cfg.TestsOnly = flags.testsOnly
+				if cfg.TestsOnly {
+					cfg.NoTests = false
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+
+			// Validate path
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+
+			// Make path absolute
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			// The map is built from full sources even if outlining is configured
+			cfg.Outline = false
+			files, err := generator.ProcessFiles(cfg, absPath)
+			if err != nil {
+				return fmt.Errorf("failed to process files: %w", err)
+			}
+
+			counter, err := tokens.NewCounter(cfg.TokenEncoding)
+			if err != nil {
+				return fmt.Errorf("failed to create token counter: %w", err)
+			}
+			content, omitted, err := repomap.Build(repomap.Rank(files), counter, flags.maxTokens)
+			if err != nil {
+				return fmt.Errorf("failed to build map: %w", err)
+			}
+			if omitted > 0 {
+				fmt.Fprintf(os.Stderr, "%d lower-ranked files omitted to fit %d tokens\n", omitted, flags.maxTokens)
+			}
+
+			if flags.output == "" {
+				fmt.Print(content)
+				return nil
+			}
+			if err := os.WriteFile(flags.output, []byte(content), 0644); err != nil {
+				return fmt.Errorf("failed to write map: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Map written to: %s\n", flags.output)
+			return nil
+		},
+	}
+
+	// Add flags bound to the local flags struct
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Write the map to this file instead of stdout")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().StringSliceVar(&flags.prunePatterns, "prune", nil, "Patterns of directories to skip entirely")
+	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.includeGenerated, "include-generated", false, "Include files marked linguist-generated or linguist-vendored in .gitattributes")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files (e.g. *_test.go, test_*.py, *.spec.ts, __tests__/)")
+	cmd.Flags().BoolVar(&flags.testsOnly, "tests-only", false, "Include only test files")
+	cmd.MarkFlagsMutuallyExclusive("no-tests", "tests-only")
+	cmd.Flags().IntVar(&flags.maxTokens, "max-tokens", 1024, "Trim the map to this many tokens, keeping the most referenced files (0 = no limit)")
+
+	return cmd
+}
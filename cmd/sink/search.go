@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/embeddings"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/llm"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+type searchFlags struct {
+	provider        string
+	model           string
+	index           string
+	top             int
+	chunkLines      int
+	prompt          bool
+	output          string
+	filterPatterns  []string
+	excludePatterns []string
+	noTests         bool
+}
+
+func newSearchCmd() *cobra.Command {
+	flags := &searchFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "search query [path]",
+		Short: "Find the code most relevant to a query using embeddings",
+		Long: `Rank chunks of the repository's files by the similarity of their embeddings
+to the query's, and print the best matches. With --prompt, generate the
+output from only the most relevant files instead.
+
+Embeddings are kept in .sink/embeddings.json (or --index) and only chunks
+that changed since the last search are embedded again.`,
+		Args: cobra.RangeArgs(1, 2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("provider") {
+				cfg.Provider = flags.provider
+			}
+			if cmd.Flags().Changed("output") {
+				cfg.Output = flags.output
+				cfg.Outputs = nil
+			}
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := args[0]
+			path := "."
+			if len(args) > 1 {
+				path = args[1]
+			}
+
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			embedder, err := llm.NewEmbedder(cfg.Provider)
+			if err != nil {
+				return err
+			}
+			model := flags.model
+			if model == "" {
+				model = llm.DefaultEmbeddingModels[cfg.Provider]
+			}
+			indexPath := flags.index
+			if indexPath == "" {
+				indexPath = filepath.Join(absPath, manifest.Dir, "embeddings.json")
+			}
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer cancel()
+
+			// Chunks are embedded from full sources even if outlining is
+			// configured
+			indexCfg := *cfg
+			indexCfg.Outline = false
+			files, err := generator.ProcessFiles(&indexCfg, absPath)
+			if err != nil {
+				return fmt.Errorf("failed to process files: %w", err)
+			}
+			previous, err := embeddings.Load(indexPath)
+			if err != nil {
+				return err
+			}
+			index, embedded, err := embeddings.Update(ctx, previous, files, embedder, cfg.Provider, model, flags.chunkLines)
+			if err != nil {
+				return err
+			}
+			if embedded > 0 {
+				if err := index.Save(indexPath); err != nil {
+					return err
+				}
+			}
+			logging.Info("indexed", "chunks", len(index.Chunks), "embedded", embedded, "index", indexPath)
+
+			vectors, err := embedder.Embed(ctx, model, []string{query})
+			if err != nil {
+				return fmt.Errorf("failed to embed query: %w", err)
+			}
+
+			if !flags.prompt {
+				for _, m := range index.Search(vectors[0], flags.top) {
+					fmt.Printf("%.3f  %s:%d-%d\n", m.Score, m.Path, m.StartLine, m.EndLine)
+				}
+				return nil
+			}
+
+			cfg.OnlyFiles = index.TopFiles(vectors[0], flags.top)
+			if len(cfg.OnlyFiles) == 0 {
+				return fmt.Errorf("no files indexed in %s", absPath)
+			}
+			return generator.RunGeneration(cfg, absPath)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.provider, "provider", "openai", "Provider to embed with (openai or ollama)")
+	cmd.Flags().StringVar(&flags.model, "embedding-model", "", "Embedding model (defaults to the provider's default)")
+	cmd.Flags().StringVar(&flags.index, "index", "", "Embeddings file (default .sink/embeddings.json in the repository)")
+	cmd.Flags().IntVarP(&flags.top, "top", "k", 10, "Number of matches to print, or of files to include with --prompt")
+	cmd.Flags().IntVar(&flags.chunkLines, "chunk-lines", embeddings.DefaultChunkLines, "Lines per embedded chunk")
+	cmd.Flags().BoolVar(&flags.prompt, "prompt", false, "Generate the output from the top files instead of printing matches")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output file path for --prompt (empty for stdout)")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files")
+
+	return cmd
+}
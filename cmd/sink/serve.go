@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/server"
+	"github.com/dwrtz/sink/internal/watcher"
+	"github.com/spf13/cobra"
+)
+
+type serveFlags struct {
+	addr             string
+	format           string
+	filterPatterns   []string
+	excludePatterns  []string
+	prunePatterns    []string
+	caseSensitive    bool
+	includeGenerated bool
+	maxFileSize      string
+	redact           bool
+	showTokens       bool
+	watch            bool
+	debounceMs       int
+}
+
+func newServeCmd() *cobra.Command {
+	flags := &serveFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "serve [path]",
+		Short: "Serve generated context over HTTP",
+		Long: `Run an HTTP server that generates context on request:
+
+  GET /         web UI to pick files, preview the prompt and copy it
+  GET /prompt   the generated document (?format= overrides the output format)
+  POST /prompt  the same, for a JSON body of {"files": [...], "format": "..."}
+  GET /files    JSON list of the files that would be included (?tokens=true
+                adds per-file token counts)
+  GET /stats    JSON codebase statistics (?tokens=true adds token counts)
+  GET /metrics  Prometheus metrics
+
+With --watch, the directory is also watched for changes:
+
+  GET /events   server-sent "changed" events after each regeneration
+                (?content=true includes the regenerated document)`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only override config values if flags were explicitly set
+			if cmd.Flags().Changed("format") {
+				cfg.Format = flags.format
+				cfg.Outputs = nil
+			}
+			if cmd.Flags().Changed("filter") {
+				cfg.FilterPatterns = flags.filterPatterns
+			}
+			if cmd.Flags().Changed("exclude") {
+				cfg.ExcludePatterns = flags.excludePatterns
+			}
+			if cmd.Flags().Changed("prune") {
+				cfg.PrunePatterns = flags.prunePatterns
+			}
+			if cmd.Flags().Changed("case-sensitive") {
+				cfg.CaseSensitive = flags.caseSensitive
+			}
+			if cmd.Flags().Changed("include-generated") {
+				cfg.IncludeGenerated = flags.includeGenerated
+			}
+			if cmd.Flags().Changed("max-file-size") {
+				cfg.MaxFileSize = flags.maxFileSize
+			}
+			if cmd.Flags().Changed("redact") {
+				cfg.Redact = flags.redact
+			}
+			if cmd.Flags().Changed("tokens") {
+				cfg.ShowTokens = flags.showTokens
+			}
+
+			readOnly(cfg)
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+
+			// Validate path
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid repository path %s: %w", path, err)
+			}
+
+			// Make path absolute
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			srv := server.New(server.Config{
+				RootPath:   absPath,
+				RepoConfig: cfg,
+				Addr:       flags.addr,
+				Watch:      flags.watch,
+			})
+
+			if flags.watch {
+				watchService, err := watcher.NewService(watcher.Config{
+					RootPath:        absPath,
+					RepoConfig:      cfg,
+					DebounceTimeout: time.Duration(flags.debounceMs) * time.Millisecond,
+					Publish:         srv.Publish,
+					Profile:         profile,
+					Reload: func() (*config.Config, error) {
+						reloaded, err := config.LoadConfig(cfgFile, profile, allowHooks)
+						if err != nil {
+							return nil, err
+						}
+						if err := reloaded.MergeFlagSet(cmd.Flags()); err != nil {
+							return nil, err
+						}
+						readOnly(reloaded)
+						return reloaded, nil
+					},
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create watch service: %w", err)
+				}
+				go func() {
+					// The server keeps running without live updates
+					if err := watchService.Watch(); err != nil {
+						logging.For("serve").Error("watch service error", "error", err)
+					}
+				}()
+			}
+
+			return srv.ListenAndServe(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.addr, "addr", "localhost:8080", "Address to listen on")
+	cmd.Flags().StringVar(&flags.format, "format", "", "Default output format for /prompt ("+strings.Join(generator.Formats(), ", ")+")")
+	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
+	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().StringSliceVar(&flags.prunePatterns, "prune", nil, "Patterns of directories to skip entirely")
+	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.includeGenerated, "include-generated", false, "Include files marked linguist-generated or linguist-vendored in .gitattributes")
+	cmd.Flags().StringVar(&flags.maxFileSize, "max-file-size", "", "Skip files larger than this size (e.g. 512kb, 2mb)")
+	cmd.Flags().BoolVar(&flags.redact, "redact", false, "Replace API keys, tokens, private keys and other secrets with [REDACTED]")
+	cmd.Flags().BoolVar(&flags.showTokens, "tokens", false, "Include token counts in /stats")
+	cmd.Flags().BoolVar(&flags.watch, "watch", false, "Watch for changes and stream regenerations to /events clients")
+	cmd.Flags().IntVar(&flags.debounceMs, "debounce", 500, "With --watch, debounce timeout in milliseconds")
+
+	return cmd
+}
+
+// readOnly turns off the settings that would make serving modify files
+// or prompt for input
+func readOnly(c *config.Config) {
+	c.Confirm = false
+	c.Inject = false
+	c.Changelog = false
+}
@@ -4,29 +4,88 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/dwrtz/sink/internal/config"
 	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/tokens"
 	"github.com/dwrtz/sink/internal/watcher"
 	"github.com/spf13/cobra"
 )
 
 type watchFlags struct {
-	output          string
-	filterPatterns  []string
-	excludePatterns []string
-	caseSensitive   bool
-	noCodeblock     bool
-	lineNumbers     bool
-	stripComments   bool
-	templatePath    string
-	showTokens      bool
-	encoding        string
-	showPrice       bool
-	provider        string
-	model           string
-	outputTokens    int
-	debounceMs      int
+	output           string
+	format           string
+	filterPatterns   []string
+	excludePatterns  []string
+	prunePatterns    []string
+	caseSensitive    bool
+	includeGenerated bool
+	maxFileSize      string
+	noTests          bool
+	testsOnly        bool
+	since            string
+	untracked        bool
+	noCodeblock      bool
+	lineNumbers      bool
+	stripComments    bool
+	redact           bool
+	outline          bool
+	dedup            bool
+	cache            bool
+	concurrency      int
+	reportSkipped    bool
+	skippedReport    string
+	groupByDir       bool
+	tree             bool
+	fileTokens       bool
+	todos            bool
+	instructions     string
+	instructionsFile string
+	instructionsPos  string
+	systemPrompt     string
+	messageTokens    int
+	chunkTokens      int
+	chunkOverlap     int
+	frontMatter      bool
+	changelog        bool
+	changelogDiffs   bool
+	order            string
+	sort             string
+	reverse          bool
+	blame            bool
+	templatePath     string
+	fileTemplate     string
+	documentTemplate string
+	templateDir      string
+	vars             []string
+	showTokens       bool
+	encoding         string
+	encodingForModel string
+	showPrice        bool
+	chatFormat       bool
+	provider         string
+	model            string
+	outputTokens     int
+	pricing          string
+	strict           bool
+	failOverTokens   int
+	maxTokens        int
+	budgetStrategy   string
+	splitTokens      int
+	inject           bool
+	debounceMs       int
+	stdout           bool
+	interval         time.Duration
+	noEvents         bool
+	metricsAddr      string
+	once             bool
+	noInitial        bool
+	exec             string
+	poll             time.Duration
+	daemon           bool
+	notifyWebhook    string
 }
 
 func newWatchCmd() *cobra.Command {
@@ -41,7 +100,10 @@ rules as the generate command.
 
 Examples:
   sink watch . -o output.md
-  sink watch . --filter "*.go,*.md" --debounce 1000`,
+  sink watch . --filter "*.go,*.md" --debounce 1000
+  sink watch . -o output.md --interval 15m --no-events
+  sink watch . -o output.md --daemon
+  sink watch stop .`,
 		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// Convert path to absolute to ensure consistent watching
@@ -53,7 +115,14 @@ Examples:
 
 			// Update config with CLI flags if they were explicitly set
 			if cmd.Flags().Changed("output") {
+				// An explicit output path replaces any configured output targets
 				cfg.Output = flags.output
+				cfg.Outputs = nil
+			}
+			if cmd.Flags().Changed("format") {
+				// An explicit format likewise applies to a single output
+				cfg.Format = flags.format
+				cfg.Outputs = nil
 			}
 			if cmd.Flags().Changed("filter") {
 				cfg.FilterPatterns = flags.filterPatterns
@@ -61,9 +130,36 @@ Examples:
 			if cmd.Flags().Changed("exclude") {
 				cfg.ExcludePatterns = flags.excludePatterns
 			}
+			if cmd.Flags().Changed("prune") {
+				cfg.PrunePatterns = flags.prunePatterns
+			}
 			if cmd.Flags().Changed("case-sensitive") {
 				cfg.CaseSensitive = flags.caseSensitive
 			}
+			if cmd.Flags().Changed("include-generated") {
+				cfg.IncludeGenerated = flags.includeGenerated
+			}
+			if cmd.Flags().Changed("max-file-size") {
+				cfg.MaxFileSize = flags.maxFileSize
+			}
+			if cmd.Flags().Changed("no-tests") {
+				cfg.NoTests = flags.noTests
+				if cfg.NoTests {
+					cfg.TestsOnly = false
+				}
+			}
+			if cmd.Flags().Changed("tests-only") {
+				cfg.TestsOnly = flags.testsOnly
+				if cfg.TestsOnly {
+					cfg.NoTests = false
+				}
+			}
+			if cmd.Flags().Changed("since") {
+				cfg.Since = flags.since
+			}
+			if cmd.Flags().Changed("untracked") {
+				cfg.Untracked = flags.untracked
+			}
 			if cmd.Flags().Changed("no-codeblock") {
 				cfg.NoCodeblock = flags.noCodeblock
 			}
@@ -73,15 +169,122 @@ Examples:
 			if cmd.Flags().Changed("strip-comments") {
 				cfg.StripComments = flags.stripComments
 			}
+			if cmd.Flags().Changed("redact") {
+				cfg.Redact = flags.redact
+			}
+			if cmd.Flags().Changed("outline") {
+				cfg.Outline = flags.outline
+			}
+			if cmd.Flags().Changed("dedup") {
+				cfg.Dedup = flags.dedup
+			}
+			if cmd.Flags().Changed("cache") {
+				cfg.Cache = flags.cache
+			}
+			if cmd.Flags().Changed("concurrency") {
+				cfg.Concurrency = flags.concurrency
+			}
+			if cmd.Flags().Changed("report-skipped") {
+				cfg.ReportSkipped = flags.reportSkipped
+			}
+			if cmd.Flags().Changed("skipped-report") {
+				cfg.SkippedReport = flags.skippedReport
+			}
+			if cmd.Flags().Changed("group-by-dir") {
+				cfg.GroupByDir = flags.groupByDir
+			}
+			if cmd.Flags().Changed("tree") {
+				cfg.Tree = flags.tree
+			}
+			if cmd.Flags().Changed("file-tokens") {
+				cfg.FileTokens = flags.fileTokens
+			}
+			if cmd.Flags().Changed("todos") {
+				cfg.Todos = flags.todos
+			}
+			if cmd.Flags().Changed("instructions") {
+				cfg.Instructions = flags.instructions
+			}
+			if cmd.Flags().Changed("instructions-file") {
+				cfg.InstructionsFile = flags.instructionsFile
+			}
+			if cmd.Flags().Changed("instructions-position") {
+				cfg.InstructionsPosition = flags.instructionsPos
+			}
+			if cmd.Flags().Changed("system-prompt") {
+				cfg.SystemPrompt = flags.systemPrompt
+			}
+			if cmd.Flags().Changed("message-tokens") {
+				cfg.MessageTokens = flags.messageTokens
+			}
+			if cmd.Flags().Changed("chunk-tokens") {
+				cfg.ChunkTokens = flags.chunkTokens
+			}
+			if cmd.Flags().Changed("chunk-overlap") {
+				cfg.ChunkOverlap = flags.chunkOverlap
+			}
+			if cmd.Flags().Changed("front-matter") {
+				cfg.FrontMatter = flags.frontMatter
+			}
+			if cmd.Flags().Changed("changelog") {
+				cfg.Changelog = flags.changelog
+			}
+			if cmd.Flags().Changed("changelog-diffs") {
+				cfg.ChangelogDiffs = flags.changelogDiffs
+			}
+			if cmd.Flags().Changed("order") {
+				cfg.Order = flags.order
+			}
+			if cmd.Flags().Changed("sort") {
+				cfg.Sort = flags.sort
+			}
+			if cmd.Flags().Changed("reverse") {
+				cfg.Reverse = flags.reverse
+			}
+			if cmd.Flags().Changed("blame") {
+				cfg.Blame = flags.blame
+			}
 			if cmd.Flags().Changed("template") {
 				cfg.TemplatePath = flags.templatePath
 			}
+			if cmd.Flags().Changed("file-template") {
+				cfg.FileTemplatePath = flags.fileTemplate
+			}
+			if cmd.Flags().Changed("document-template") {
+				cfg.DocumentTemplatePath = flags.documentTemplate
+			}
+			if cmd.Flags().Changed("template-dir") {
+				cfg.TemplateDir = flags.templateDir
+			}
+			if cmd.Flags().Changed("var") {
+				vars, err := config.ParseVars(flags.vars)
+				if err != nil {
+					return err
+				}
+				if cfg.Vars == nil {
+					cfg.Vars = make(map[string]string)
+				}
+				for k, v := range vars {
+					cfg.Vars[k] = v
+				}
+			}
 			if cmd.Flags().Changed("tokens") {
 				cfg.ShowTokens = flags.showTokens
 			}
 			if cmd.Flags().Changed("encoding") {
 				cfg.TokenEncoding = flags.encoding
 			}
+			if cmd.Flags().Changed("encoding-for-model") {
+				encoding, err := tokens.EncodingForModel(flags.encodingForModel)
+				if err != nil {
+					return err
+				}
+				cfg.EncodingForModel = flags.encodingForModel
+				cfg.TokenEncoding = encoding
+			}
+			if cmd.Flags().Changed("chat-format") {
+				cfg.ChatFormat = flags.chatFormat
+			}
 			if cmd.Flags().Changed("price") {
 				cfg.ShowPrice = flags.showPrice
 			}
@@ -94,6 +297,41 @@ Examples:
 			if cmd.Flags().Changed("output-tokens") {
 				cfg.OutputTokens = flags.outputTokens
 			}
+			if cmd.Flags().Changed("pricing") {
+				cfg.Pricing = flags.pricing
+			}
+			if cmd.Flags().Changed("strict") {
+				cfg.Strict = flags.strict
+			}
+			if cmd.Flags().Changed("fail-over-tokens") {
+				cfg.FailOverTokens = flags.failOverTokens
+			}
+			if cmd.Flags().Changed("max-tokens") {
+				cfg.MaxTokens = flags.maxTokens
+			}
+			if cmd.Flags().Changed("budget-strategy") {
+				cfg.BudgetStrategy = flags.budgetStrategy
+			}
+			if cmd.Flags().Changed("split-tokens") {
+				cfg.SplitTokens = flags.splitTokens
+			}
+			if cmd.Flags().Changed("inject") {
+				cfg.Inject = flags.inject
+			}
+			if cmd.Flags().Changed("notify-webhook") {
+				cfg.NotifyWebhook = flags.notifyWebhook
+			}
+
+			// Regeneration runs unattended, so never prompt before writing
+			cfg.Confirm = false
+
+			if flags.daemon && flags.stdout {
+				return fmt.Errorf("--daemon cannot stream to --stdout; write to a file instead")
+			}
+
+			if flags.noEvents && flags.interval <= 0 {
+				return fmt.Errorf("--no-events requires --interval")
+			}
 
 			// Validate the path exists
 			if _, err := os.Stat(args[0]); err != nil {
@@ -103,22 +341,54 @@ Examples:
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := generator.RunGeneration(cfg, args[0])
-			if err != nil {
-				return fmt.Errorf("failed to generate file: %w", err)
+			if flags.daemon {
+				return startDaemon(args[0])
+			}
+			if os.Getenv(daemonEnv) != "" {
+				defer removeOwnPIDFile(args[0])
 			}
 
 			watchService, err := watcher.NewService(watcher.Config{
 				RootPath:        args[0],
 				RepoConfig:      cfg,
 				DebounceTimeout: time.Duration(flags.debounceMs) * time.Millisecond,
+				Stdout:          flags.stdout,
+				Interval:        flags.interval,
+				NoEvents:        flags.noEvents,
+				MetricsAddr:     flags.metricsAddr,
+				Once:            flags.once,
+				Exec:            flags.exec,
+				Poll:            flags.poll,
+				Profile:         profile,
+				Reload: func() (*config.Config, error) {
+					reloaded, err := config.LoadConfig(cfgFile, profile, allowHooks)
+					if err != nil {
+						return nil, err
+					}
+					if err := reloaded.MergeFlagSet(cmd.Flags()); err != nil {
+						return nil, err
+					}
+					reloaded.Confirm = false
+					return reloaded, nil
+				},
 			})
 			if err != nil {
 				return fmt.Errorf("failed to create watch service: %w", err)
 			}
 
-			fmt.Printf("Watching %s for changes...\n", args[0])
-			fmt.Println("Press Ctrl+C to stop")
+			if !flags.noInitial {
+				if err := watchService.Generate(); err != nil {
+					return fmt.Errorf("failed to generate file: %w", err)
+				}
+			}
+
+			// Keep stdout clean for the document stream
+			status := os.Stdout
+			if flags.stdout {
+				status = os.Stderr
+			}
+			fmt.Fprintf(status, "Watching %s for changes...\n", args[0])
+			fmt.Fprintln(status, "Press Ctrl+C to stop")
 
 			// Watch will block until interrupted
 			if err := watchService.Watch(); err != nil {
@@ -131,20 +401,81 @@ Examples:
 
 	// Add flags
 	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output file path")
+	cmd.Flags().StringVar(&flags.format, "format", "", "Output format ("+strings.Join(generator.Formats(), ", ")+")")
 	cmd.Flags().StringSliceVarP(&flags.filterPatterns, "filter", "f", nil, "Filter patterns to include files")
 	cmd.Flags().StringSliceVarP(&flags.excludePatterns, "exclude", "e", nil, "Patterns to exclude files")
+	cmd.Flags().StringSliceVar(&flags.prunePatterns, "prune", nil, "Patterns of directories to skip entirely")
 	cmd.Flags().BoolVarP(&flags.caseSensitive, "case-sensitive", "c", false, "Use case-sensitive pattern matching")
+	cmd.Flags().BoolVar(&flags.includeGenerated, "include-generated", false, "Include files marked linguist-generated or linguist-vendored in .gitattributes")
+	cmd.Flags().StringVar(&flags.maxFileSize, "max-file-size", "", "Skip files larger than this size (e.g. 512kb, 2mb)")
+	cmd.Flags().BoolVar(&flags.noTests, "no-tests", false, "Exclude test files (e.g. *_test.go, test_*.py, *.spec.ts, __tests__/)")
+	cmd.Flags().BoolVar(&flags.testsOnly, "tests-only", false, "Include only test files")
+	cmd.MarkFlagsMutuallyExclusive("no-tests", "tests-only")
+	cmd.Flags().StringVar(&flags.since, "since", "", "Only include files changed between this git ref and HEAD, plus uncommitted changes")
+	cmd.Flags().BoolVar(&flags.untracked, "untracked", false, "With --since, also include untracked files")
 	cmd.Flags().BoolVar(&flags.noCodeblock, "no-codeblock", false, "Disable wrapping code in markdown code blocks")
 	cmd.Flags().BoolVarP(&flags.lineNumbers, "line-numbers", "l", false, "Add line numbers to code blocks")
 	cmd.Flags().BoolVarP(&flags.stripComments, "strip-comments", "s", false, "Strip comments from code")
+	cmd.Flags().BoolVar(&flags.redact, "redact", false, "Replace API keys, tokens, private keys and other secrets with [REDACTED]")
+	cmd.Flags().BoolVar(&flags.outline, "outline", false, "Reduce code to imports, type definitions and function signatures with bodies elided")
+	cmd.Flags().BoolVar(&flags.dedup, "dedup", false, "Include identical files once and replace copies with a reference to the first")
+	cmd.Flags().BoolVar(&flags.cache, "cache", false, "Reuse processed contents and token counts of unchanged files from .sink/cache")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", 0, "Number of files to read and process in parallel (0 = number of CPUs)")
+	cmd.Flags().BoolVar(&flags.reportSkipped, "report-skipped", false, "Print a summary of skipped files and directories and why they were skipped")
+	cmd.Flags().StringVar(&flags.skippedReport, "skipped-report", "", "Write every skipped file and directory with its reason to this JSON file")
+	cmd.Flags().BoolVar(&flags.groupByDir, "group-by-dir", false, "Group files under per-directory sections")
+	cmd.Flags().BoolVar(&flags.tree, "tree", false, "Prepend a directory tree of the included files")
+	cmd.Flags().BoolVar(&flags.fileTokens, "file-tokens", false, "Show the token count of each file in its markdown metadata")
+	cmd.Flags().BoolVar(&flags.todos, "todos", false, "Append a Known TODOs section listing TODO, FIXME, HACK and XXX markers")
+	cmd.Flags().StringVar(&flags.instructions, "instructions", "", "Task description to place in an Instructions section with the files")
+	cmd.Flags().StringVar(&flags.instructionsFile, "instructions-file", "", "File whose contents go in the Instructions section")
+	cmd.Flags().StringVar(&flags.instructionsPos, "instructions-position", "", "Place the instructions before or after the files (default before)")
+	cmd.Flags().StringVar(&flags.systemPrompt, "system-prompt", "", "System message of the messages format")
+	cmd.Flags().IntVar(&flags.messageTokens, "message-tokens", 0, "Spread files over user messages of at most this many tokens in the messages format (0 = one message)")
+	cmd.Flags().IntVar(&flags.chunkTokens, "chunk-tokens", 0, "Most tokens per record in the chunks format (0 = 512)")
+	cmd.Flags().IntVar(&flags.chunkOverlap, "chunk-overlap", 0, "Tokens of lines repeated from the end of one chunk at the start of the next in the chunks format")
+	cmd.Flags().BoolVar(&flags.frontMatter, "front-matter", false, "Prepend a YAML front matter block with generation metadata")
+	cmd.Flags().BoolVar(&flags.changelog, "changelog", false, "Append a section listing files changed since the last generation")
+	cmd.Flags().BoolVar(&flags.changelogDiffs, "changelog-diffs", false, "Include diffs of modified files in the changelog section")
+	cmd.Flags().StringVar(&flags.order, "order", "", "File ordering preset (docs-first)")
+	cmd.Flags().StringVar(&flags.sort, "sort", "", "Sort files by path, size, mtime, tokens or none (walk order)")
+	cmd.Flags().BoolVar(&flags.reverse, "reverse", false, "Reverse the sorted file order")
+	cmd.Flags().BoolVar(&flags.blame, "blame", false, "Prefix each line with the commit and author from git blame")
 	cmd.Flags().StringVarP(&flags.templatePath, "template", "t", "", "Path to template file")
+	cmd.Flags().StringVar(&flags.fileTemplate, "file-template", "", "Path to a template rendered once per file")
+	cmd.Flags().StringVar(&flags.documentTemplate, "document-template", "", "Path to a template wrapping the rendered files (header, table of contents, footer)")
+	cmd.Flags().StringVar(&flags.templateDir, "template-dir", "", "Directory of *.tmpl files that templates can include by name; main.tmpl is the entry point")
+	cmd.Flags().StringArrayVar(&flags.vars, "var", nil, "Template variable as key=value, available as .Vars.key (repeatable)")
 	cmd.Flags().BoolVar(&flags.showTokens, "tokens", false, "Show token count")
-	cmd.Flags().StringVar(&flags.encoding, "encoding", "cl100k_base", "Token encoding to use")
+	cmd.Flags().StringVar(&flags.encoding, "encoding", "cl100k_base", "Token encoding to use (o200k_base, cl100k_base, p50k_base, p50k_edit or r50k_base)")
+	cmd.Flags().StringVar(&flags.encodingForModel, "encoding-for-model", "", "Use the token encoding of this OpenAI model (e.g. gpt-4o); overrides --encoding")
 	cmd.Flags().BoolVar(&flags.showPrice, "price", false, "Show estimated price")
+	cmd.Flags().BoolVar(&flags.chatFormat, "chat-format", false, "Include the provider's chat message overhead in token and price estimates")
 	cmd.Flags().StringVar(&flags.provider, "provider", "openai", "Provider for price estimation")
 	cmd.Flags().StringVar(&flags.model, "model", "gpt-3.5-turbo", "Model for price estimation")
 	cmd.Flags().IntVar(&flags.outputTokens, "output-tokens", 1000, "Expected number of output tokens")
+	cmd.Flags().StringVar(&flags.pricing, "pricing", "", "Path or URL of a pricing.yaml overriding the built-in model prices")
+	cmd.Flags().BoolVar(&flags.strict, "strict", false, "Fail instead of warning when the output exceeds the model's context window")
+	cmd.Flags().IntVar(&flags.failOverTokens, "fail-over-tokens", 0, "Exit with an error if the output exceeds this many tokens, listing the largest files")
+	cmd.Flags().BoolVar(&flags.inject, "inject", false, "Replace only the region between <!-- sink:begin --> and <!-- sink:end --> in the output file")
+	cmd.Flags().IntVar(&flags.maxTokens, "max-tokens", 0, "Drop or truncate the lowest-priority files so the output fits this many tokens")
+	cmd.Flags().StringVar(&flags.budgetStrategy, "budget-strategy", "", "Which files to keep first under --max-tokens (order, pattern, size, depth or importance)")
+	cmd.Flags().IntVar(&flags.splitTokens, "split-tokens", 0, "Split output into numbered parts of at most this many tokens (0 = no splitting)")
 	cmd.Flags().IntVar(&flags.debounceMs, "debounce", 500, "Debounce timeout in milliseconds")
+	cmd.Flags().DurationVar(&flags.interval, "interval", 0, "Also regenerate on a timer (e.g. 15m)")
+	cmd.Flags().BoolVar(&flags.noEvents, "no-events", false, "Ignore file system events and only regenerate on --interval")
+	cmd.Flags().StringVar(&flags.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics on this address (e.g. :9090)")
+	cmd.Flags().BoolVar(&flags.once, "once", false, "Exit after the first regeneration triggered by a change or --interval")
+	cmd.Flags().BoolVar(&flags.noInitial, "no-initial-generate", false, "Skip generating when the watcher starts and wait for the first change")
+	cmd.Flags().DurationVar(&flags.poll, "poll", 0, "Scan for changed files on this interval (e.g. 2s) instead of using file system events, for network filesystems and bind mounts")
+	cmd.MarkFlagsMutuallyExclusive("poll", "no-events")
+	cmd.Flags().StringVar(&flags.exec, "exec", "", "Shell command to run after each successful regeneration; {output} and {changed} expand to the output paths and changed files")
+	cmd.Flags().BoolVar(&flags.daemon, "daemon", false, "Run in the background, writing a PID file and log to .sink/ (see sink watch stop and status)")
+	cmd.Flags().StringVar(&flags.notifyWebhook, "notify-webhook", "", "POST a JSON summary of each regeneration (outputs, tokens, changed files, duration) to this URL")
+	cmd.Flags().BoolVar(&flags.stdout, "stdout", false, "Stream each regenerated document to stdout as NDJSON instead of writing files")
+
+	cmd.AddCommand(newWatchStopCmd())
+	cmd.AddCommand(newWatchStatusCmd())
 
 	return cmd
 }
@@ -6,14 +6,22 @@ import (
 	"sort"
 	"strings"
 	"sync"
+
+	"github.com/dwrtz/sink/internal/processor"
 )
 
 // Stats represents statistics about file extensions in the codebase
 type Stats struct {
 	Extensions     map[string]int            // Map of extensions to count
 	DirectoryCount map[string]map[string]int // Map of directories to extension counts
+	DirectorySize  map[string]int64          // Map of directories to the size of their files
 	TotalFiles     int                       // Total number of files
 	TotalSize      int64                     // Total size in bytes
+	Tokens         map[string]int            // Map of extensions to token counts
+	FileTokens     map[string]int            // Map of file paths to token counts
+	FileSizes      map[string]int64          // Map of file paths to sizes in bytes
+	TotalTokens    int                       // Total number of tokens
+	Languages      map[string]*LanguageStats // Map of detected languages to their size
 }
 
 // Result holds the analysis results in different formats
@@ -33,36 +41,34 @@ func New() *Analyzer {
 	return &Analyzer{}
 }
 
-// Analyze processes files and generates statistics
-func (a *Analyzer) Analyze(files []string) (*Stats, error) {
+// Analyze generates statistics from processed files, using the size and
+// language the file processor already determined
+func (a *Analyzer) Analyze(files []processor.FileInfo) (*Stats, error) {
 	stats := &Stats{
 		Extensions:     make(map[string]int),
 		DirectoryCount: make(map[string]map[string]int),
+		DirectorySize:  make(map[string]int64),
+		Tokens:         make(map[string]int),
+		FileTokens:     make(map[string]int),
+		FileSizes:      make(map[string]int64),
+		Languages:      make(map[string]*LanguageStats),
 	}
 
-	// Use a WaitGroup for concurrent processing
-	var wg sync.WaitGroup
-	// Process files concurrently
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	for _, file := range files {
-		wg.Add(1)
-		go func(filepath string) {
-			defer wg.Done()
-			a.processFile(filepath, stats)
-		}(file)
+		a.processFile(file, stats)
 	}
-	wg.Wait()
 
 	return stats, nil
 }
 
-// processFile analyzes a single file and updates statistics
-func (a *Analyzer) processFile(path string, stats *Stats) {
+// processFile adds a single file to the statistics
+func (a *Analyzer) processFile(file processor.FileInfo, stats *Stats) {
+	path := file.Path
 	ext := filepath.Ext(path)
 	dir := filepath.Dir(path)
-
-	// Thread-safe updates to stats
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	size := file.Size
 
 	// Update extension count
 	stats.Extensions[ext]++
@@ -73,6 +79,21 @@ func (a *Analyzer) processFile(path string, stats *Stats) {
 		stats.DirectoryCount[dir] = make(map[string]int)
 	}
 	stats.DirectoryCount[dir][ext]++
+	stats.DirectorySize[dir] += size
+	stats.FileSizes[path] = size
+	stats.TotalSize += size
+
+	addLanguage(stats, file)
+}
+
+// AddTokens records the token count of a file in the extension statistics
+func (a *Analyzer) AddTokens(stats *Stats, path string, count int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats.Tokens[filepath.Ext(path)] += count
+	stats.FileTokens[path] += count
+	stats.TotalTokens += count
 }
 
 // FormatFlat returns a flat view of extension statistics
@@ -89,11 +110,15 @@ func (a *Analyzer) FormatFlat(stats *Stats) string {
 	// Build output
 	for _, ext := range extensions {
 		count := stats.Extensions[ext]
+		line := fmt.Sprintf("%s: %d files", ext, count)
 		if count == 1 {
-			result = append(result, fmt.Sprintf("%s: 1 file", ext))
-		} else {
-			result = append(result, fmt.Sprintf("%s: %d files", ext, count))
+			line = fmt.Sprintf("%s: 1 file", ext)
+		}
+		if stats.TotalTokens > 0 {
+			tokens := stats.Tokens[ext]
+			line += fmt.Sprintf(", %d tokens (%.1f%%)", tokens, float64(tokens)*100/float64(stats.TotalTokens))
 		}
+		result = append(result, line)
 	}
 
 	return strings.Join(result, "\n")
@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/gitinfo"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// FormatChurn lists the n files among files changed by the most commits,
+// with the date of their last change
+func (a *Analyzer) FormatChurn(churn map[string]*gitinfo.Churn, files []processor.FileInfo, n int) string {
+	var changed []processor.FileInfo
+	for _, file := range files {
+		if churn[file.Path] != nil {
+			changed = append(changed, file)
+		}
+	}
+	if len(changed) == 0 {
+		return "No commits found for the analyzed files"
+	}
+
+	sort.SliceStable(changed, func(i, j int) bool {
+		a, b := churn[changed[i].Path], churn[changed[j].Path]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.LastChange.After(b.LastChange)
+	})
+	if len(changed) > n {
+		changed = changed[:n]
+	}
+
+	width := len(fmt.Sprint(churn[changed[0].Path].Commits))
+	lines := []string{fmt.Sprintf("Most changed %d files:", len(changed))}
+	for _, file := range changed {
+		c := churn[file.Path]
+		lines = append(lines, fmt.Sprintf("  %*d commits  last %s  %s", width, c.Commits, c.LastChange.Format("2006-01-02"), file.RelPath))
+	}
+	return strings.Join(lines, "\n")
+}
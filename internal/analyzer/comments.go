@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/processor/comments"
+)
+
+// CommentStats compares files before and after comment stripping
+type CommentStats struct {
+	Lines         int   // Non-blank lines
+	CommentLines  int   // Non-blank lines that only hold comments
+	Bytes         int64 // Size before stripping
+	StrippedBytes int64 // Size after stripping
+}
+
+func (c *CommentStats) add(file processor.FileInfo, stripped string) {
+	lines := countNonBlank(file.Content)
+	c.Lines += lines
+	c.CommentLines += lines - countNonBlank(stripped)
+	c.Bytes += int64(len(file.Content))
+	c.StrippedBytes += int64(len(stripped))
+}
+
+// CommentRatios strips comments from every file in a language supported by
+// comment stripping and aggregates the results by language and by directory
+// relative to the repository root
+func (a *Analyzer) CommentRatios(files []processor.FileInfo) (byLanguage, byDir map[string]*CommentStats) {
+	byLanguage = make(map[string]*CommentStats)
+	byDir = make(map[string]*CommentStats)
+	for _, file := range files {
+		if !comments.Supported(file.Language) {
+			continue
+		}
+		stripped := comments.StripComments(file.Content, file.Language)
+
+		dir := filepath.ToSlash(filepath.Dir(file.RelPath))
+		for key, m := range map[string]map[string]*CommentStats{file.Language: byLanguage, dir: byDir} {
+			if m[key] == nil {
+				m[key] = &CommentStats{}
+			}
+			m[key].add(file, stripped)
+		}
+	}
+	return byLanguage, byDir
+}
+
+// FormatComments reports the share of comment lines and the bytes
+// --strip-comments would save, by language and by directory
+func (a *Analyzer) FormatComments(byLanguage, byDir map[string]*CommentStats) string {
+	if len(byLanguage) == 0 {
+		return "No files in a language supported by comment stripping"
+	}
+
+	lines := []string{"Comments by language:"}
+	lines = append(lines, formatCommentRows(byLanguage)...)
+	lines = append(lines, "", "Comments by directory:")
+	lines = append(lines, formatCommentRows(byDir)...)
+	return strings.Join(lines, "\n")
+}
+
+func formatCommentRows(stats map[string]*CommentStats) []string {
+	keys := make([]string, 0, len(stats))
+	width := 0
+	for key := range stats {
+		keys = append(keys, key)
+		width = max(width, len(key))
+	}
+	sort.Strings(keys)
+
+	rows := make([]string, len(keys))
+	for i, key := range keys {
+		c := stats[key]
+		rows[i] = fmt.Sprintf("  %-*s  %5.1f%% comment lines (%d of %d), --strip-comments saves %.1f%% of bytes",
+			width, key, percent(float64(c.CommentLines), float64(c.Lines)), c.CommentLines, c.Lines,
+			percent(float64(c.Bytes-c.StrippedBytes), float64(c.Bytes)))
+	}
+	return rows
+}
+
+// countNonBlank counts lines that contain more than whitespace
+func countNonBlank(content string) int {
+	n := 0
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// FunctionComplexity is the cyclomatic complexity of a Go function
+type FunctionComplexity struct {
+	Path       string // Path relative to the repository root
+	Line       int
+	Function   string // Function name, with the receiver type for methods
+	Complexity int
+}
+
+// GoComplexity parses the Go files among files and returns the cyclomatic
+// complexity of every function, most complex first. Files that fail to
+// parse are skipped.
+func (a *Analyzer) GoComplexity(files []processor.FileInfo) []FunctionComplexity {
+	var results []FunctionComplexity
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if file.Ext != ".go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Path, file.Content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			results = append(results, FunctionComplexity{
+				Path:       file.RelPath,
+				Line:       fset.Position(fn.Pos()).Line,
+				Function:   funcName(fn),
+				Complexity: cyclomatic(fn),
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Complexity > results[j].Complexity
+	})
+	return results
+}
+
+// cyclomatic counts the decision points of a function plus one. Closures are
+// counted as part of the enclosing function.
+func cyclomatic(fn *ast.FuncDecl) int {
+	complexity := 1
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// funcName returns the name of a function, qualified with the receiver type
+// for methods
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if index, ok := typ.(*ast.IndexExpr); ok {
+		typ = index.X
+	}
+	if index, ok := typ.(*ast.IndexListExpr); ok {
+		typ = index.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// FormatComplexity summarizes the analyzed functions and lists those whose
+// complexity exceeds threshold
+func (a *Analyzer) FormatComplexity(functions []FunctionComplexity, threshold int) string {
+	if len(functions) == 0 {
+		return "No Go functions found"
+	}
+
+	total := 0
+	var hotspots []FunctionComplexity
+	for _, fn := range functions {
+		total += fn.Complexity
+		if fn.Complexity > threshold {
+			hotspots = append(hotspots, fn)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("Go complexity: %d functions, average %.1f, maximum %d",
+		len(functions), float64(total)/float64(len(functions)), functions[0].Complexity)}
+	if len(hotspots) == 0 {
+		lines = append(lines, fmt.Sprintf("No functions above complexity %d", threshold))
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, fmt.Sprintf("Functions above complexity %d:", threshold))
+	width := len(fmt.Sprint(hotspots[0].Complexity))
+	for _, fn := range hotspots {
+		lines = append(lines, fmt.Sprintf("  %*d  %s (%s:%d)", width, fn.Complexity, fn.Function, fn.Path, fn.Line))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// csvRow aggregates the files of one extension in one directory
+type csvRow struct {
+	dir    string
+	ext    string
+	files  int
+	bytes  int64
+	tokens int
+}
+
+// WriteCSV writes one row per directory and extension with the columns dir,
+// ext, files, bytes and tokens. Directories are relative to root; the tokens
+// column is empty when tokens were not counted.
+func (a *Analyzer) WriteCSV(w io.Writer, stats *Stats, root string) error {
+	rows := make(map[[2]string]*csvRow)
+	for path, size := range stats.FileSizes {
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			dir = filepath.Dir(path)
+		}
+		key := [2]string{filepath.ToSlash(dir), filepath.Ext(path)}
+		row, ok := rows[key]
+		if !ok {
+			row = &csvRow{dir: key[0], ext: key[1]}
+			rows[key] = row
+		}
+		row.files++
+		row.bytes += size
+		row.tokens += stats.FileTokens[path]
+	}
+
+	sorted := make([]*csvRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].dir != sorted[j].dir {
+			return sorted[i].dir < sorted[j].dir
+		}
+		return sorted[i].ext < sorted[j].ext
+	})
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"dir", "ext", "files", "bytes", "tokens"}); err != nil {
+		return err
+	}
+	for _, row := range sorted {
+		tokens := ""
+		if stats.TotalTokens > 0 {
+			tokens = strconv.Itoa(row.tokens)
+		}
+		record := []string{row.dir, row.ext, strconv.Itoa(row.files), strconv.FormatInt(row.bytes, 10), tokens}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
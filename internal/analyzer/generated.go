@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// vendoredDirs are directory names that usually hold vendored or generated code
+var vendoredDirs = map[string]string{
+	"vendor":           "vendored directory",
+	"node_modules":     "vendored directory",
+	"third_party":      "vendored directory",
+	"bower_components": "vendored directory",
+	"mocks":            "generated mocks",
+	"__mocks__":        "generated mocks",
+	"generated":        "generated directory",
+	"dist":             "build output",
+}
+
+// generatedFilePatterns are file name patterns of generated files
+var generatedFilePatterns = []struct {
+	pattern string
+	reason  string
+}{
+	{"*.pb.go", "generated protobuf"},
+	{"*_pb.go", "generated protobuf"},
+	{"*_pb2.py", "generated protobuf"},
+	{"*_generated.go", "generated code"},
+	{"*.gen.go", "generated code"},
+	{"*_mock.go", "generated mocks"},
+	{"mock_*.go", "generated mocks"},
+	{"*.min.js", "minified bundle"},
+	{"*.min.css", "minified bundle"},
+	{"package-lock.json", "lock file"},
+	{"yarn.lock", "lock file"},
+	{"pnpm-lock.yaml", "lock file"},
+	{"go.sum", "lock file"},
+}
+
+// generatedHeader matches the standard Go "Code generated ... DO NOT EDIT." marker
+var generatedHeader = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// GeneratedFinding describes vendored or generated content and the exclude
+// pattern that would remove it
+type GeneratedFinding struct {
+	Pattern string // Suggested exclude pattern
+	Reason  string
+	Files   int
+	Size    int64
+	Tokens  int
+}
+
+// DetectGenerated flags directories and files that look vendored or
+// generated, aggregating their size and token counts per suggested pattern
+func (a *Analyzer) DetectGenerated(files []processor.FileInfo, stats *Stats) []GeneratedFinding {
+	findings := make(map[string]*GeneratedFinding)
+
+	for _, file := range files {
+		pattern, reason := classifyGenerated(file)
+		if pattern == "" {
+			continue
+		}
+
+		finding, ok := findings[pattern]
+		if !ok {
+			finding = &GeneratedFinding{Pattern: pattern, Reason: reason}
+			findings[pattern] = finding
+		}
+		finding.Files++
+		finding.Size += file.Size
+		finding.Tokens += stats.FileTokens[file.Path]
+	}
+
+	var result []GeneratedFinding
+	for _, finding := range findings {
+		result = append(result, *finding)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Pattern < result[j].Pattern
+	})
+
+	return result
+}
+
+// classifyGenerated returns the suggested exclude pattern and reason for a
+// vendored or generated file, or an empty pattern otherwise
+func classifyGenerated(file processor.FileInfo) (string, string) {
+	rel := filepath.ToSlash(file.RelPath)
+
+	// Vendored directories anywhere in the path
+	parts := strings.Split(rel, "/")
+	for i, part := range parts[:len(parts)-1] {
+		if reason, ok := vendoredDirs[part]; ok {
+			return strings.Join(parts[:i+1], "/") + "/**", reason
+		}
+	}
+
+	base := filepath.Base(rel)
+	for _, p := range generatedFilePatterns {
+		if matched, _ := filepath.Match(p.pattern, base); matched {
+			return p.pattern, p.reason
+		}
+	}
+
+	// Go files carrying the standard generated-code header
+	if file.Ext == ".go" && generatedHeader.MatchString(file.Content) {
+		return rel, "generated code"
+	}
+
+	return "", ""
+}
+
+// FormatGenerated renders generated content findings along with the exclude
+// patterns to add to sink-config.yaml
+func (a *Analyzer) FormatGenerated(findings []GeneratedFinding, stats *Stats) string {
+	if len(findings) == 0 {
+		return ""
+	}
+
+	var result []string
+	result = append(result, "Vendored or generated content:")
+	for _, f := range findings {
+		count := fmt.Sprintf("%d files", f.Files)
+		if f.Files == 1 {
+			count = "1 file"
+		}
+		line := fmt.Sprintf("  %s (%s): %s, %s", f.Pattern, f.Reason, count, formatSize(f.Size))
+		if stats.TotalSize > 0 {
+			line += fmt.Sprintf(" (%.1f%% of size)", float64(f.Size)*100/float64(stats.TotalSize))
+		}
+		if stats.TotalTokens > 0 {
+			line += fmt.Sprintf(", %d tokens (%.1f%%)", f.Tokens, float64(f.Tokens)*100/float64(stats.TotalTokens))
+		}
+		result = append(result, line)
+	}
+
+	result = append(result, "", "Suggested additions to sink-config.yaml:", "exclude-patterns:")
+	for _, f := range findings {
+		result = append(result, fmt.Sprintf("  - %q", f.Pattern))
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// formatSize renders a byte count in human readable units
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// jsonReport is the machine-readable form of the analysis
+type jsonReport struct {
+	TotalFiles  int             `json:"total_files"`
+	TotalSize   int64           `json:"total_size"`
+	TotalTokens int             `json:"total_tokens,omitempty"`
+	Extensions  []jsonExtension `json:"extensions"`
+	Languages   []jsonLanguage  `json:"languages"`
+	Directories []jsonDirectory `json:"directories"`
+	Generated   []jsonGenerated `json:"generated,omitempty"`
+}
+
+type jsonExtension struct {
+	Extension string `json:"extension"`
+	Files     int    `json:"files"`
+	Tokens    int    `json:"tokens,omitempty"`
+}
+
+type jsonLanguage struct {
+	Language     string  `json:"language"`
+	Files        int     `json:"files"`
+	Bytes        int64   `json:"bytes"`
+	Lines        int     `json:"lines"`
+	BytesPercent float64 `json:"bytes_percent"`
+	LinesPercent float64 `json:"lines_percent"`
+}
+
+type jsonDirectory struct {
+	Path       string         `json:"path"`
+	Files      int            `json:"files"`
+	Size       int64          `json:"size"`
+	Tokens     int            `json:"tokens,omitempty"`
+	Extensions map[string]int `json:"extensions"`
+}
+
+type jsonGenerated struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+	Files   int    `json:"files"`
+	Size    int64  `json:"size"`
+	Tokens  int    `json:"tokens,omitempty"`
+}
+
+// FormatJSON returns the statistics and generated content findings as an
+// indented JSON document. Directory statistics cover only the files directly
+// in each directory, whose path is relative to root.
+func (a *Analyzer) FormatJSON(stats *Stats, root string, findings []GeneratedFinding) (string, error) {
+	report := jsonReport{
+		TotalFiles:  stats.TotalFiles,
+		TotalSize:   stats.TotalSize,
+		TotalTokens: stats.TotalTokens,
+		Extensions:  []jsonExtension{},
+		Languages:   []jsonLanguage{},
+		Directories: []jsonDirectory{},
+	}
+
+	totalBytes, totalLines := languageTotals(stats)
+	for _, language := range languagesByBytes(stats) {
+		ls := stats.Languages[language]
+		report.Languages = append(report.Languages, jsonLanguage{
+			Language:     language,
+			Files:        ls.Files,
+			Bytes:        ls.Bytes,
+			Lines:        ls.Lines,
+			BytesPercent: percent(float64(ls.Bytes), float64(totalBytes)),
+			LinesPercent: percent(float64(ls.Lines), float64(totalLines)),
+		})
+	}
+
+	for _, ext := range sortedKeys(stats.Extensions) {
+		report.Extensions = append(report.Extensions, jsonExtension{
+			Extension: ext,
+			Files:     stats.Extensions[ext],
+			Tokens:    stats.Tokens[ext],
+		})
+	}
+
+	dirTokens := make(map[string]int)
+	for path, count := range stats.FileTokens {
+		dirTokens[filepath.Dir(path)] += count
+	}
+
+	dirs := make([]string, 0, len(stats.DirectoryCount))
+	for dir := range stats.DirectoryCount {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			rel = dir
+		}
+		files := 0
+		for _, count := range stats.DirectoryCount[dir] {
+			files += count
+		}
+		report.Directories = append(report.Directories, jsonDirectory{
+			Path:       filepath.ToSlash(rel),
+			Files:      files,
+			Size:       stats.DirectorySize[dir],
+			Tokens:     dirTokens[dir],
+			Extensions: stats.DirectoryCount[dir],
+		})
+	}
+
+	for _, f := range findings {
+		report.Generated = append(report.Generated, jsonGenerated{
+			Pattern: f.Pattern,
+			Reason:  f.Reason,
+			Files:   f.Files,
+			Size:    f.Size,
+			Tokens:  f.Tokens,
+		})
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// LanguageStats holds the size of a language in the codebase
+type LanguageStats struct {
+	Files int
+	Bytes int64
+	Lines int
+}
+
+// addLanguage records a file's bytes and lines under the language detected
+// by the file processor
+func addLanguage(stats *Stats, file processor.FileInfo) {
+	language := file.Language
+	if language == "" {
+		language = "unknown"
+	}
+	ls, ok := stats.Languages[language]
+	if !ok {
+		ls = &LanguageStats{}
+		stats.Languages[language] = ls
+	}
+	ls.Files++
+	ls.Bytes += file.Size
+	ls.Lines += countLines(file.Content)
+}
+
+// languagesByBytes returns the recorded languages, largest first
+func languagesByBytes(stats *Stats) []string {
+	languages := make([]string, 0, len(stats.Languages))
+	for language := range stats.Languages {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		a, b := stats.Languages[languages[i]], stats.Languages[languages[j]]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return languages[i] < languages[j]
+	})
+	return languages
+}
+
+// languageTotals returns the total bytes and lines over all languages
+func languageTotals(stats *Stats) (int64, int) {
+	var bytes int64
+	var lines int
+	for _, ls := range stats.Languages {
+		bytes += ls.Bytes
+		lines += ls.Lines
+	}
+	return bytes, lines
+}
+
+// FormatLanguages returns each language's share of the codebase by bytes and
+// by lines, similar to GitHub's language bar
+func (a *Analyzer) FormatLanguages(stats *Stats) string {
+	if len(stats.Languages) == 0 {
+		return ""
+	}
+	totalBytes, totalLines := languageTotals(stats)
+
+	languages := languagesByBytes(stats)
+	width := 0
+	for _, language := range languages {
+		width = max(width, len(language))
+	}
+
+	lines := []string{"Languages:"}
+	for _, language := range languages {
+		ls := stats.Languages[language]
+		lines = append(lines, fmt.Sprintf("  %-*s  %5.1f%% bytes  %5.1f%% lines",
+			width, language, percent(float64(ls.Bytes), float64(totalBytes)), percent(float64(ls.Lines), float64(totalLines))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func percent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part * 100 / total
+}
+
+// countLines counts lines, including a final line without a newline
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	n := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
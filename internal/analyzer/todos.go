@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// todoMarker matches TODO-style markers and captures the marker and the note
+// that follows it
+var todoMarker = regexp.MustCompile(`\b(TODO|FIXME|HACK|XXX)\b(?:\([^)]*\))?:?\s*(.*)`)
+
+// Todo is a TODO-style marker found in a file
+type Todo struct {
+	Path   string // Path relative to the repository root
+	Line   int
+	Marker string
+	Text   string
+}
+
+// FindTodos scans files for TODO, FIXME, HACK and XXX markers, returning them
+// in file order
+func (a *Analyzer) FindTodos(files []processor.FileInfo) []Todo {
+	var todos []Todo
+	for _, file := range files {
+		for i, line := range strings.Split(file.Content, "\n") {
+			m := todoMarker.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			text := strings.TrimSpace(m[2])
+			text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+			text = strings.TrimSpace(strings.TrimSuffix(text, "-->"))
+			todos = append(todos, Todo{
+				Path:   file.RelPath,
+				Line:   i + 1,
+				Marker: m[1],
+				Text:   text,
+			})
+		}
+	}
+	return todos
+}
+
+// FormatTodos lists markers grouped by file
+func (a *Analyzer) FormatTodos(todos []Todo) string {
+	if len(todos) == 0 {
+		return "No TODOs found"
+	}
+
+	var lines []string
+	current := ""
+	for _, todo := range todos {
+		if todo.Path != current {
+			if current != "" {
+				lines = append(lines, "")
+			}
+			lines = append(lines, todo.Path)
+			current = todo.Path
+		}
+		lines = append(lines, fmt.Sprintf("  %d: %s %s", todo.Line, todo.Marker, todo.Text))
+	}
+	return fmt.Sprintf("TODOs (%d):\n%s", len(todos), strings.Join(lines, "\n"))
+}
+
+// FormatTodosMarkdown renders markers as a "Known TODOs" markdown section, or
+// an empty string if there are none
+func (a *Analyzer) FormatTodosMarkdown(todos []Todo) string {
+	if len(todos) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("# Known TODOs\n")
+	current := ""
+	for _, todo := range todos {
+		if todo.Path != current {
+			b.WriteString(fmt.Sprintf("\n## %s\n\n", todo.Path))
+			current = todo.Path
+		}
+		b.WriteString(fmt.Sprintf("- Line %d: %s %s\n", todo.Line, todo.Marker, todo.Text))
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FormatTopFiles returns a table of the n files with the most tokens, with
+// paths relative to root. It returns an empty string if tokens were not
+// counted.
+func (a *Analyzer) FormatTopFiles(stats *Stats, root string, n int) string {
+	if stats.TotalTokens == 0 || n <= 0 {
+		return ""
+	}
+
+	values := make(map[string]int64, len(stats.FileTokens))
+	for path, count := range stats.FileTokens {
+		values[path] = int64(count)
+	}
+	return formatRanking("Top %d files by tokens", values, int64(stats.TotalTokens), root, n)
+}
+
+// FormatLargest returns tables of the n largest files by bytes and, if
+// tokens were counted, by tokens
+func (a *Analyzer) FormatLargest(stats *Stats, root string, n int) string {
+	if n <= 0 || len(stats.FileSizes) == 0 {
+		return ""
+	}
+
+	var total int64
+	for _, size := range stats.FileSizes {
+		total += size
+	}
+	tables := []string{formatRanking("Largest %d files by bytes", stats.FileSizes, total, root, n)}
+
+	if stats.TotalTokens > 0 {
+		values := make(map[string]int64, len(stats.FileTokens))
+		for path, count := range stats.FileTokens {
+			values[path] = int64(count)
+		}
+		tables = append(tables, formatRanking("Largest %d files by tokens", values, int64(stats.TotalTokens), root, n))
+	}
+
+	return strings.Join(tables, "\n\n")
+}
+
+// formatRanking renders the n paths with the highest values as a table of
+// value, share of total and path relative to root. The title is a format
+// string receiving the number of rows.
+func formatRanking(title string, values map[string]int64, total int64, root string, n int) string {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if values[paths[i]] != values[paths[j]] {
+			return values[paths[i]] > values[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	if len(paths) > n {
+		paths = paths[:n]
+	}
+	if len(paths) == 0 {
+		return ""
+	}
+
+	width := len(fmt.Sprint(values[paths[0]]))
+	lines := []string{fmt.Sprintf(title+":", len(paths))}
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		value := values[path]
+		lines = append(lines, fmt.Sprintf("  %*d  %5.1f%%  %s", width, value, percent(float64(value), float64(total)), rel))
+	}
+	return strings.Join(lines, "\n")
+}
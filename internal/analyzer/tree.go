@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dirStats holds the statistics of a directory and everything beneath it
+type dirStats struct {
+	name       string
+	children   map[string]*dirStats
+	extensions map[string]int
+	files      int
+	tokens     int
+}
+
+func newDirStats(name string) *dirStats {
+	return &dirStats{
+		name:       name,
+		children:   make(map[string]*dirStats),
+		extensions: make(map[string]int),
+	}
+}
+
+// FormatTree returns a hierarchical view of the statistics with the file
+// count and extension breakdown of each directory, including its
+// subdirectories. Directories are shown relative to root.
+func (a *Analyzer) FormatTree(stats *Stats, root string) string {
+	tree := newDirStats(".")
+
+	// walk returns the nodes from the root down to dir, creating missing ones
+	walk := func(dir string) []*dirStats {
+		nodes := []*dirStats{tree}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == "." {
+			return nodes
+		}
+		node := tree
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = newDirStats(part)
+				node.children[part] = child
+			}
+			node = child
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	for dir, extensions := range stats.DirectoryCount {
+		for _, node := range walk(dir) {
+			for ext, count := range extensions {
+				node.extensions[ext] += count
+				node.files += count
+			}
+		}
+	}
+	for path, count := range stats.FileTokens {
+		for _, node := range walk(filepath.Dir(path)) {
+			node.tokens += count
+		}
+	}
+
+	var lines []string
+	tree.format(&lines, 0, stats.TotalTokens > 0)
+	return strings.Join(lines, "\n")
+}
+
+func (d *dirStats) format(lines *[]string, depth int, showTokens bool) {
+	name := d.name
+	if depth > 0 {
+		name += "/"
+	}
+
+	files := fmt.Sprintf("%d files", d.files)
+	if d.files == 1 {
+		files = "1 file"
+	}
+	line := fmt.Sprintf("%s%s (%s: %s)", strings.Repeat("  ", depth), name, files, d.extensionBreakdown())
+	if showTokens {
+		line += fmt.Sprintf(", %d tokens", d.tokens)
+	}
+	*lines = append(*lines, line)
+
+	names := make([]string, 0, len(d.children))
+	for name := range d.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.children[name].format(lines, depth+1, showTokens)
+	}
+}
+
+// extensionBreakdown lists extensions by descending file count
+func (d *dirStats) extensionBreakdown() string {
+	extensions := make([]string, 0, len(d.extensions))
+	for ext := range d.extensions {
+		extensions = append(extensions, ext)
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		if d.extensions[extensions[i]] != d.extensions[extensions[j]] {
+			return d.extensions[extensions[i]] > d.extensions[extensions[j]]
+		}
+		return extensions[i] < extensions[j]
+	})
+
+	parts := make([]string, len(extensions))
+	for i, ext := range extensions {
+		name := ext
+		if name == "" {
+			name = "(no extension)"
+		}
+		parts[i] = fmt.Sprintf("%s %d", name, d.extensions[ext])
+	}
+	return strings.Join(parts, ", ")
+}
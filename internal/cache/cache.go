@@ -0,0 +1,325 @@
+// Package cache keeps processed file contents and token counts between runs
+// under .sink/cache, so unchanged files are neither re-processed nor
+// re-counted.
+//
+// The index (files.gob) records each file's size, modification time and
+// source hash along with the hash of its processed content, which is stored
+// on its own under objects/. Contents are only read for the files a run
+// includes.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// version is bumped whenever the format or meaning of entries changes
+const version = 2
+
+// Entry is a processed file, valid while its size and modification time,
+// or else the hash of its source, are unchanged
+type Entry struct {
+	Size    int64
+	ModTime time.Time
+	// SHA-256 of the file as read, before processing
+	SourceHash string
+	Content    string
+	Language   string
+}
+
+// record is the index entry of a file; its content is stored separately
+type record struct {
+	Size        int64
+	ModTime     time.Time
+	SourceHash  string
+	ContentHash string
+	Language    string
+}
+
+// Cache holds the entries of a repository for one set of processing
+// settings. It is safe for concurrent use.
+type Cache struct {
+	dir string
+
+	mu       sync.Mutex
+	settings string
+	files    map[string]record
+	// Token counts keyed by encoding and content hash
+	tokens map[string]int
+	// Keys used since the cache was loaded; the rest are dropped on save
+	usedFiles  map[string]bool
+	usedTokens map[string]bool
+}
+
+// stored is the on-disk form of the index
+type stored struct {
+	Version  int
+	Settings string
+	Files    map[string]record
+	Tokens   map[string]int
+}
+
+// loaded keeps caches in memory across generations in the same process,
+// such as in watch mode
+var (
+	loaded   = make(map[string]*Cache)
+	loadedMu sync.Mutex
+)
+
+// Load returns the cache in dir for the given processing settings. A
+// missing, unreadable or outdated cache, or one written with different
+// settings, starts out empty.
+func Load(dir, settings string) *Cache {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	if c, ok := loaded[dir]; ok && c.settings == settings {
+		c.resetUsage()
+		return c
+	}
+
+	c := &Cache{
+		dir:      dir,
+		settings: settings,
+		files:    make(map[string]record),
+		tokens:   make(map[string]int),
+	}
+	if f, err := os.Open(c.indexPath()); err == nil {
+		var s stored
+		if gob.NewDecoder(f).Decode(&s) == nil && s.Version == version && s.Settings == settings {
+			c.files = s.Files
+			c.tokens = s.Tokens
+		}
+		f.Close()
+	}
+	c.resetUsage()
+	loaded[dir] = c
+	return c
+}
+
+func (c *Cache) indexPath() string {
+	return filepath.Join(c.dir, "files.gob")
+}
+
+// objectPath returns where processed content with the given hash is stored
+func (c *Cache) objectPath(hash string) string {
+	return filepath.Join(c.dir, "objects", hash[:2], hash)
+}
+
+func (c *Cache) resetUsage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usedFiles = make(map[string]bool)
+	c.usedTokens = make(map[string]bool)
+}
+
+// Lookup returns the entry for relPath if the file's size and modification
+// time still match
+func (c *Cache) Lookup(relPath string, size int64, modTime time.Time) (Entry, bool) {
+	c.mu.Lock()
+	rec, ok := c.files[relPath]
+	c.mu.Unlock()
+	if !ok || rec.Size != size || !rec.ModTime.Equal(modTime) {
+		return Entry{}, false
+	}
+	return c.use(relPath, rec)
+}
+
+// LookupSource returns the entry for relPath if its source hash matches,
+// such as for a file touched or checked out again without changes, and
+// records the file's new size and modification time
+func (c *Cache) LookupSource(relPath, sourceHash string, size int64, modTime time.Time) (Entry, bool) {
+	c.mu.Lock()
+	rec, ok := c.files[relPath]
+	if ok && rec.SourceHash == sourceHash {
+		rec.Size, rec.ModTime = size, modTime
+		c.files[relPath] = rec
+	}
+	c.mu.Unlock()
+	if !ok || rec.SourceHash != sourceHash {
+		return Entry{}, false
+	}
+	return c.use(relPath, rec)
+}
+
+// use reads the content of rec and marks relPath as used
+func (c *Cache) use(relPath string, rec record) (Entry, bool) {
+	content, err := os.ReadFile(c.objectPath(rec.ContentHash))
+	if err != nil {
+		return Entry{}, false
+	}
+	c.mu.Lock()
+	c.usedFiles[relPath] = true
+	c.mu.Unlock()
+	return Entry{
+		Size:       rec.Size,
+		ModTime:    rec.ModTime,
+		SourceHash: rec.SourceHash,
+		Content:    string(content),
+		Language:   rec.Language,
+	}, true
+}
+
+// Store records the processed form of relPath. Failing to write the
+// content only means the file is processed again next time.
+func (c *Cache) Store(relPath string, entry Entry) {
+	hash := Hash([]byte(entry.Content))
+	if err := c.writeObject(hash, entry.Content); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[relPath] = record{
+		Size:        entry.Size,
+		ModTime:     entry.ModTime,
+		SourceHash:  entry.SourceHash,
+		ContentHash: hash,
+		Language:    entry.Language,
+	}
+	c.usedFiles[relPath] = true
+}
+
+// writeObject stores content under its hash unless it is already there
+func (c *Cache) writeObject(hash, content string) error {
+	path := c.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Concurrent writers of the same content each rename a complete file
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// TokenCount returns the cached token count of content under encoding
+func (c *Cache) TokenCount(encoding, content string) (int, bool) {
+	key := tokenKey(encoding, content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count, ok := c.tokens[key]
+	if ok {
+		c.usedTokens[key] = true
+	}
+	return count, ok
+}
+
+// StoreTokenCount records the token count of content under encoding
+func (c *Cache) StoreTokenCount(encoding, content string, count int) {
+	key := tokenKey(encoding, content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = count
+	c.usedTokens[key] = true
+}
+
+// Hash returns the SHA-256 of data in hex, as used for source and content
+// hashes
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenKey(encoding, content string) string {
+	return encoding + ":" + Hash([]byte(content))
+}
+
+// Save writes the index of the entries used since the cache was loaded,
+// dropping those of deleted or excluded files along with contents no entry
+// refers to
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	referenced := make(map[string]bool)
+	for relPath, rec := range c.files {
+		if !c.usedFiles[relPath] {
+			delete(c.files, relPath)
+			continue
+		}
+		referenced[rec.ContentHash] = true
+	}
+	for key := range c.tokens {
+		if !c.usedTokens[key] {
+			delete(c.tokens, key)
+		}
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Write to a temporary file first so an interrupted save never leaves a
+	// truncated cache behind
+	tmp := c.indexPath() + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	err = gob.NewEncoder(f).Encode(stored{Version: version, Settings: c.settings, Files: c.files, Tokens: c.tokens})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.indexPath()); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	return c.pruneObjects(referenced)
+}
+
+// pruneObjects removes stored contents that are not referenced
+func (c *Cache) pruneObjects(referenced map[string]bool) error {
+	dir := filepath.Join(c.dir, "objects")
+	shards, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache objects: %w", err)
+	}
+	for _, shard := range shards {
+		shardDir := filepath.Join(dir, shard.Name())
+		objects, err := os.ReadDir(shardDir)
+		if err != nil {
+			continue
+		}
+		kept := 0
+		for _, object := range objects {
+			if referenced[object.Name()] {
+				kept++
+				continue
+			}
+			if err := os.Remove(filepath.Join(shardDir, object.Name())); err != nil {
+				return fmt.Errorf("failed to remove cache object: %w", err)
+			}
+		}
+		if kept == 0 {
+			os.Remove(shardDir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reload drops the in-memory copy of the cache in dir and loads it from
+// disk, as a new process would
+func reload(dir, settings string) *Cache {
+	loadedMu.Lock()
+	delete(loaded, dir)
+	loadedMu.Unlock()
+	return Load(dir, settings)
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := Load(dir, "s")
+	c.Store("a.go", Entry{Size: 10, ModTime: modTime, SourceHash: "src", Content: "outlined", Language: "go"})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	c = reload(dir, "s")
+
+	cases := []struct {
+		relPath string
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{relPath: "a.go", size: 10, modTime: modTime, want: true},
+		{relPath: "a.go", size: 11, modTime: modTime, want: false},
+		{relPath: "a.go", size: 10, modTime: modTime.Add(time.Second), want: false},
+		{relPath: "b.go", size: 10, modTime: modTime, want: false},
+	}
+	for _, tc := range cases {
+		entry, ok := c.Lookup(tc.relPath, tc.size, tc.modTime)
+		if ok != tc.want {
+			t.Errorf("Lookup(%s, %d, %v) hit = %t, want %t", tc.relPath, tc.size, tc.modTime, ok, tc.want)
+			continue
+		}
+		if ok && (entry.Content != "outlined" || entry.Language != "go") {
+			t.Errorf("Lookup(%s) = %+v, want the stored content and language", tc.relPath, entry)
+		}
+	}
+}
+
+func TestLookupSource(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	touched := modTime.Add(time.Hour)
+	c := Load(dir, "s")
+	c.Store("a.go", Entry{Size: 10, ModTime: modTime, SourceHash: "src", Content: "x"})
+
+	if _, ok := c.LookupSource("a.go", "other", 10, touched); ok {
+		t.Error("LookupSource() hit with a different source hash")
+	}
+	if _, ok := c.Lookup("a.go", 10, touched); ok {
+		t.Error("a failed LookupSource() re-keyed the entry")
+	}
+
+	entry, ok := c.LookupSource("a.go", "src", 12, touched)
+	if !ok || entry.Content != "x" {
+		t.Fatalf("LookupSource() = %+v, %t; want the stored entry", entry, ok)
+	}
+	if _, ok := c.Lookup("a.go", 12, touched); !ok {
+		t.Error("Lookup() missed with the size and time recorded by LookupSource()")
+	}
+	if _, ok := c.Lookup("a.go", 10, modTime); ok {
+		t.Error("Lookup() hit with the old size and time")
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := Load(dir, "redact=false")
+	c.Store("a.go", Entry{Size: 1, ModTime: modTime, Content: "x"})
+	c.StoreTokenCount("cl100k_base", "x", 1)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		settings string
+		want     bool
+	}{
+		{settings: "redact=false", want: true},
+		{settings: "redact=true", want: false},
+	}
+	for _, tc := range cases {
+		c := reload(dir, tc.settings)
+		if _, ok := c.Lookup("a.go", 1, modTime); ok != tc.want {
+			t.Errorf("Lookup() under %q hit = %t, want %t", tc.settings, ok, tc.want)
+		}
+		if _, ok := c.TokenCount("cl100k_base", "x"); ok != tc.want {
+			t.Errorf("TokenCount() under %q hit = %t, want %t", tc.settings, ok, tc.want)
+		}
+	}
+
+	// In memory too, other settings start an empty cache
+	Load(dir, "redact=false")
+	if _, ok := Load(dir, "redact=true").Lookup("a.go", 1, modTime); ok {
+		t.Error("Lookup() hit after the settings changed in the same process")
+	}
+}
+
+func TestSavePrunes(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := Load(dir, "s")
+	c.Store("kept.go", Entry{Size: 1, ModTime: modTime, Content: "kept"})
+	c.Store("deleted.go", Entry{Size: 1, ModTime: modTime, Content: "deleted"})
+	c.StoreTokenCount("cl100k_base", "deleted", 1)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An object no entry refers to, such as left by an interrupted run
+	orphan := Hash([]byte("orphan"))
+	if err := c.writeObject(orphan, "orphan"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run only includes kept.go
+	c = reload(dir, "s")
+	if _, ok := c.Lookup("kept.go", 1, modTime); !ok {
+		t.Fatal("Lookup(kept.go) missed")
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = reload(dir, "s")
+	if _, ok := c.Lookup("deleted.go", 1, modTime); ok {
+		t.Error("the entry of an unused file survived Save()")
+	}
+	if _, ok := c.TokenCount("cl100k_base", "deleted"); ok {
+		t.Error("an unused token count survived Save()")
+	}
+	cases := []struct {
+		content string
+		want    bool
+	}{
+		{content: "kept", want: true},
+		{content: "deleted", want: false},
+		{content: "orphan", want: false},
+	}
+	for _, tc := range cases {
+		_, err := os.Stat(c.objectPath(Hash([]byte(tc.content))))
+		if exists := err == nil; exists != tc.want {
+			t.Errorf("object of %q exists = %t, want %t", tc.content, exists, tc.want)
+		}
+	}
+	shards, err := os.ReadDir(filepath.Join(dir, "objects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 1 {
+		t.Errorf("%d object directories left, want 1 for the kept content", len(shards))
+	}
+}
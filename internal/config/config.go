@@ -1,10 +1,17 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/tokens"
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v3"
 )
@@ -13,45 +20,219 @@ import (
 type Config struct {
 	// Core settings
 	Output          string   `yaml:"output"`
+	Format          string   `yaml:"format"`
 	FilterPatterns  []string `yaml:"filter-patterns"`
 	ExcludePatterns []string `yaml:"exclude-patterns"`
+	PrunePatterns   []string `yaml:"prune-patterns"`
 	CaseSensitive   bool     `yaml:"case-sensitive"`
 
+	// Include files marked linguist-generated or linguist-vendored in .gitattributes
+	IncludeGenerated bool `yaml:"include-generated"`
+
+	// Skip files larger than this size, e.g. "512kb"
+	MaxFileSize string `yaml:"max-file-size"`
+
+	// Exclude test files, or include only test files, using per-language
+	// naming conventions
+	NoTests   bool `yaml:"no-tests"`
+	TestsOnly bool `yaml:"tests-only"`
+
+	// Only include files changed between this git ref and HEAD, plus
+	// uncommitted changes and, if Untracked is set, untracked files
+	Since     string `yaml:"since"`
+	Untracked bool   `yaml:"untracked"`
+	// If set, only these paths relative to the repository root are
+	// included. Set by commands such as search, never from a config file.
+	OnlyFiles []string `yaml:"-"`
+
+	// Ask for confirmation before overwriting an existing output file
+	Confirm bool `yaml:"confirm"`
+	// Replace only the region between sink markers in the existing output file
+	Inject bool `yaml:"inject"`
+	// Copy the generated output to the system clipboard
+	Clipboard bool `yaml:"clipboard"`
+	// Reuse processed contents and token counts of unchanged files from
+	// .sink/cache
+	Cache bool `yaml:"cache"`
+	// Number of files read and processed at once (0 = number of CPUs)
+	Concurrency int `yaml:"concurrency"`
+	// Hide the progress line, status messages such as token counts, and
+	// informational log messages
+	Quiet bool `yaml:"quiet"`
+	// Minimum level of log messages: debug, info, warn or error
+	LogLevel string `yaml:"log-level"`
+	// Print a summary of skipped files and directories by reason after
+	// generating, and write the full list as JSON to SkippedReport if set
+	ReportSkipped bool   `yaml:"report-skipped"`
+	SkippedReport string `yaml:"skipped-report"`
+	// Log as key=value text lines or, with json, one JSON object per line
+	LogFormat string `yaml:"log-format"`
+
 	// Processing options
 	NoCodeblock   bool `yaml:"no-codeblock"`
 	LineNumbers   bool `yaml:"line-numbers"`
 	StripComments bool `yaml:"strip-comments"`
+	Redact        bool `yaml:"redact"`
+	Outline       bool `yaml:"outline"`
+	Dedup         bool `yaml:"dedup"`
+	GroupByDir    bool `yaml:"group-by-dir"`
+	Tree          bool `yaml:"tree"`
+	FileTokens    bool `yaml:"file-tokens"`
+	Todos         bool `yaml:"todos"`
+	FrontMatter   bool `yaml:"front-matter"`
+
+	// Regular expressions whose matches are redacted from every file
+	RedactPatterns []string `yaml:"redact-patterns"`
+
+	// Shell commands transforming each file's content before it is
+	// processed, and each generated document before it is written
+	PreHook  string `yaml:"pre-hook"`
+	PostHook string `yaml:"post-hook"`
+
+	// Task description placed in an Instructions section before or after
+	// the files in markdown and plain output; the contents of
+	// InstructionsFile follow Instructions when both are set
+	Instructions         string `yaml:"instructions"`
+	InstructionsFile     string `yaml:"instructions-file"`
+	InstructionsPosition string `yaml:"instructions-position"`
+
+	// System message of the messages format, and the most tokens of files
+	// in each of its user messages (0 = all files in one message)
+	SystemPrompt  string `yaml:"system-prompt"`
+	MessageTokens int    `yaml:"message-tokens"`
+
+	// Most tokens per record of the chunks format (0 = 512), and tokens of
+	// lines repeated from the end of one chunk at the start of the next
+	ChunkTokens  int `yaml:"chunk-tokens"`
+	ChunkOverlap int `yaml:"chunk-overlap"`
+
+	// Append a section listing changes since the previous generation,
+	// optionally with diffs of modified files
+	Changelog      bool `yaml:"changelog"`
+	ChangelogDiffs bool `yaml:"changelog-diffs"`
+
+	// Git blame annotations, limited to files matching BlamePatterns if set
+	Blame         bool     `yaml:"blame"`
+	BlamePatterns []string `yaml:"blame-patterns"`
+
+	// File ordering preset
+	Order string `yaml:"order"`
+	// Sort key applied before the ordering preset, and whether to reverse it
+	Sort    string `yaml:"sort"`
+	Reverse bool   `yaml:"reverse"`
+	// Files matching earlier patterns are placed first; "!" patterns last
+	PriorityPatterns []string `yaml:"priority-patterns"`
 
 	// Token settings
 	ShowTokens    bool   `yaml:"show-tokens"`
 	TokenEncoding string `yaml:"token-encoding"`
+	// Model whose encoding replaces TokenEncoding, e.g. gpt-4o
+	EncodingForModel string `yaml:"encoding-for-model"`
+	MaxTokens        int    `yaml:"max-tokens"`
+	ChatFormat       bool   `yaml:"chat-format"`
+
+	// Which files to keep first when enforcing MaxTokens
+	BudgetStrategy string `yaml:"budget-strategy"`
+	// Split each output into numbered parts of at most this many tokens
+	SplitTokens int `yaml:"split-tokens"`
 
 	// Price estimation
 	ShowPrice    bool   `yaml:"show-price"`
 	Provider     string `yaml:"provider"`
 	Model        string `yaml:"model"`
 	OutputTokens int    `yaml:"output-tokens"`
+	// File or URL of a pricing catalog overriding the built-in prices
+	Pricing string `yaml:"pricing"`
+	// Fail instead of warning when output exceeds the model's context window
+	Strict bool `yaml:"strict"`
+	// Fail when output exceeds this many tokens (0 = no limit)
+	FailOverTokens int `yaml:"fail-over-tokens"`
 
 	// Syntax highlighting mappings
 	SyntaxMap map[string]string `yaml:"syntax-map"`
+	// Fence languages forced for files matching path globs
+	LanguageOverrides map[string]string `yaml:"language-overrides"`
 
 	// Template settings
 	TemplatePath string `yaml:"template-path"`
+	// Templates rendered once per file and around all rendered files,
+	// used when either is set and TemplatePath is not
+	FileTemplatePath     string `yaml:"file-template-path"`
+	DocumentTemplatePath string `yaml:"document-template-path"`
+	// Directory of *.tmpl files that templates include by name; its
+	// main.tmpl is rendered when no other template is set
+	TemplateDir string `yaml:"template-dir"`
+	// Values available to templates as .Vars
+	Vars map[string]string `yaml:"vars"`
+
+	// URL sink watch POSTs a JSON summary to after each regeneration
+	NotifyWebhook string `yaml:"notify-webhook"`
+
+	// Additional output targets generated from a single scan
+	Outputs []OutputTarget `yaml:"outputs"`
+
+	// Fine-tuning dataset export settings
+	Export ExportConfig `yaml:"export"`
+
+	// Named sets of settings selected with --profile
+	Profiles map[string]*Config `yaml:"profiles"`
+}
+
+// ExportConfig holds the settings of the export command
+type ExportConfig struct {
+	Chunk             string `yaml:"chunk"`
+	SystemTemplate    string `yaml:"system-template"`
+	UserTemplate      string `yaml:"user-template"`
+	AssistantTemplate string `yaml:"assistant-template"`
+}
+
+// OutputTarget describes a single generated document
+type OutputTarget struct {
+	Path                 string `yaml:"path"`
+	Format               string `yaml:"format"`
+	TemplatePath         string `yaml:"template-path"`
+	FileTemplatePath     string `yaml:"file-template-path"`
+	DocumentTemplatePath string `yaml:"document-template-path"`
+	TemplateDir          string `yaml:"template-dir"`
+}
+
+// TemplatePaths returns the template files the target is rendered with,
+// including the *.tmpl files of its template directory
+func (t OutputTarget) TemplatePaths() []string {
+	var paths []string
+	for _, p := range []string{t.TemplatePath, t.FileTemplatePath, t.DocumentTemplatePath} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if t.TemplateDir != "" {
+		// Glob only fails on malformed patterns
+		matches, _ := filepath.Glob(filepath.Join(t.TemplateDir, "*.tmpl"))
+		paths = append(paths, matches...)
+	}
+	return paths
 }
 
 // DefaultConfig returns a new Config with default values
 func DefaultConfig() *Config {
 	return &Config{
-		TokenEncoding: "cl100k_base",
-		Provider:      "openai",
-		Model:         "gpt-3.5-turbo",
-		OutputTokens:  1000,
-		SyntaxMap:     make(map[string]string),
+		TokenEncoding:     "cl100k_base",
+		Provider:          "openai",
+		Model:             "gpt-3.5-turbo",
+		OutputTokens:      1000,
+		SyntaxMap:         make(map[string]string),
+		LanguageOverrides: make(map[string]string),
+		Vars:              make(map[string]string),
 	}
 }
 
 // LoadConfig loads configuration from multiple sources with proper precedence
-func LoadConfig(cmdConfigPath string) (*Config, error) {
+// and applies the named profile, if any. Hooks run arbitrary commands and
+// the notify webhook receives the repository's paths, so unless allowHooks
+// is set they are only taken from the system and user config files: a
+// local sink-config.yaml may come with a checked-out repository, and an
+// explicit config file may too.
+func LoadConfig(cmdConfigPath, profile string, allowHooks bool) (*Config, error) {
 	config := DefaultConfig()
 
 	// 1. Load system config
@@ -69,21 +250,90 @@ func LoadConfig(cmdConfigPath string) (*Config, error) {
 	// 3. Load local config
 	localConfig, err := loadLocalConfig()
 	if err == nil {
+		if !allowHooks {
+			localConfig.dropHooks(getLocalConfigPath())
+		}
 		config.merge(localConfig)
 	}
 
 	// 4. Load explicitly specified config file
 	if cmdConfigPath != "" {
-		explicitConfig, err := loadConfigFile(cmdConfigPath)
+		explicitConfig, err := LoadFile(cmdConfigPath)
 		if err != nil {
 			return nil, fmt.Errorf("error loading specified config file: %w", err)
 		}
+		if !allowHooks {
+			explicitConfig.dropHooks(cmdConfigPath)
+		}
 		config.merge(explicitConfig)
 	}
 
+	// 5. Apply the selected profile
+	if profile != "" {
+		if err := config.ApplyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.resolveEncoding(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// dropHooks clears the hooks and notify webhook of c and its profiles,
+// warning about those found in the config file at path
+func (c *Config) dropHooks(path string) {
+	configs := []*Config{c}
+	for _, profile := range c.Profiles {
+		if profile != nil {
+			configs = append(configs, profile)
+		}
+	}
+	dropped := false
+	for _, cfg := range configs {
+		dropped = dropped || cfg.PreHook != "" || cfg.PostHook != "" || cfg.NotifyWebhook != ""
+		cfg.PreHook = ""
+		cfg.PostHook = ""
+		cfg.NotifyWebhook = ""
+	}
+	if dropped {
+		logging.Warn("ignoring hooks and notify-webhook outside the system and user configs; pass --allow-hooks to use them", "path", path)
+	}
+}
+
+// Layer is a configuration file location along with the name of the layer
+// it belongs to
+type Layer struct {
+	Name string
+	Path string
+}
+
+// Layers returns the configuration file locations in increasing order of
+// precedence. The explicit layer is only included when cmdConfigPath is set.
+func Layers(cmdConfigPath string) []Layer {
+	layers := []Layer{
+		{Name: "system", Path: getSystemConfigPath()},
+		{Name: "user", Path: getUserConfigPath()},
+		{Name: "local", Path: getLocalConfigPath()},
+	}
+	if cmdConfigPath != "" {
+		layers = append(layers, Layer{Name: "explicit", Path: cmdConfigPath})
+	}
+	return layers
+}
+
+// PluginDir returns the directory WebAssembly plugins are loaded from: the
+// plugins directory next to the user's config, or "" if there is none
+func PluginDir() string {
+	path := getUserConfigPath()
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), "plugins")
+}
+
 // getSystemConfigPath returns the path to the system-wide config
 func getSystemConfigPath() string {
 	if os.Getenv("SINK_SYSTEM_CONFIG") != "" {
@@ -119,21 +369,21 @@ func getLocalConfigPath() string {
 
 // loadSystemConfig loads the system-wide configuration
 func loadSystemConfig() (*Config, error) {
-	return loadConfigFile(getSystemConfigPath())
+	return LoadFile(getSystemConfigPath())
 }
 
 // loadUserConfig loads the user's configuration
 func loadUserConfig() (*Config, error) {
-	return loadConfigFile(getUserConfigPath())
+	return LoadFile(getUserConfigPath())
 }
 
 // loadLocalConfig loads the local configuration
 func loadLocalConfig() (*Config, error) {
-	return loadConfigFile(getLocalConfigPath())
+	return LoadFile(getLocalConfigPath())
 }
 
-// loadConfigFile loads and parses a configuration file
-func loadConfigFile(path string) (*Config, error) {
+// LoadFile loads and parses a single configuration file
+func LoadFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -147,6 +397,22 @@ func loadConfigFile(path string) (*Config, error) {
 	return config, nil
 }
 
+// CheckFile parses a configuration file strictly and returns an error
+// describing the first unknown key, which LoadFile silently ignores
+func CheckFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&Config{}); err != nil && err != io.EOF {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
 // merge merges another config into this one
 func (c *Config) merge(other *Config) {
 	if other == nil {
@@ -157,17 +423,41 @@ func (c *Config) merge(other *Config) {
 	if other.Output != "" {
 		c.Output = other.Output
 	}
+	if other.Format != "" {
+		c.Format = other.Format
+	}
 	if len(other.FilterPatterns) > 0 {
 		c.FilterPatterns = other.FilterPatterns
 	}
 	if len(other.ExcludePatterns) > 0 {
 		c.ExcludePatterns = other.ExcludePatterns
 	}
+	if len(other.PrunePatterns) > 0 {
+		c.PrunePatterns = other.PrunePatterns
+	}
 
 	// Boolean flags need special handling - they should only be overridden if explicitly set
 	if other.CaseSensitive {
 		c.CaseSensitive = true
 	}
+	if other.IncludeGenerated {
+		c.IncludeGenerated = true
+	}
+	if other.MaxFileSize != "" {
+		c.MaxFileSize = other.MaxFileSize
+	}
+	if other.NoTests {
+		c.NoTests = true
+	}
+	if other.TestsOnly {
+		c.TestsOnly = true
+	}
+	if other.Since != "" {
+		c.Since = other.Since
+	}
+	if other.Untracked {
+		c.Untracked = true
+	}
 	if other.NoCodeblock {
 		c.NoCodeblock = true
 	}
@@ -177,16 +467,130 @@ func (c *Config) merge(other *Config) {
 	if other.StripComments {
 		c.StripComments = true
 	}
+	if other.Redact {
+		c.Redact = true
+	}
+	if other.Outline {
+		c.Outline = true
+	}
+	if other.Dedup {
+		c.Dedup = true
+	}
+	if other.Cache {
+		c.Cache = true
+	}
+	if other.Concurrency != 0 {
+		c.Concurrency = other.Concurrency
+	}
+	if other.Quiet {
+		c.Quiet = true
+	}
+	if other.LogLevel != "" {
+		c.LogLevel = other.LogLevel
+	}
+	if other.ReportSkipped {
+		c.ReportSkipped = true
+	}
+	if other.SkippedReport != "" {
+		c.SkippedReport = other.SkippedReport
+	}
+	if other.LogFormat != "" {
+		c.LogFormat = other.LogFormat
+	}
+	if len(other.RedactPatterns) > 0 {
+		c.RedactPatterns = other.RedactPatterns
+	}
+	if other.GroupByDir {
+		c.GroupByDir = true
+	}
+	if other.Tree {
+		c.Tree = true
+	}
+	if other.FileTokens {
+		c.FileTokens = true
+	}
+	if other.Todos {
+		c.Todos = true
+	}
+	if other.Instructions != "" {
+		c.Instructions = other.Instructions
+	}
+	if other.InstructionsFile != "" {
+		c.InstructionsFile = other.InstructionsFile
+	}
+	if other.InstructionsPosition != "" {
+		c.InstructionsPosition = other.InstructionsPosition
+	}
+	if other.SystemPrompt != "" {
+		c.SystemPrompt = other.SystemPrompt
+	}
+	if other.MessageTokens != 0 {
+		c.MessageTokens = other.MessageTokens
+	}
+	if other.ChunkTokens != 0 {
+		c.ChunkTokens = other.ChunkTokens
+	}
+	if other.ChunkOverlap != 0 {
+		c.ChunkOverlap = other.ChunkOverlap
+	}
+	if other.FrontMatter {
+		c.FrontMatter = true
+	}
+	if other.Changelog {
+		c.Changelog = true
+	}
+	if other.ChangelogDiffs {
+		c.ChangelogDiffs = true
+	}
+	if other.Blame {
+		c.Blame = true
+	}
+	if len(other.BlamePatterns) > 0 {
+		c.BlamePatterns = other.BlamePatterns
+	}
 	if other.ShowTokens {
 		c.ShowTokens = true
 	}
 	if other.ShowPrice {
 		c.ShowPrice = true
 	}
+	if other.ChatFormat {
+		c.ChatFormat = true
+	}
+	if other.Confirm {
+		c.Confirm = true
+	}
+	if other.Inject {
+		c.Inject = true
+	}
+	if other.Clipboard {
+		c.Clipboard = true
+	}
 
 	if other.TokenEncoding != "" {
 		c.TokenEncoding = other.TokenEncoding
 	}
+	if other.EncodingForModel != "" {
+		c.EncodingForModel = other.EncodingForModel
+	}
+	if other.Pricing != "" {
+		c.Pricing = other.Pricing
+	}
+	if other.NotifyWebhook != "" {
+		c.NotifyWebhook = other.NotifyWebhook
+	}
+	if other.PreHook != "" {
+		c.PreHook = other.PreHook
+	}
+	if other.PostHook != "" {
+		c.PostHook = other.PostHook
+	}
+	if other.Strict {
+		c.Strict = true
+	}
+	if other.FailOverTokens != 0 {
+		c.FailOverTokens = other.FailOverTokens
+	}
 	if other.Provider != "" {
 		c.Provider = other.Provider
 	}
@@ -196,39 +600,244 @@ func (c *Config) merge(other *Config) {
 	if other.OutputTokens != 0 {
 		c.OutputTokens = other.OutputTokens
 	}
+	if other.MaxTokens != 0 {
+		c.MaxTokens = other.MaxTokens
+	}
 	if other.TemplatePath != "" {
 		c.TemplatePath = other.TemplatePath
 	}
+	if other.FileTemplatePath != "" {
+		c.FileTemplatePath = other.FileTemplatePath
+	}
+	if other.DocumentTemplatePath != "" {
+		c.DocumentTemplatePath = other.DocumentTemplatePath
+	}
+	if other.TemplateDir != "" {
+		c.TemplateDir = other.TemplateDir
+	}
+	if other.SplitTokens != 0 {
+		c.SplitTokens = other.SplitTokens
+	}
+	if other.BudgetStrategy != "" {
+		c.BudgetStrategy = other.BudgetStrategy
+	}
+	if other.Order != "" {
+		c.Order = other.Order
+	}
+	if other.Sort != "" {
+		c.Sort = other.Sort
+	}
+	if other.Reverse {
+		c.Reverse = true
+	}
+	if len(other.PriorityPatterns) > 0 {
+		c.PriorityPatterns = other.PriorityPatterns
+	}
+
+	if len(other.Outputs) > 0 {
+		c.Outputs = other.Outputs
+	}
+
+	if other.Export.Chunk != "" {
+		c.Export.Chunk = other.Export.Chunk
+	}
+	if other.Export.SystemTemplate != "" {
+		c.Export.SystemTemplate = other.Export.SystemTemplate
+	}
+	if other.Export.UserTemplate != "" {
+		c.Export.UserTemplate = other.Export.UserTemplate
+	}
+	if other.Export.AssistantTemplate != "" {
+		c.Export.AssistantTemplate = other.Export.AssistantTemplate
+	}
+
+	// Merge profiles, letting later layers redefine a profile entirely
+	for name, profile := range other.Profiles {
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]*Config)
+		}
+		c.Profiles[name] = profile
+	}
 
 	// Merge syntax map
 	for k, v := range other.SyntaxMap {
 		c.SyntaxMap[k] = v
 	}
+	for k, v := range other.LanguageOverrides {
+		c.LanguageOverrides[k] = v
+	}
+	for k, v := range other.Vars {
+		if c.Vars == nil {
+			c.Vars = make(map[string]string)
+		}
+		c.Vars[k] = v
+	}
+}
+
+// ApplyProfile merges the named profile over the loaded configuration
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+	c.merge(profile)
+	return nil
+}
+
+// OutputTargets returns the configured output targets. When no outputs list
+// is configured, a single target is built from Output, Format and the
+// template paths.
+func (c *Config) OutputTargets() []OutputTarget {
+	if len(c.Outputs) > 0 {
+		return c.Outputs
+	}
+	return []OutputTarget{{
+		Path:                 c.Output,
+		Format:               c.Format,
+		TemplatePath:         c.TemplatePath,
+		FileTemplatePath:     c.FileTemplatePath,
+		DocumentTemplatePath: c.DocumentTemplatePath,
+		TemplateDir:          c.TemplateDir,
+	}}
+}
+
+// resolveEncoding sets TokenEncoding to the encoding of EncodingForModel
+func (c *Config) resolveEncoding() error {
+	if c.EncodingForModel == "" {
+		return nil
+	}
+	encoding, err := tokens.EncodingForModel(c.EncodingForModel)
+	if err != nil {
+		return fmt.Errorf("invalid encoding-for-model: %w", err)
+	}
+	c.TokenEncoding = encoding
+	return nil
+}
+
+// Hash returns a short fingerprint of the effective settings, so documents
+// generated with different settings can be told apart
+func (c *Config) Hash() string {
+	// Marshaling plain config values cannot fail
+	data, _ := yaml.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
 }
 
 // MergeFlagSet merges cobra flag values into the config
 func (c *Config) MergeFlagSet(flags *pflag.FlagSet) error {
+	var mergeErr error
 	// Only override if flag was explicitly set
 	flags.Visit(func(f *pflag.Flag) {
 		switch f.Name {
 		case "output":
+			// An explicit output path or format replaces any configured
+			// output targets
 			c.Output, _ = flags.GetString("output")
+			c.Outputs = nil
+		case "format":
+			c.Format, _ = flags.GetString("format")
+			c.Outputs = nil
 		case "filter":
 			c.FilterPatterns, _ = flags.GetStringSlice("filter")
 		case "exclude":
 			c.ExcludePatterns, _ = flags.GetStringSlice("exclude")
+		case "prune":
+			c.PrunePatterns, _ = flags.GetStringSlice("prune")
 		case "case-sensitive":
 			c.CaseSensitive, _ = flags.GetBool("case-sensitive")
+		case "include-generated":
+			c.IncludeGenerated, _ = flags.GetBool("include-generated")
+		case "max-file-size":
+			c.MaxFileSize, _ = flags.GetString("max-file-size")
+		case "no-tests":
+			c.NoTests, _ = flags.GetBool("no-tests")
+		case "tests-only":
+			c.TestsOnly, _ = flags.GetBool("tests-only")
+		case "since":
+			c.Since, _ = flags.GetString("since")
+		case "untracked":
+			c.Untracked, _ = flags.GetBool("untracked")
 		case "no-codeblock":
 			c.NoCodeblock, _ = flags.GetBool("no-codeblock")
 		case "line-numbers":
 			c.LineNumbers, _ = flags.GetBool("line-numbers")
 		case "strip-comments":
 			c.StripComments, _ = flags.GetBool("strip-comments")
+		case "redact":
+			c.Redact, _ = flags.GetBool("redact")
+		case "outline":
+			c.Outline, _ = flags.GetBool("outline")
+		case "dedup":
+			c.Dedup, _ = flags.GetBool("dedup")
+		case "cache":
+			c.Cache, _ = flags.GetBool("cache")
+		case "concurrency":
+			c.Concurrency, _ = flags.GetInt("concurrency")
+		case "quiet":
+			c.Quiet, _ = flags.GetBool("quiet")
+		case "log-level":
+			c.LogLevel, _ = flags.GetString("log-level")
+		case "report-skipped":
+			c.ReportSkipped, _ = flags.GetBool("report-skipped")
+		case "skipped-report":
+			c.SkippedReport, _ = flags.GetString("skipped-report")
+		case "log-format":
+			c.LogFormat, _ = flags.GetString("log-format")
+		case "group-by-dir":
+			c.GroupByDir, _ = flags.GetBool("group-by-dir")
+		case "tree":
+			c.Tree, _ = flags.GetBool("tree")
+		case "file-tokens":
+			c.FileTokens, _ = flags.GetBool("file-tokens")
+		case "todos":
+			c.Todos, _ = flags.GetBool("todos")
+		case "instructions":
+			c.Instructions, _ = flags.GetString("instructions")
+		case "instructions-file":
+			c.InstructionsFile, _ = flags.GetString("instructions-file")
+		case "instructions-position":
+			c.InstructionsPosition, _ = flags.GetString("instructions-position")
+		case "system-prompt":
+			c.SystemPrompt, _ = flags.GetString("system-prompt")
+		case "message-tokens":
+			c.MessageTokens, _ = flags.GetInt("message-tokens")
+		case "chunk-tokens":
+			c.ChunkTokens, _ = flags.GetInt("chunk-tokens")
+		case "chunk-overlap":
+			c.ChunkOverlap, _ = flags.GetInt("chunk-overlap")
+		case "front-matter":
+			c.FrontMatter, _ = flags.GetBool("front-matter")
+		case "changelog":
+			c.Changelog, _ = flags.GetBool("changelog")
+		case "changelog-diffs":
+			c.ChangelogDiffs, _ = flags.GetBool("changelog-diffs")
+		case "blame":
+			c.Blame, _ = flags.GetBool("blame")
+		case "order":
+			c.Order, _ = flags.GetString("order")
+		case "sort":
+			c.Sort, _ = flags.GetString("sort")
+		case "reverse":
+			c.Reverse, _ = flags.GetBool("reverse")
 		case "tokens":
 			c.ShowTokens, _ = flags.GetBool("tokens")
 		case "encoding":
 			c.TokenEncoding, _ = flags.GetString("encoding")
+		case "pricing":
+			c.Pricing, _ = flags.GetString("pricing")
+		case "notify-webhook":
+			c.NotifyWebhook, _ = flags.GetString("notify-webhook")
+		case "strict":
+			c.Strict, _ = flags.GetBool("strict")
+		case "fail-over-tokens":
+			c.FailOverTokens, _ = flags.GetInt("fail-over-tokens")
+		case "encoding-for-model":
+			c.EncodingForModel, _ = flags.GetString("encoding-for-model")
+			if err := c.resolveEncoding(); err != nil {
+				mergeErr = err
+			}
+		case "chat-format":
+			c.ChatFormat, _ = flags.GetBool("chat-format")
 		case "price":
 			c.ShowPrice, _ = flags.GetBool("price")
 		case "provider":
@@ -239,8 +848,52 @@ func (c *Config) MergeFlagSet(flags *pflag.FlagSet) error {
 			c.OutputTokens, _ = flags.GetInt("output-tokens")
 		case "template":
 			c.TemplatePath, _ = flags.GetString("template")
+		case "file-template":
+			c.FileTemplatePath, _ = flags.GetString("file-template")
+		case "document-template":
+			c.DocumentTemplatePath, _ = flags.GetString("document-template")
+		case "template-dir":
+			c.TemplateDir, _ = flags.GetString("template-dir")
+		case "var":
+			values, _ := flags.GetStringArray("var")
+			vars, err := ParseVars(values)
+			if err != nil {
+				mergeErr = err
+				return
+			}
+			for k, v := range vars {
+				if c.Vars == nil {
+					c.Vars = make(map[string]string)
+				}
+				c.Vars[k] = v
+			}
+		case "confirm":
+			c.Confirm, _ = flags.GetBool("confirm")
+		case "inject":
+			c.Inject, _ = flags.GetBool("inject")
+		case "clipboard":
+			c.Clipboard, _ = flags.GetBool("clipboard")
+		case "max-tokens":
+			c.MaxTokens, _ = flags.GetInt("max-tokens")
+		case "split-tokens":
+			c.SplitTokens, _ = flags.GetInt("split-tokens")
+		case "budget-strategy":
+			c.BudgetStrategy, _ = flags.GetString("budget-strategy")
 		}
 	})
 
-	return nil
+	return mergeErr
+}
+
+// ParseVars parses key=value pairs, as given to --var, into a map
+func ParseVars(values []string) (map[string]string, error) {
+	vars := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid var %q: expected key=value", value)
+		}
+		vars[key] = val
+	}
+	return vars, nil
 }
@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestParseVars(t *testing.T) {
+	cases := []struct {
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{values: []string{"team=core"}, want: map[string]string{"team": "core"}},
+		{values: []string{"a=b=c"}, want: map[string]string{"a": "b=c"}},
+		{values: []string{"empty="}, want: map[string]string{"empty": ""}},
+		{values: []string{"a=1", "a=2"}, want: map[string]string{"a": "2"}},
+		{values: nil, want: map[string]string{}},
+		{values: []string{"=x"}, wantErr: true},
+		{values: []string{"novalue"}, wantErr: true},
+		{values: []string{"a=1", "bad"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseVars(tc.values)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseVars(%q) = %v, want an error", tc.values, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseVars(%q) error: %v", tc.values, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("ParseVars(%q) = %v, want %v", tc.values, got, tc.want)
+			continue
+		}
+		for key, val := range tc.want {
+			if got[key] != val {
+				t.Errorf("ParseVars(%q)[%q] = %q, want %q", tc.values, key, got[key], val)
+			}
+		}
+	}
+}
+
+func TestDropHooks(t *testing.T) {
+	cfg := &Config{
+		PreHook:       "./scrub",
+		PostHook:      "./sign",
+		NotifyWebhook: "https://example.com/hook",
+		Outline:       true,
+		Profiles: map[string]*Config{
+			"ci": {NotifyWebhook: "https://example.com/ci", Redact: true},
+		},
+	}
+	cfg.dropHooks("sink-config.yaml")
+
+	cases := []struct {
+		name string
+		cfg  *Config
+	}{
+		{name: "config", cfg: cfg},
+		{name: "profile", cfg: cfg.Profiles["ci"]},
+	}
+	for _, tc := range cases {
+		if tc.cfg.PreHook != "" || tc.cfg.PostHook != "" || tc.cfg.NotifyWebhook != "" {
+			t.Errorf("%s kept hooks or webhook: %q %q %q", tc.name, tc.cfg.PreHook, tc.cfg.PostHook, tc.cfg.NotifyWebhook)
+		}
+	}
+	if !cfg.Outline || !cfg.Profiles["ci"].Redact {
+		t.Error("dropHooks() cleared other settings")
+	}
+}
@@ -2,22 +2,34 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/tokens"
+	"github.com/dwrtz/sink/internal/utils"
 )
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	// Validate token encoding
-	if !isValidEncoding(c.TokenEncoding) {
+	if !tokens.IsValidEncoding(c.TokenEncoding) {
 		return fmt.Errorf("invalid token encoding: %s", c.TokenEncoding)
 	}
+	if c.EncodingForModel != "" {
+		if _, err := tokens.EncodingForModel(c.EncodingForModel); err != nil {
+			return fmt.Errorf("invalid encoding-for-model: %w", err)
+		}
+	}
 
 	// Validate provider
 	if c.ShowPrice {
 		if !isValidProvider(c.Provider) {
 			return fmt.Errorf("invalid provider: %s", c.Provider)
 		}
-		if !isValidModel(c.Provider, c.Model) {
+		// Custom catalogs may add models; they are checked when estimating
+		if c.Pricing == "" && !isValidModel(c.Provider, c.Model) {
 			return fmt.Errorf("invalid model %s for provider %s", c.Model, c.Provider)
 		}
 	}
@@ -27,24 +39,162 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output tokens must be non-negative")
 	}
 
-	// Validate template path if specified
-	if c.TemplatePath != "" {
-		if _, err := os.Stat(c.TemplatePath); err != nil {
+	// Validate ordering preset
+	if !isValidOrder(c.Order) {
+		return fmt.Errorf("invalid order: %s", c.Order)
+	}
+
+	// Validate sort key
+	if !isValidSort(c.Sort) {
+		return fmt.Errorf("invalid sort: %s", c.Sort)
+	}
+
+	// Validate token budget
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must be non-negative")
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be non-negative")
+	}
+	if c.FailOverTokens < 0 {
+		return fmt.Errorf("fail-over-tokens must be non-negative")
+	}
+
+	// Validate file size limit
+	if c.MaxFileSize != "" {
+		if _, err := utils.ParseSize(c.MaxFileSize); err != nil {
+			return fmt.Errorf("invalid max-file-size: %w", err)
+		}
+	}
+
+	// Validate test file selection
+	if c.NoTests && c.TestsOnly {
+		return fmt.Errorf("no-tests and tests-only cannot both be set")
+	}
+
+	// Validate split size
+	if c.SplitTokens < 0 {
+		return fmt.Errorf("split tokens must be non-negative")
+	}
+
+	// Validate instructions
+	if c.InstructionsFile != "" {
+		if _, err := os.Stat(c.InstructionsFile); err != nil {
+			return fmt.Errorf("invalid instructions file: %w", err)
+		}
+	}
+	if !isValidInstructionsPosition(c.InstructionsPosition) {
+		return fmt.Errorf("invalid instructions position: %s (must be before or after)", c.InstructionsPosition)
+	}
+
+	if c.MessageTokens < 0 {
+		return fmt.Errorf("message tokens must be non-negative")
+	}
+
+	if c.ChunkTokens < 0 || c.ChunkOverlap < 0 {
+		return fmt.Errorf("chunk tokens and chunk overlap must be non-negative")
+	}
+	if c.ChunkTokens > 0 && c.ChunkOverlap >= c.ChunkTokens {
+		return fmt.Errorf("chunk overlap must be smaller than chunk tokens")
+	}
+
+	// Validate budget strategy
+	if !isValidBudgetStrategy(c.BudgetStrategy) {
+		return fmt.Errorf("invalid budget strategy: %s", c.BudgetStrategy)
+	}
+
+	// Validate redaction patterns
+	for _, pattern := range c.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Validate template paths if specified
+	for _, path := range []string{c.TemplatePath, c.FileTemplatePath, c.DocumentTemplatePath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
 			return fmt.Errorf("invalid template path: %w", err)
 		}
 	}
 
+	if err := validateTemplateDir(c.TemplateDir); err != nil {
+		return err
+	}
+
+	// Validate logging options
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if err := logging.ValidateFormat(c.LogFormat); err != nil {
+		return err
+	}
+
+	// Validate watch notifications
+	if c.NotifyWebhook != "" {
+		u, err := url.Parse(c.NotifyWebhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid notify-webhook: %s (must be an http or https URL)", c.NotifyWebhook)
+		}
+	}
+
+	// Validate output targets; formats are checked against the formatter
+	// registry by generator.CheckFormats
+	for _, target := range c.Outputs {
+		for _, path := range target.TemplatePaths() {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid template path for output %s: %w", target.Path, err)
+			}
+		}
+		if err := validateTemplateDir(target.TemplateDir); err != nil {
+			return fmt.Errorf("invalid output %s: %w", target.Path, err)
+		}
+	}
+
 	return nil
 }
 
-func isValidEncoding(encoding string) bool {
-	validEncodings := map[string]bool{
-		"cl100k_base": true,
-		"p50k_base":   true,
-		"p50k_edit":   true,
-		"r50k_base":   true,
+func isValidOrder(order string) bool {
+	validOrders := map[string]bool{
+		"":           true,
+		"docs-first": true,
+	}
+	return validOrders[order]
+}
+
+func isValidSort(key string) bool {
+	validKeys := map[string]bool{
+		"":       true,
+		"none":   true,
+		"path":   true,
+		"size":   true,
+		"mtime":  true,
+		"tokens": true,
+	}
+	return validKeys[key]
+}
+
+func isValidBudgetStrategy(strategy string) bool {
+	validStrategies := map[string]bool{
+		"":           true,
+		"order":      true,
+		"pattern":    true,
+		"size":       true,
+		"depth":      true,
+		"importance": true,
+	}
+	return validStrategies[strategy]
+}
+
+func isValidInstructionsPosition(position string) bool {
+	validPositions := map[string]bool{
+		"":       true,
+		"before": true,
+		"after":  true,
 	}
-	return validEncodings[encoding]
+	return validPositions[position]
 }
 
 func isValidProvider(provider string) bool {
@@ -54,26 +204,30 @@ func isValidProvider(provider string) bool {
 		"google":    true,
 		"mistral":   true,
 		"cohere":    true,
+		"ollama":    true,
 	}
 	return validProviders[provider]
 }
 
 func isValidModel(provider, model string) bool {
-	validModels := map[string]map[string]bool{
-		"openai": {
-			"gpt-3.5-turbo": true,
-			"gpt-4":         true,
-			"gpt-4-32k":     true,
-		},
-		"anthropic": {
-			"claude-2":       true,
-			"claude-instant": true,
-		},
-		// Add more providers and their models as needed
-	}
-
-	if models, ok := validModels[provider]; ok {
-		return models[model]
-	}
-	return false
+	pricing, err := tokens.LoadPricing("")
+	if err != nil {
+		return false
+	}
+	return pricing.Has(provider, model)
+}
+
+// validateTemplateDir checks that dir, if set, is a directory
+func validateTemplateDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid template directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid template directory: %s is not a directory", dir)
+	}
+	return nil
 }
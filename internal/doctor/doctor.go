@@ -0,0 +1,288 @@
+package doctor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/filter"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/plugins"
+	sinktemplate "github.com/dwrtz/sink/internal/processor/template"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Result is the outcome of a single diagnostic check along with a suggested
+// fix when something is wrong
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+	Fix     string
+}
+
+// providerKeys maps providers to the environment variables holding their API keys
+var providerKeys = map[string][]string{
+	"openai":    {"OPENAI_API_KEY"},
+	"anthropic": {"ANTHROPIC_API_KEY"},
+	"google":    {"GEMINI_API_KEY", "GOOGLE_API_KEY"},
+	"mistral":   {"MISTRAL_API_KEY"},
+	"cohere":    {"COHERE_API_KEY", "CO_API_KEY"},
+}
+
+// Run performs all diagnostic checks for the repository at root using the
+// merged configuration cfg
+func Run(root, cmdConfigPath string, cfg *config.Config) []Result {
+	var results []Result
+	results = append(results, checkConfigLayers(cmdConfigPath)...)
+	results = append(results, checkConfig(cfg))
+	results = append(results, checkTemplates(cfg)...)
+	results = append(results, checkGitignore(root))
+	results = append(results, checkInotify(root))
+	results = append(results, checkTokenizer(cfg))
+	results = append(results, checkAPIKey(cfg))
+	results = append(results, checkPlugins(config.PluginDir())...)
+	return results
+}
+
+// Failed reports whether any check failed
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigLayers parses each configuration file layer on its own
+func checkConfigLayers(cmdConfigPath string) []Result {
+	var results []Result
+	for _, layer := range config.Layers(cmdConfigPath) {
+		name := fmt.Sprintf("config (%s)", layer.Name)
+		if layer.Path == "" {
+			results = append(results, Result{Name: name, Status: StatusSkip, Message: "no path could be determined"})
+			continue
+		}
+
+		if _, err := os.Stat(layer.Path); os.IsNotExist(err) {
+			status := StatusSkip
+			fix := ""
+			if layer.Name == "explicit" {
+				status = StatusFail
+				fix = "check the path passed to --config"
+			}
+			results = append(results, Result{Name: name, Status: status, Message: fmt.Sprintf("%s not found", layer.Path), Fix: fix})
+			continue
+		}
+
+		if _, err := config.LoadFile(layer.Path); err != nil {
+			results = append(results, Result{
+				Name:    name,
+				Status:  StatusFail,
+				Message: err.Error(),
+				Fix:     fmt.Sprintf("fix the YAML syntax in %s", layer.Path),
+			})
+			continue
+		}
+
+		results = append(results, Result{Name: name, Status: StatusOK, Message: fmt.Sprintf("%s parsed", layer.Path)})
+	}
+	return results
+}
+
+// checkConfig validates the merged configuration
+func checkConfig(cfg *config.Config) Result {
+	err := cfg.Validate()
+	if err == nil {
+		err = generator.CheckFormats(cfg)
+	}
+	if err != nil {
+		return Result{
+			Name:    "config (merged)",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Fix:     "correct the setting in the highest-precedence config file that sets it",
+		}
+	}
+	return Result{Name: "config (merged)", Status: StatusOK, Message: "valid"}
+}
+
+// checkTemplates verifies that every configured template parses
+func checkTemplates(cfg *config.Config) []Result {
+	var paths []string
+	for _, target := range cfg.OutputTargets() {
+		paths = append(paths, target.TemplatePaths()...)
+	}
+	if len(paths) == 0 {
+		return []Result{{Name: "template", Status: StatusSkip, Message: "no template configured, using built-in markdown"}}
+	}
+
+	var results []Result
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			results = append(results, Result{Name: "template", Status: StatusFail, Message: err.Error(), Fix: "check template-path in your config"})
+			continue
+		}
+		if _, err := template.New(filepath.Base(p)).Funcs(sinktemplate.Funcs).Parse(string(data)); err != nil {
+			results = append(results, Result{Name: "template", Status: StatusFail, Message: err.Error(), Fix: fmt.Sprintf("fix the template syntax in %s", p)})
+			continue
+		}
+		results = append(results, Result{Name: "template", Status: StatusOK, Message: fmt.Sprintf("%s parses", p)})
+	}
+	return results
+}
+
+// checkGitignore verifies that gitignore patterns can be loaded
+func checkGitignore(root string) Result {
+	_, err := filter.NewFilter(filter.GitignoreConfig{
+		RepoRoot:           root,
+		LoadGlobalPatterns: true,
+		LoadSystemPatterns: true,
+	})
+	if err != nil {
+		return Result{
+			Name:    "gitignore",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Fix:     "check that .gitignore files and core.excludesfile in ~/.gitconfig are readable",
+		}
+	}
+	return Result{Name: "gitignore", Status: StatusOK, Message: "patterns loaded"}
+}
+
+// checkInotify compares the number of directories the watcher would need
+// against the inotify watch limit on Linux
+func checkInotify(root string) Result {
+	if runtime.GOOS != "linux" {
+		return Result{Name: "inotify", Status: StatusSkip, Message: "not running on Linux"}
+	}
+
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return Result{Name: "inotify", Status: StatusWarn, Message: fmt.Sprintf("could not read watch limit: %v", err)}
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return Result{Name: "inotify", Status: StatusWarn, Message: fmt.Sprintf("could not parse watch limit: %v", err)}
+	}
+
+	dirs := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == ".sink" {
+				return filepath.SkipDir
+			}
+			dirs++
+		}
+		return nil
+	})
+
+	if dirs > limit {
+		return Result{
+			Name:    "inotify",
+			Status:  StatusFail,
+			Message: fmt.Sprintf("%d directories but max_user_watches is %d", dirs, limit),
+			Fix:     "raise the limit with: sudo sysctl fs.inotify.max_user_watches=524288",
+		}
+	}
+	if dirs > limit/2 {
+		return Result{
+			Name:    "inotify",
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("%d directories uses over half of max_user_watches (%d)", dirs, limit),
+			Fix:     "consider raising the limit with: sudo sysctl fs.inotify.max_user_watches=524288",
+		}
+	}
+	return Result{Name: "inotify", Status: StatusOK, Message: fmt.Sprintf("%d directories, limit %d", dirs, limit)}
+}
+
+// checkTokenizer verifies that the tokenizer data for the configured
+// encoding is cached or can be downloaded
+func checkTokenizer(cfg *config.Config) Result {
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return Result{Name: "tokenizer", Status: StatusFail, Message: err.Error(), Fix: "set token-encoding to a supported encoding"}
+	}
+	if _, err := counter.Count("sink doctor"); err != nil {
+		return Result{
+			Name:    "tokenizer",
+			Status:  StatusFail,
+			Message: fmt.Sprintf("%s data unavailable: %v", cfg.TokenEncoding, err),
+			Fix:     "allow access to openaipublic.blob.core.windows.net once, or point TIKTOKEN_CACHE_DIR at a directory with cached encodings",
+		}
+	}
+	return Result{Name: "tokenizer", Status: StatusOK, Message: fmt.Sprintf("%s available", cfg.TokenEncoding)}
+}
+
+// checkPlugins loads the WebAssembly plugins in dir and reports each that
+// fails to compile or describe itself, which sink skips
+func checkPlugins(dir string) []Result {
+	if dir == "" {
+		return []Result{{Name: "plugins", Status: StatusSkip, Message: "no plugin directory could be determined"}}
+	}
+	loaded, errs := plugins.Load(dir)
+	var results []Result
+	for _, err := range errs {
+		results = append(results, Result{
+			Name:    "plugins",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Fix:     fmt.Sprintf("rebuild the plugin as a WASI command module or remove it from %s", dir),
+		})
+	}
+	if len(loaded) > 0 || len(errs) == 0 {
+		names := make([]string, len(loaded))
+		for i, p := range loaded {
+			names[i] = p.Name
+		}
+		message := fmt.Sprintf("none in %s", dir)
+		if len(loaded) > 0 {
+			message = fmt.Sprintf("%s loaded from %s", strings.Join(names, ", "), dir)
+		}
+		results = append(results, Result{Name: "plugins", Status: StatusOK, Message: message})
+	}
+	return results
+}
+
+// checkAPIKey verifies that an API key is set for the configured provider
+func checkAPIKey(cfg *config.Config) Result {
+	if cfg.Provider == "ollama" {
+		return Result{Name: "api key", Status: StatusOK, Message: "ollama runs locally and needs no API key"}
+	}
+	vars, ok := providerKeys[cfg.Provider]
+	if !ok {
+		return Result{Name: "api key", Status: StatusSkip, Message: fmt.Sprintf("no known API key for provider %s", cfg.Provider)}
+	}
+	for _, v := range vars {
+		if os.Getenv(v) != "" {
+			return Result{Name: "api key", Status: StatusOK, Message: fmt.Sprintf("%s is set", v)}
+		}
+	}
+	return Result{
+		Name:    "api key",
+		Status:  StatusWarn,
+		Message: fmt.Sprintf("no API key for %s", cfg.Provider),
+		Fix:     fmt.Sprintf("export %s=<your key>", vars[0]),
+	}
+}
@@ -0,0 +1,162 @@
+package doctor
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/filter"
+)
+
+// Validate checks the configuration for the repository at root: every layer
+// parses without unknown keys, the merged configuration is valid, templates
+// parse and every pattern matches at least one path
+func Validate(root, cmdConfigPath string, cfg *config.Config) []Result {
+	var results []Result
+	results = append(results, checkConfigLayers(cmdConfigPath)...)
+	results = append(results, checkUnknownKeys(cmdConfigPath)...)
+	results = append(results, checkConfig(cfg))
+	results = append(results, checkTemplates(cfg)...)
+	results = append(results, checkPatterns(root, cfg)...)
+	return results
+}
+
+// checkUnknownKeys reports keys in each existing config layer that sink does
+// not recognize, which are usually typos
+func checkUnknownKeys(cmdConfigPath string) []Result {
+	var results []Result
+	for _, layer := range config.Layers(cmdConfigPath) {
+		if layer.Path == "" {
+			continue
+		}
+		if _, err := config.LoadFile(layer.Path); err != nil {
+			// Unreadable or malformed files are reported by checkConfigLayers
+			continue
+		}
+
+		name := fmt.Sprintf("keys (%s)", layer.Name)
+		if err := config.CheckFile(layer.Path); err != nil {
+			results = append(results, Result{
+				Name:    name,
+				Status:  StatusFail,
+				Message: err.Error(),
+				Fix:     fmt.Sprintf("correct or remove the unknown key in %s; see examples/sink-config.yaml for valid keys", layer.Path),
+			})
+			continue
+		}
+		results = append(results, Result{Name: name, Status: StatusOK, Message: "all keys recognized"})
+	}
+	return results
+}
+
+// repoPaths lists the files and directories under root that are not ignored
+// by gitignore, relative to root
+func repoPaths(root string) (files, dirs []string, err error) {
+	ignorer, err := filter.NewFilter(filter.GitignoreConfig{
+		RepoRoot:           root,
+		LoadGlobalPatterns: true,
+		LoadSystemPatterns: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == "." {
+			return nil
+		}
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == ".sink") {
+			return filepath.SkipDir
+		}
+		if ignored, err := ignorer.IsIgnored(relPath); err == nil && ignored {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, relPath)
+		} else {
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	return files, dirs, err
+}
+
+// checkPatterns reports configured patterns that match nothing in the
+// repository
+func checkPatterns(root string, cfg *config.Config) []Result {
+	files, dirs, err := repoPaths(root)
+	if err != nil {
+		return []Result{{Name: "patterns", Status: StatusFail, Message: err.Error(), Fix: "check that the repository is readable"}}
+	}
+	all := append(append([]string(nil), files...), dirs...)
+
+	overrides := make([]string, 0, len(cfg.LanguageOverrides))
+	for pattern := range cfg.LanguageOverrides {
+		overrides = append(overrides, pattern)
+	}
+	sort.Strings(overrides)
+
+	priority := make([]string, len(cfg.PriorityPatterns))
+	for i, pattern := range cfg.PriorityPatterns {
+		priority[i] = strings.TrimPrefix(pattern, "!")
+	}
+
+	groups := []struct {
+		key      string
+		patterns []string
+		paths    []string
+	}{
+		{"filter-patterns", cfg.FilterPatterns, files},
+		{"exclude-patterns", cfg.ExcludePatterns, all},
+		{"prune-patterns", cfg.PrunePatterns, dirs},
+		{"blame-patterns", cfg.BlamePatterns, files},
+		{"language-overrides", overrides, files},
+		{"priority-patterns", priority, files},
+	}
+
+	var unmatched []string
+	checked := 0
+	for _, group := range groups {
+		for _, pattern := range group.patterns {
+			checked++
+			if !matchesSome(pattern, group.paths, cfg.CaseSensitive) {
+				unmatched = append(unmatched, fmt.Sprintf("%s: %q", group.key, pattern))
+			}
+		}
+	}
+
+	if checked == 0 {
+		return []Result{{Name: "patterns", Status: StatusSkip, Message: "no patterns configured"}}
+	}
+	if len(unmatched) > 0 {
+		return []Result{{
+			Name:    "patterns",
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("%d pattern(s) match nothing in %s: %s", len(unmatched), root, strings.Join(unmatched, ", ")),
+			Fix:     "check the patterns for typos; patterns without a slash match file names, patterns with a slash match paths from the repository root",
+		}}
+	}
+	return []Result{{Name: "patterns", Status: StatusOK, Message: fmt.Sprintf("all %d patterns match", checked)}}
+}
+
+// matchesSome reports whether pattern matches at least one of paths
+func matchesSome(pattern string, paths []string, caseSensitive bool) bool {
+	patterns := []string{pattern}
+	for _, p := range paths {
+		if filter.MatchesAny(p, patterns, caseSensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+// Package embeddings keeps an index of embedding vectors for chunks of a
+// repository's files and ranks the chunks by similarity to a query.
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/llm"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+const (
+	// DefaultChunkLines is how many lines of a file go into one chunk
+	DefaultChunkLines = 60
+	// maxChunkBytes keeps chunks of files with very long lines within the
+	// input limits of embedding models
+	maxChunkBytes = 8000
+	// batchSize is how many chunks are embedded per request
+	batchSize = 64
+)
+
+// Chunk is a range of lines of a file and its embedding
+type Chunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start"`
+	EndLine   int       `json:"end"`
+	Hash      string    `json:"hash"`
+	Vector    []float32 `json:"vector"`
+}
+
+// Index holds the embeddings of every chunk of a repository's files
+type Index struct {
+	Provider   string  `json:"provider"`
+	Model      string  `json:"model"`
+	ChunkLines int     `json:"chunk_lines"`
+	Chunks     []Chunk `json:"chunks"`
+}
+
+// Match is a chunk and its similarity to a query, from -1 to 1
+type Match struct {
+	Chunk
+	Score float64
+}
+
+// Load reads an index from path. It returns nil without an error if the
+// file does not exist.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings: %w", err)
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings %s: %w", path, err)
+	}
+	return &index, nil
+}
+
+// Save writes the index to path
+func (idx *Index) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to encode embeddings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create embeddings directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write embeddings: %w", err)
+	}
+	return nil
+}
+
+// Update returns an index of the chunks of files, reusing the vectors of
+// chunks unchanged since previous and embedding the rest. It also returns
+// how many chunks were embedded. previous is ignored if it was built with a
+// different provider, model or chunk size.
+func Update(ctx context.Context, previous *Index, files []processor.FileInfo, embedder llm.Embedder, provider, model string, chunkLines int) (*Index, int, error) {
+	if chunkLines <= 0 {
+		chunkLines = DefaultChunkLines
+	}
+	known := make(map[string][]float32)
+	if previous != nil && previous.Provider == provider && previous.Model == model && previous.ChunkLines == chunkLines {
+		for _, c := range previous.Chunks {
+			known[c.Hash] = c.Vector
+		}
+	}
+
+	index := &Index{Provider: provider, Model: model, ChunkLines: chunkLines}
+	var pending []int // Chunks that need embedding
+	var inputs []string
+	for _, file := range files {
+		// Duplicates hold a stub pointing at the file they duplicate
+		if file.DuplicateOf != "" {
+			continue
+		}
+		for _, c := range chunkFile(file, chunkLines) {
+			if vector, ok := known[c.Hash]; ok {
+				c.Vector = vector
+			} else {
+				pending = append(pending, len(index.Chunks))
+				inputs = append(inputs, c.text)
+			}
+			index.Chunks = append(index.Chunks, c.Chunk)
+		}
+	}
+
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+		vectors, err := embedder.Embed(ctx, model, inputs[start:end])
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for i, vector := range vectors {
+			index.Chunks[pending[start+i]].Vector = vector
+		}
+	}
+	return index, len(inputs), nil
+}
+
+// Search returns the k chunks most similar to the query vector, best first
+func (idx *Index) Search(query []float32, k int) []Match {
+	matches := make([]Match, 0, len(idx.Chunks))
+	for _, c := range idx.Chunks {
+		matches = append(matches, Match{Chunk: c, Score: cosine(query, c.Vector)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// TopFiles returns the paths of the k files whose best chunk is most similar
+// to the query vector, best first
+func (idx *Index) TopFiles(query []float32, k int) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, m := range idx.Search(query, 0) {
+		if seen[m.Path] {
+			continue
+		}
+		seen[m.Path] = true
+		paths = append(paths, m.Path)
+		if len(paths) == k {
+			break
+		}
+	}
+	return paths
+}
+
+type chunk struct {
+	Chunk
+	text string // What is embedded: the path and line range, then the lines
+}
+
+// chunkFile splits a file into chunks of chunkLines lines
+func chunkFile(file processor.FileInfo, chunkLines int) []chunk {
+	path := filepath.ToSlash(file.RelPath)
+	lines := strings.Split(strings.TrimRight(file.Content, "\n"), "\n")
+	var chunks []chunk
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		body := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		if len(body) > maxChunkBytes {
+			body = body[:maxChunkBytes]
+		}
+		text := fmt.Sprintf("%s:%d-%d\n%s", path, start+1, end, body)
+		sum := sha256.Sum256([]byte(text))
+		chunks = append(chunks, chunk{
+			Chunk: Chunk{Path: path, StartLine: start + 1, EndLine: end, Hash: hex.EncodeToString(sum[:])},
+			text:  text,
+		})
+	}
+	return chunks
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 if they differ
+// in length or either is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// Chunk is a unit of source exported as one training example: either a
+// whole file or a single function
+type Chunk struct {
+	Repo      string
+	Path      string
+	RelPath   string
+	Language  string
+	Name      string // Function name, empty for whole-file chunks
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// Chunks splits files into export chunks. In "function" mode Go files are
+// split into one chunk per function or method; other files stay whole.
+func Chunks(files []processor.FileInfo, mode, repo string) ([]Chunk, error) {
+	var chunks []Chunk
+	for _, file := range files {
+		switch mode {
+		case "", "file":
+			chunks = append(chunks, fileChunk(file, repo))
+		case "function":
+			if file.Language != "go" {
+				chunks = append(chunks, fileChunk(file, repo))
+				continue
+			}
+			funcs, err := goFunctionChunks(file, repo)
+			if err != nil {
+				// Unparseable sources are still exported as a whole
+				chunks = append(chunks, fileChunk(file, repo))
+				continue
+			}
+			chunks = append(chunks, funcs...)
+		default:
+			return nil, fmt.Errorf("unknown chunk mode: %s", mode)
+		}
+	}
+	return chunks, nil
+}
+
+func fileChunk(file processor.FileInfo, repo string) Chunk {
+	return Chunk{
+		Repo:      repo,
+		Path:      file.Path,
+		RelPath:   file.RelPath,
+		Language:  file.Language,
+		StartLine: 1,
+		EndLine:   strings.Count(file.Content, "\n") + 1,
+		Content:   file.Content,
+	}
+}
+
+// goFunctionChunks returns one chunk per function declaration, including its
+// doc comment
+func goFunctionChunks(file processor.FileInfo, repo string) ([]Chunk, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file.Path, file.Content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		start := fn.Pos()
+		if fn.Doc != nil {
+			start = fn.Doc.Pos()
+		}
+		startOffset := fset.Position(start).Offset
+		endOffset := fset.Position(fn.End()).Offset
+
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = fmt.Sprintf("%s.%s", receiverType(fn.Recv.List[0].Type), name)
+		}
+
+		chunks = append(chunks, Chunk{
+			Repo:      repo,
+			Path:      file.Path,
+			RelPath:   file.RelPath,
+			Language:  file.Language,
+			Name:      name,
+			StartLine: fset.Position(start).Line,
+			EndLine:   fset.Position(fn.End()).Line,
+			Content:   file.Content[startOffset:endOffset],
+		})
+	}
+	return chunks, nil
+}
+
+// receiverType returns the type name of a method receiver
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Default message templates for fine-tuning examples
+const (
+	DefaultSystemTemplate    = "You are an expert on the {{ .Repo }} codebase."
+	DefaultUserTemplate      = "{{ if .Name }}Show the implementation of {{ .Name }} in {{ .RelPath }}.{{ else }}Show the contents of {{ .RelPath }}.{{ end }}"
+	DefaultAssistantTemplate = "```{{ .Language }}\n{{ .Content }}\n```"
+)
+
+// Templates holds the message templates rendered once per chunk
+type Templates struct {
+	System    string
+	User      string
+	Assistant string
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type example struct {
+	Messages []message `json:"messages"`
+}
+
+// WriteOpenAIFineTuning writes one OpenAI chat fine-tuning example per chunk
+// as JSONL
+func WriteOpenAIFineTuning(w io.Writer, chunks []Chunk, templates Templates) error {
+	system, err := parseTemplate("system", templates.System, DefaultSystemTemplate)
+	if err != nil {
+		return err
+	}
+	user, err := parseTemplate("user", templates.User, DefaultUserTemplate)
+	if err != nil {
+		return err
+	}
+	assistant, err := parseTemplate("assistant", templates.Assistant, DefaultAssistantTemplate)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, chunk := range chunks {
+		var ex example
+		for _, m := range []struct {
+			role string
+			tmpl *template.Template
+		}{{"system", system}, {"user", user}, {"assistant", assistant}} {
+			var buf bytes.Buffer
+			if err := m.tmpl.Execute(&buf, chunk); err != nil {
+				return fmt.Errorf("failed to render %s message for %s: %w", m.role, chunk.RelPath, err)
+			}
+			if buf.Len() == 0 {
+				continue
+			}
+			ex.Messages = append(ex.Messages, message{Role: m.role, Content: buf.String()})
+		}
+
+		if err := encoder.Encode(ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses text, falling back to def when text is empty
+func parseTemplate(name, text, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
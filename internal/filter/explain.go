@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreMatch describes the gitignore pattern that decided whether a path
+// is ignored
+type IgnoreMatch struct {
+	Source  string // File the pattern was read from
+	Line    int    // Line number in Source, 0 when unknown
+	Pattern string // Pattern text, empty when unknown
+	Ignored bool   // False when a negated pattern re-included the path
+}
+
+// sourcedPattern is a gitignore pattern along with where it was read from
+type sourcedPattern struct {
+	pattern gitignore.Pattern
+	match   IgnoreMatch
+}
+
+// IgnoreExplainer attributes gitignore matches for a path and its parent
+// directories to the pattern and file that decided them
+type IgnoreExplainer struct {
+	fs       billy.Filesystem
+	patterns []sourcedPattern
+}
+
+// Explainer reads the ignore files that can apply to path (relative to the
+// repository root): .git/info/exclude, the .gitignore files of the root and
+// of each directory containing path, and the global and system excludes.
+// No other directory is read.
+func (g *GitignoreFilter) Explainer(path string) *IgnoreExplainer {
+	patterns := g.readSourcedFile(nil, ".git/info/exclude")
+	parts := PathParts(path)
+	for i := 0; i < len(parts); i++ {
+		patterns = append(patterns, g.readSourcedFile(parts[:i], ".gitignore")...)
+	}
+	for _, p := range g.globalPatterns {
+		patterns = append(patterns, sourcedPattern{pattern: p, match: IgnoreMatch{Source: "global excludes file (core.excludesfile)"}})
+	}
+	for _, p := range g.systemPatterns {
+		patterns = append(patterns, sourcedPattern{pattern: p, match: IgnoreMatch{Source: "system excludes file (/etc/gitconfig)"}})
+	}
+	return &IgnoreExplainer{fs: g.fs, patterns: patterns}
+}
+
+// Explain returns the gitignore pattern that decides whether path is
+// ignored, or nil if no pattern matches it. path must be the path the
+// explainer was created for or one of its parent directories. Like
+// IsIgnored, later patterns take precedence over earlier ones.
+func (e *IgnoreExplainer) Explain(path string) (*IgnoreMatch, error) {
+	info, err := e.fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := PathParts(path)
+	for i := len(e.patterns) - 1; i >= 0; i-- {
+		result := e.patterns[i].pattern.Match(parts, info.IsDir())
+		if result == gitignore.NoMatch {
+			continue
+		}
+		match := e.patterns[i].match
+		match.Ignored = result == gitignore.Exclude
+		return &match, nil
+	}
+	return nil, nil
+}
+
+// readSourcedFile parses a single ignore file, returning nothing if it does
+// not exist or cannot be read
+func (g *GitignoreFilter) readSourcedFile(dir []string, name string) []sourcedPattern {
+	source := g.fs.Join(append(append([]string(nil), dir...), name)...)
+	// Patterns keep dir as their domain, so it must not share storage with
+	// the caller's slice
+	dir = append([]string(nil), dir...)
+	f, err := g.fs.Open(source)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []sourcedPattern
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.HasPrefix(text, "#") || strings.TrimSpace(text) == "" {
+			continue
+		}
+		patterns = append(patterns, sourcedPattern{
+			pattern: gitignore.ParsePattern(text, dir),
+			match:   IgnoreMatch{Source: filepath.FromSlash(source), Line: line, Pattern: text},
+		})
+	}
+	return patterns
+}
+
+// FirstMatch returns the first of patterns that matches path using the same
+// rules as MatchesAny, or false if none does
+func FirstMatch(path string, patterns []string, caseSensitive bool) (string, bool) {
+	for _, pattern := range patterns {
+		if MatchesAny(path, []string{pattern}, caseSensitive) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
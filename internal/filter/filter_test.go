@@ -1,6 +1,8 @@
 package filter
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -44,3 +46,68 @@ func TestMatchesAny(t *testing.T) {
 		}
 	}
 }
+
+func TestIsTestFile(t *testing.T) {
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"internal/filter/filter_test.go", true},
+		{"internal/filter/patterns.go", false},
+		{"pkg/test_utils.py", true},
+		{"pkg/utils.py", false},
+		{"src/app.spec.ts", true},
+		{"src/components/__tests__/button.js", true},
+		{"src/components/button.js", false},
+		{"src/main/java/LatestTest.java", true},
+		{"src/main/java/Latest.java", false},
+		{"tests/integration.rs", true},
+		{"src/lib.rs", false},
+	}
+
+	for _, tc := range cases {
+		if got := IsTestFile(tc.path); got != tc.want {
+			t.Errorf("IsTestFile(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestAttributesFilter(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitattributes":              "*.pb.go linguist-generated\n",
+		"vendor/.gitattributes":       "* linguist-vendored\nkeep.go -linguist-vendored\n",
+		"node_modules/.gitattributes": "* -linguist-generated\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := NewAttributesFilter(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		"api/service.pb.go":  true,
+		"api/service.go":     false,
+		"vendor/lib/lib.go":  true,
+		"vendor/keep.go":     false,
+		"other/vendor.pb.go": true,
+	}
+	for path, want := range cases {
+		if got := a.IsLinguistExcluded(filepath.FromSlash(path)); got != want {
+			t.Errorf("IsLinguistExcluded(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	// Directories without checked paths are never read
+	if _, ok := a.dirs["node_modules"]; ok {
+		t.Error("read .gitattributes of a directory no checked path is in")
+	}
+}
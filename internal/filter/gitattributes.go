@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+// linguistAttributes are the .gitattributes entries that mark files as not
+// being part of the project's own source
+var linguistAttributes = []string{"linguist-generated", "linguist-vendored"}
+
+// AttributesFilter matches files marked as generated or vendored in
+// .gitattributes files. Nested files are read on first use, so only the
+// directories of paths actually checked are ever looked at.
+type AttributesFilter struct {
+	fs billy.Filesystem
+
+	mu sync.Mutex
+	// Entries of the .gitattributes file in each directory read so far,
+	// keyed by slash-separated path relative to the root
+	dirs map[string][]gitattributes.MatchAttribute
+}
+
+// NewAttributesFilter reads the .gitattributes file at the root of repoRoot
+func NewAttributesFilter(repoRoot string) (*AttributesFilter, error) {
+	fs := osfs.New(repoRoot)
+	// Only the root file may define macros
+	root, err := gitattributes.ReadAttributesFile(fs, nil, ".gitattributes", true)
+	if err != nil {
+		return nil, err
+	}
+	return &AttributesFilter{fs: fs, dirs: map[string][]gitattributes.MatchAttribute{"": root}}, nil
+}
+
+// dirAttributes returns the entries of the .gitattributes file in dir,
+// reading it on first use. Unreadable files count as empty.
+func (a *AttributesFilter) dirAttributes(dir []string) []gitattributes.MatchAttribute {
+	key := strings.Join(dir, "/")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if attributes, ok := a.dirs[key]; ok {
+		return attributes
+	}
+	// The reader appends to dir, so give it a copy of its own
+	attributes, _ := gitattributes.ReadAttributesFile(a.fs, append([]string(nil), dir...), ".gitattributes", false)
+	a.dirs[key] = attributes
+	return attributes
+}
+
+// IsLinguistExcluded reports whether path is marked linguist-generated or
+// linguist-vendored by the .gitattributes files of its directory and its
+// ancestors. Later and more deeply nested entries take precedence.
+func (a *AttributesFilter) IsLinguistExcluded(path string) bool {
+	parts := PathParts(path)
+
+	var attributes []gitattributes.MatchAttribute
+	for i := 0; i < len(parts); i++ {
+		attributes = append(attributes, a.dirAttributes(parts[:i])...)
+	}
+
+	for _, name := range linguistAttributes {
+		for i := len(attributes) - 1; i >= 0; i-- {
+			entry := attributes[i]
+			if entry.Pattern == nil || !entry.Pattern.Match(parts) {
+				continue
+			}
+
+			attr, ok := findAttribute(entry.Attributes, name)
+			if !ok {
+				continue
+			}
+			if attr.IsSet() || (attr.IsValueSet() && attr.Value() == "true") {
+				return true
+			}
+			// Explicitly unset or set to another value: stop looking
+			break
+		}
+	}
+
+	return false
+}
+
+func findAttribute(attributes []gitattributes.Attribute, name string) (gitattributes.Attribute, bool) {
+	for _, attr := range attributes {
+		if attr.Name() == name {
+			return attr, true
+		}
+	}
+	return nil, false
+}
@@ -13,6 +13,10 @@ import (
 type GitignoreFilter struct {
 	matcher gitignore.Matcher
 	fs      billy.Filesystem
+	// Patterns from the global and system excludes files, kept so Explain
+	// can attribute matches to them
+	globalPatterns []gitignore.Pattern
+	systemPatterns []gitignore.Pattern
 }
 
 type GitignoreConfig struct {
@@ -58,14 +62,15 @@ func NewFilter(config GitignoreConfig) (*GitignoreFilter, error) {
 		return nil, err
 	}
 
+	filter := &GitignoreFilter{fs: fs}
+
 	if config.LoadGlobalPatterns {
 		globalPatterns, err := gitignore.LoadGlobalPatterns(fs)
 		if err != nil {
 			return nil, err
 		}
-		if globalPatterns != nil {
-			patterns = append(patterns, globalPatterns...)
-		}
+		patterns = append(patterns, globalPatterns...)
+		filter.globalPatterns = globalPatterns
 	}
 
 	if config.LoadSystemPatterns {
@@ -73,13 +78,12 @@ func NewFilter(config GitignoreConfig) (*GitignoreFilter, error) {
 		if err != nil {
 			return nil, err
 		}
-		if systemPatterns != nil {
-			patterns = append(patterns, systemPatterns...)
-		}
+		patterns = append(patterns, systemPatterns...)
+		filter.systemPatterns = systemPatterns
 	}
 
-	matcher := gitignore.NewMatcher(patterns)
-	return &GitignoreFilter{matcher: matcher, fs: fs}, nil
+	filter.matcher = gitignore.NewMatcher(patterns)
+	return filter, nil
 }
 
 func (g *GitignoreFilter) IsIgnored(path string) (bool, error) {
@@ -88,7 +92,11 @@ func (g *GitignoreFilter) IsIgnored(path string) (bool, error) {
 		return false, err
 	}
 
-	pathParts := PathParts(path)
-	ignored := g.matcher.Match(pathParts, info.IsDir())
-	return ignored, nil
+	return g.Matches(path, info.IsDir()), nil
+}
+
+// Matches reports whether the patterns ignore path, which need not exist,
+// such as a file that was just removed
+func (g *GitignoreFilter) Matches(path string, isDir bool) bool {
+	return g.matcher.Match(PathParts(path), isDir)
 }
@@ -0,0 +1,40 @@
+package filter
+
+// TestPatterns are the per-language conventions used to recognize test files.
+// They are matched case-sensitively so that e.g. "*Test.java" does not match
+// "Latest.java".
+var TestPatterns = []string{
+	// Go
+	"*_test.go",
+	// Python
+	"test_*.py",
+	"*_test.py",
+	"conftest.py",
+	// JavaScript and TypeScript
+	"*.test.js", "*.test.jsx", "*.test.mjs", "*.test.cjs", "*.test.ts", "*.test.tsx",
+	"*.spec.js", "*.spec.jsx", "*.spec.mjs", "*.spec.cjs", "*.spec.ts", "*.spec.tsx",
+	"**/__tests__/**",
+	// Ruby
+	"*_spec.rb",
+	"*_test.rb",
+	// Java, Kotlin, Scala, C#, PHP and Swift
+	"*Test.java", "*Tests.java",
+	"*Test.kt", "*Tests.kt",
+	"*Spec.scala", "*Test.scala",
+	"*Test.cs", "*Tests.cs",
+	"*Test.php",
+	"*Tests.swift",
+	// C and C++
+	"*_test.c", "*_test.cc", "*_test.cpp", "*_unittest.cc", "*_unittest.cpp",
+	// Elixir and Erlang
+	"*_test.exs",
+	"*_SUITE.erl",
+	// Rust integration tests
+	"**/tests/**/*.rs",
+}
+
+// IsTestFile reports whether a path relative to the repository root looks
+// like a test file according to TestPatterns
+func IsTestFile(relPath string) bool {
+	return MatchesAny(relPath, TestPatterns, true)
+}
@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/filter"
+	"github.com/dwrtz/sink/internal/gitinfo"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// annotateBlame prefixes the lines of files matching the blame patterns (or
+// all files when none are set) with git blame information
+func annotateBlame(files []processor.FileInfo, cfg *config.Config, path string) error {
+	blamer, err := gitinfo.NewBlamer(path)
+	if err != nil {
+		return fmt.Errorf("failed to set up git blame: %w", err)
+	}
+
+	for i, file := range files {
+		if len(cfg.BlamePatterns) > 0 && !filter.MatchesAny(file.RelPath, cfg.BlamePatterns, cfg.CaseSensitive) {
+			continue
+		}
+		if file.DuplicateOf != "" {
+			continue
+		}
+
+		content, err := blamer.Annotate(file.Path, file.Content)
+		if err != nil {
+			return err
+		}
+		files[i].Content = content
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/cache"
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/filter"
+	"github.com/dwrtz/sink/internal/importgraph"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/progress"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// minTruncatedTokens is the smallest remaining budget worth filling with a
+// truncated file; below this the file is dropped instead
+const minTruncatedTokens = 50
+
+const truncationMarker = "\n... [truncated by sink to fit token budget]\n"
+
+// Omission records a file that was dropped or truncated to fit a token budget
+type Omission struct {
+	Path      string
+	Tokens    int  // Token count of the original content
+	Truncated bool // True if the file was truncated rather than dropped
+}
+
+// generateWithinBudget renders a target, dropping or truncating the
+// lowest-priority files until the output, including the sections d adds
+// around the files, fits within cfg.MaxTokens. Priority is determined by
+// cfg.BudgetStrategy; kept files stay in output order and are returned along
+// with the undecorated content.
+func generateWithinBudget(files []processor.FileInfo, cfg *config.Config, target config.OutputTarget, repoRoot string, d decoration) (string, []processor.FileInfo, []Omission, error) {
+	if cfg.MaxTokens <= 0 {
+		content, err := generateContent(files, cfg, target, repoRoot)
+		return content, files, nil, err
+	}
+
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	instructionTokens, err := counter.Count(d.instructions)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	if instructionTokens > cfg.MaxTokens {
+		return "", nil, nil, fmt.Errorf("token budget of %d is too small: the instructions alone have %d tokens", cfg.MaxTokens, instructionTokens)
+	}
+
+	counts, err := countFileTokens(files, counter)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	priority, err := budgetPriority(files, cfg, repoRoot)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// Start with the budget left after the instructions and shrink it by
+	// the formatting and other sections until the rendered output fits
+	budget := cfg.MaxTokens - instructionTokens
+	for {
+		kept, omitted, err := fitBudget(files, counts, priority, counter, budget)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		content, err := generateContent(kept, cfg, target, repoRoot)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		decorated, err := d.apply(content, target, kept, kept, 0, 1)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		count, err := counter.Count(decorated)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to count tokens: %w", err)
+		}
+
+		if count <= cfg.MaxTokens {
+			return content, kept, omitted, nil
+		}
+		if len(kept) == 0 {
+			return "", nil, nil, fmt.Errorf("token budget of %d is too small: output without any files has %d tokens", cfg.MaxTokens, count)
+		}
+		budget -= count - cfg.MaxTokens
+	}
+}
+
+// countFileTokens counts the tokens in the content of each file
+func countFileTokens(files []processor.FileInfo, counter *tokens.Counter) ([]int, error) {
+	counts := make([]int, len(files))
+	for i, file := range files {
+		count, err := counter.Count(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tokens in %s: %w", file.Path, err)
+		}
+		counts[i] = count
+	}
+	return counts, nil
+}
+
+// setFileTokens sets the token count of each file to that of its content,
+// reusing counts from fileCache and reporting them to reporter when set
+func setFileTokens(files []processor.FileInfo, encoding string, fileCache *cache.Cache, reporter *progress.Reporter) error {
+	counter, err := tokens.NewCounter(encoding)
+	if err != nil {
+		return fmt.Errorf("failed to create token counter: %w", err)
+	}
+	reporter.CountingTokens(len(files))
+	for i, file := range files {
+		if fileCache != nil {
+			if count, ok := fileCache.TokenCount(encoding, file.Content); ok {
+				files[i].Tokens = count
+				reporter.Counted(count)
+				continue
+			}
+		}
+		count, err := counter.Count(file.Content)
+		if err != nil {
+			return fmt.Errorf("failed to count tokens in %s: %w", file.Path, err)
+		}
+		files[i].Tokens = count
+		reporter.Counted(count)
+		if fileCache != nil {
+			fileCache.StoreTokenCount(encoding, file.Content, count)
+		}
+	}
+	return nil
+}
+
+// fitBudget keeps files in priority order until the content budget is used
+// up. The first file that does not fit is truncated if enough budget remains,
+// and every file after it is dropped. Kept files are returned in their
+// original order.
+func fitBudget(files []processor.FileInfo, counts []int, priority []int, counter *tokens.Counter, budget int) ([]processor.FileInfo, []Omission, error) {
+	keep := make([]*processor.FileInfo, len(files))
+	var omitted []Omission
+
+	used := 0
+	full := false
+	for _, i := range priority {
+		file := files[i]
+		if !full && used+counts[i] <= budget {
+			keep[i] = &file
+			used += counts[i]
+			continue
+		}
+
+		if !full {
+			full = true
+			remaining := budget - used
+			if remaining >= minTruncatedTokens {
+				content, err := counter.Truncate(file.Content, remaining-minTruncatedTokens/2)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to truncate %s: %w", file.Path, err)
+				}
+				file.Content = content + truncationMarker
+				if file.Tokens, err = counter.Count(file.Content); err != nil {
+					return nil, nil, fmt.Errorf("failed to count tokens in %s: %w", file.Path, err)
+				}
+				keep[i] = &file
+				omitted = append(omitted, Omission{Path: file.Path, Tokens: counts[i], Truncated: true})
+				continue
+			}
+		}
+
+		omitted = append(omitted, Omission{Path: file.Path, Tokens: counts[i]})
+	}
+
+	var kept []processor.FileInfo
+	for _, file := range keep {
+		if file != nil {
+			kept = append(kept, *file)
+		}
+	}
+
+	return kept, omitted, nil
+}
+
+// budgetPriority returns the indices of files from highest to lowest priority
+// according to the budget strategy:
+//   - "order" (default): output order
+//   - "pattern": files matching earlier filter patterns first
+//   - "size": smallest files first
+//   - "depth": files closest to the repository root first
+//   - "importance": files most central in the import graph first
+//
+// Ties keep output order.
+func budgetPriority(files []processor.FileInfo, cfg *config.Config, repoRoot string) ([]int, error) {
+	priority := make([]int, len(files))
+	for i := range files {
+		priority[i] = i
+	}
+
+	var rank func(file processor.FileInfo) int
+	switch cfg.BudgetStrategy {
+	case "", "order":
+		return priority, nil
+	case "pattern":
+		rank = func(file processor.FileInfo) int {
+			for i, pattern := range cfg.FilterPatterns {
+				if filter.MatchesAny(file.RelPath, []string{pattern}, cfg.CaseSensitive) {
+					return i
+				}
+			}
+			return len(cfg.FilterPatterns)
+		}
+	case "size":
+		rank = func(file processor.FileInfo) int {
+			return int(file.Size)
+		}
+	case "depth":
+		rank = func(file processor.FileInfo) int {
+			return strings.Count(filepath.ToSlash(file.RelPath), "/")
+		}
+	case "importance":
+		scores := importgraph.Build(files, repoRoot).Rank()
+		sort.SliceStable(priority, func(a, b int) bool {
+			return scores[priority[a]] > scores[priority[b]]
+		})
+		return priority, nil
+	default:
+		return nil, fmt.Errorf("unknown budget strategy: %s", cfg.BudgetStrategy)
+	}
+
+	sort.SliceStable(priority, func(a, b int) bool {
+		return rank(files[priority[a]]) < rank(files[priority[b]])
+	})
+	return priority, nil
+}
+
+// printOmissions reports the files that were dropped or truncated to w
+func printOmissions(w io.Writer, omitted []Omission, maxTokens int) {
+	if len(omitted) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nOmitted to fit token budget of %d:\n", maxTokens)
+	for _, o := range omitted {
+		action := "dropped"
+		if o.Truncated {
+			action = "truncated"
+		}
+		fmt.Fprintf(w, "  - %s (%d tokens, %s)\n", o.Path, o.Tokens, action)
+	}
+}
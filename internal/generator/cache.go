@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/cache"
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/manifest"
+)
+
+// loadCache returns the file cache of the repository at path for the
+// processing settings of cfg
+func loadCache(cfg *config.Config, path string) *cache.Cache {
+	return cache.Load(filepath.Join(path, manifest.Dir, "cache"), processingSettings(cfg, path))
+}
+
+// processingSettings describes what changes how a file's content is
+// processed or its language detected: the settings, the sink build, whose
+// detectors and outliners may differ, the files the pre-hook command names
+// and the transform plugins. Cached entries are only reused under the same
+// settings.
+func processingSettings(cfg *config.Config, path string) string {
+	return fmt.Sprintf("build=%s redact=%t patterns=%q outline=%t syntax=%v overrides=%v pre-hook=%q hook-files=%s plugins=%s",
+		buildID(), cfg.Redact, cfg.RedactPatterns, cfg.Outline, cfg.SyntaxMap, cfg.LanguageOverrides, cfg.PreHook, hookFingerprint(cfg.PreHook, path), pluginFingerprint())
+}
+
+// buildID identifies the running sink build by its module version, VCS
+// revision and whether cgo, which tree-sitter outlining needs, was enabled
+func buildID() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	id := info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.modified", "CGO_ENABLED":
+			id += " " + setting.Key + "=" + setting.Value
+		}
+	}
+	return id
+}
+
+// hookFingerprint hashes the files a hook command names by path, such as
+// the script in "python3 scripts/scrub.py", so editing them starts a fresh
+// cache. Programs found through PATH and files the hook reads on its own
+// are not covered.
+func hookFingerprint(command, root string) string {
+	if command == "" {
+		return ""
+	}
+	h := sha256.New()
+	for _, field := range strings.Fields(command) {
+		name := strings.Trim(field, `"'`)
+		p := name
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		if info, err := os.Stat(p); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s %s\n", name, cache.Hash(data))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
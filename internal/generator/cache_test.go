@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/plugins"
+)
+
+func TestProcessingSettings(t *testing.T) {
+	root := t.TempDir()
+	script := filepath.Join(root, "scrub.sh")
+	if err := os.WriteFile(script, []byte("sed s/a/b/\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	hooked := config.DefaultConfig()
+	hooked.PreHook = "sh scrub.sh"
+	base := processingSettings(hooked, root)
+
+	cases := []struct {
+		name   string
+		change func(cfg *config.Config)
+	}{
+		{name: "redact patterns", change: func(cfg *config.Config) { cfg.RedactPatterns = []string{"CUST-[0-9]+"} }},
+		{name: "outline", change: func(cfg *config.Config) { cfg.Outline = true }},
+		{name: "pre-hook command", change: func(cfg *config.Config) { cfg.PreHook = "sh scrub.sh --strict" }},
+		{name: "pre-hook file", change: func(cfg *config.Config) {
+			if err := os.WriteFile(script, []byte("sed s/a/c/\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "transform plugins", change: func(cfg *config.Config) {
+			filePlugins = []*plugins.Plugin{{Name: "scrub", Hash: "0123456789abcdef", Transform: true}}
+		}},
+	}
+	for _, tc := range cases {
+		cfg := *hooked
+		tc.change(&cfg)
+		if processingSettings(&cfg, root) == base {
+			t.Errorf("processingSettings() unchanged after changing the %s", tc.name)
+		}
+		// Undo changes outside cfg for the next case
+		filePlugins = nil
+		if err := os.WriteFile(script, []byte("sed s/a/b/\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if processingSettings(hooked, root) != base {
+		t.Error("processingSettings() changed without a change in settings")
+	}
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/manifest"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// changelogHeading starts the section listing changes since the previous
+// generation
+const changelogHeading = "## Changes since last generation\n"
+
+// changelog holds the changes since the previous generation along with the
+// manifest and files to record once the documents are delivered
+type changelog struct {
+	section string
+	next    *manifest.Manifest
+	files   []processor.FileInfo
+}
+
+// buildChangelog compares the processed files against the manifest of the
+// previous generation and renders a markdown section describing the changes.
+// The section is empty when there is no previous manifest.
+func buildChangelog(files []processor.FileInfo, cfg *config.Config, repoRoot string) (*changelog, error) {
+	next := manifest.Build(files, time.Now())
+	c := &changelog{next: next, files: files}
+
+	previous, err := manifest.Load(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return c, nil
+	}
+
+	changes := previous.Compare(next)
+
+	var section strings.Builder
+	section.WriteString(changelogHeading + "\n")
+	section.WriteString(fmt.Sprintf("Previous generation: %s\n\n", previous.GeneratedAt.Format("2006-01-02 15:04:05")))
+	if changes.Empty() {
+		section.WriteString("No changes.\n")
+		c.section = section.String()
+		return c, nil
+	}
+
+	writeList := func(title string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		section.WriteString(fmt.Sprintf("### %s\n\n", title))
+		for _, p := range paths {
+			section.WriteString(fmt.Sprintf("- %s\n", p))
+		}
+		section.WriteString("\n")
+	}
+	writeList("Added", changes.Added)
+	writeList("Removed", changes.Removed)
+	writeList("Modified", changes.Modified)
+
+	if cfg.ChangelogDiffs {
+		contents := make(map[string]string)
+		for _, file := range files {
+			contents[filepath.ToSlash(file.RelPath)] = file.Content
+		}
+
+		for _, p := range changes.Modified {
+			entry, _ := previous.Lookup(p)
+			old, err := manifest.LoadBlob(repoRoot, entry.Hash)
+			if err != nil {
+				// Snapshots only exist if the previous run recorded diffs
+				continue
+			}
+			section.WriteString(fmt.Sprintf("### Diff: %s\n\n````diff\n%s````\n\n", p, lineDiff(old, contents[p])))
+		}
+	}
+
+	c.section = section.String()
+	return c, nil
+}
+
+// save records the manifest, and file snapshots when diffs are enabled, for
+// the next generation to compare against, and removes snapshots the new
+// manifest no longer references
+func (c *changelog) save(cfg *config.Config, repoRoot string) error {
+	if cfg.ChangelogDiffs {
+		if err := manifest.SaveBlobs(repoRoot, c.files); err != nil {
+			return err
+		}
+	}
+	if err := c.next.Save(repoRoot); err != nil {
+		return err
+	}
+	return c.next.PruneBlobs(repoRoot)
+}
+
+// RecordChangelog saves the manifest of a generation made with changelog
+// enabled, so the next one reports changes against it. WriteDocuments calls
+// it once every document is written; callers delivering documents
+// themselves call it after doing so.
+func RecordChangelog(docs []Document, cfg *config.Config, repoRoot string) error {
+	if len(docs) == 0 || docs[0].changes == nil {
+		return nil
+	}
+	return docs[0].changes.save(cfg, repoRoot)
+}
+
+// lineDiff renders the added and removed lines between two versions of a file
+func lineDiff(old, new string) string {
+	var result strings.Builder
+	for _, d := range diff.Do(old, new) {
+		prefix := ""
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		default:
+			continue
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			result.WriteString(prefix + line)
+			if !strings.HasSuffix(line, "\n") {
+				result.WriteString("\n")
+			}
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// volatileFields matches front matter fields that change while the included
+// files stay the same: the timestamp, the checked-out branch and commit, and
+// the token count, which covers the changelog section
+var volatileFields = regexp.MustCompile(`(?m)^(generated-at|branch|commit|tokens): .*\n`)
+
+// fileTimes matches the modification times markdown lists for each file,
+// which a checkout or touch changes without changing the file
+var fileTimes = regexp.MustCompile(`(?m)^(- (?:Created|Modified):) \d{4}-\d\d-\d\d \d\d:\d\d:\d\d$`)
+
+// CheckGeneration generates every output target for path in memory and
+// compares it with the existing output files without writing them. It
+// prints a summary of the differences for each stale file and returns an
+// error if any output is missing or out of date. Volatile front matter
+// fields, file modification times and the changelog section are ignored,
+// and file paths are compared relative to the repository root, so a fresh
+// checkout or a copy elsewhere still matches.
+func CheckGeneration(cfg *config.Config, path string) error {
+	// Generate leaves the changelog manifest alone; only WriteDocuments
+	// records it
+	docs, err := Generate(cfg, path)
+	if err != nil {
+		return err
+	}
+
+	stale := 0
+	for _, doc := range docs {
+		if doc.Target.Path == "" {
+			return fmt.Errorf("--check needs an output path to compare against")
+		}
+		output, content, err := finalOutput(doc.Content, doc.Target.Path, path, cfg)
+		if err != nil {
+			return err
+		}
+
+		existing, err := os.ReadFile(output)
+		if os.IsNotExist(err) {
+			fmt.Printf("%s does not exist\n", output)
+			stale++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read existing output: %w", err)
+		}
+
+		if normalizeOutput(string(existing), doc.Included) == normalizeOutput(content, doc.Included) {
+			continue
+		}
+		printDiffSummary(output, string(existing), content, cfg.TokenEncoding)
+		stale++
+	}
+
+	if stale > 0 {
+		return fmt.Errorf("%d of %d output files are out of date", stale, len(docs))
+	}
+	return nil
+}
+
+// normalizeOutput removes content that changes between otherwise identical
+// generations: volatile front matter fields, file modification times and
+// the changelog section. Absolute paths of files are made relative to the
+// root they were generated from.
+func normalizeOutput(content string, files []processor.FileInfo) string {
+	if strings.HasPrefix(content, "---\n") {
+		if end := strings.Index(content[4:], "\n---\n"); end >= 0 {
+			end += 5
+			content = volatileFields.ReplaceAllString(content[:end], "") + content[end:]
+		}
+	}
+
+	// The changelog is the last section, followed only by instructions
+	// placed after the files
+	if start := strings.Index(content, "\n\n"+changelogHeading); start >= 0 {
+		end := len(content)
+		if i := strings.Index(content[start:], "\n\n"+instructionsHeading); i >= 0 {
+			end = start + i
+		}
+		content = content[:start] + content[end:]
+	}
+
+	content = fileTimes.ReplaceAllString(content, "$1")
+	if root := generatedRoot(content, files); root != "" {
+		content = strings.ReplaceAll(content, root, "")
+	}
+	return strings.TrimRight(content, "\n")
+}
+
+// generatedRoot finds the repository root, with a trailing separator, that
+// content was generated from by looking for the absolute path of the file
+// with the longest relative path, which cannot be the tail of another
+// file's path. It returns "" if content has no such path.
+func generatedRoot(content string, files []processor.FileInfo) string {
+	longest := ""
+	for _, file := range files {
+		if len(file.RelPath) > len(longest) {
+			longest = file.RelPath
+		}
+	}
+	if longest == "" {
+		return ""
+	}
+
+	sep := regexp.QuoteMeta(string(filepath.Separator))
+	pattern := regexp.MustCompile(`(?:^|[\s>"])((?:[A-Za-z]:)?` + sep + `[^\s<>"]*?` + sep + `)` + regexp.QuoteMeta(longest))
+	match := pattern.FindStringSubmatch(content)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// byteLoader is a vocabulary of single bytes, so every byte is one token
+// and counting needs no download
+type byteLoader struct{}
+
+func (byteLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int, 256)
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	return ranks, nil
+}
+
+func init() { tiktoken.SetBpeLoader(byteLoader{}) }
+
+func TestNormalizeOutput(t *testing.T) {
+	files := []processor.FileInfo{{RelPath: "a.go"}, {RelPath: filepath.Join("sub", "b.go")}}
+	cases := []struct {
+		name     string
+		written  string
+		checked  string
+		wantSame bool
+	}{
+		{
+			name: "volatile front matter and changelog",
+			written: "---\ntitle: repo codebase context\ngenerated-at: \"2026-01-01T00:00:00Z\"\nbranch: main\ncommit: abc123\nfiles: 2\ntokens: 120\n---\n\n# a.go\n\n" +
+				"## Changes since last generation\n\n### Added\n\n- a.go\n\n" +
+				"# Instructions\n\nReview a.go\n",
+			checked: "---\ntitle: repo codebase context\ngenerated-at: \"2026-02-01T00:00:00Z\"\nbranch: release\ncommit: def456\nfiles: 2\ntokens: 98\n---\n\n# a.go\n\n" +
+				"## Changes since last generation\n\nNo changes.\n\n" +
+				"# Instructions\n\nReview a.go\n",
+			wantSame: true,
+		},
+		{
+			name:     "fields outside the front matter",
+			written:  "# a.md\n\ncommit: abc\n",
+			checked:  "# a.md\n\ncommit: def\n",
+			wantSame: false,
+		},
+		{
+			name:     "modification times",
+			written:  "## File: /src/a.go\n\n- Created: 2026-01-01 10:00:00\n- Modified: 2026-01-01 10:00:00\n",
+			checked:  "## File: /src/a.go\n\n- Created: 2026-03-04 11:12:13\n- Modified: 2026-03-04 11:12:13\n",
+			wantSame: true,
+		},
+		{
+			name:     "relocated root",
+			written:  "- /home/a/repo/a.go\n- /home/a/repo/sub/b.go\n\n## File: /home/a/repo/sub/b.go\n",
+			checked:  "- /tmp/copy/a.go\n- /tmp/copy/sub/b.go\n\n## File: /tmp/copy/sub/b.go\n",
+			wantSame: true,
+		},
+		{
+			name:     "different files",
+			written:  "- /home/a/repo/a.go\n- /home/a/repo/sub/b.go\n",
+			checked:  "- /tmp/copy/a.go\n- /tmp/copy/sub/c.go\n",
+			wantSame: false,
+		},
+	}
+
+	for _, tc := range cases {
+		same := normalizeOutput(tc.written, files) == normalizeOutput(tc.checked, files)
+		if same != tc.wantSame {
+			t.Errorf("%s: normalized outputs equal = %v, want %v", tc.name, same, tc.wantSame)
+		}
+	}
+}
+
+func TestCheckGenerationStable(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"sub/util.go": "package sub\n\nfunc Util() {}\n",
+	})
+	cfg := config.DefaultConfig()
+	cfg.Output = filepath.Join(root, "context.md")
+	if err := RunGeneration(cfg, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckGeneration(cfg, root); err != nil {
+		t.Errorf("check right after generating: %v", err)
+	}
+
+	// The output inside the tree is not read back as input, and new times
+	// are not a change
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "main.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckGeneration(cfg, root); err != nil {
+		t.Errorf("check after touching a file: %v", err)
+	}
+
+	// A copy elsewhere, output included, has the same content
+	copied := t.TempDir()
+	for _, rel := range []string{"main.go", "sub/util.go", "context.md"} {
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTree(t, copied, map[string]string{rel: string(data)})
+	}
+	cfg.Output = filepath.Join(copied, "context.md")
+	if err := CheckGeneration(cfg, copied); err != nil {
+		t.Errorf("check from a relocated copy: %v", err)
+	}
+
+	// A real change is still reported
+	writeTree(t, copied, map[string]string{"main.go": "package main\n\nfunc main() { println() }\n"})
+	if err := CheckGeneration(cfg, copied); err == nil {
+		t.Error("check after changing a file succeeded")
+	}
+}
+
+// writeTree writes files, keyed by slash-separated path relative to root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// copyToClipboard puts the content of all documents on the system clipboard,
+// separated by blank lines, and reports it to w
+func copyToClipboard(w io.Writer, docs []Document) error {
+	contents := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		contents = append(contents, strings.TrimRight(doc.Content, "\n"))
+	}
+
+	if clipboard.Unsupported {
+		return fmt.Errorf("clipboard is not supported on this system (on Linux, install xclip, xsel or wl-clipboard)")
+	}
+	if err := clipboard.WriteAll(strings.Join(contents, "\n\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+
+	fmt.Fprintln(w, "Output copied to clipboard")
+	return nil
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// confirmOverwrite prints a summary of the differences between the existing
+// output file and the new content and asks the user whether to write it.
+// It returns true without prompting when the output file does not exist yet.
+func confirmOverwrite(output, content, encoding string) (bool, error) {
+	existing, err := os.ReadFile(output)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read existing output: %w", err)
+	}
+
+	if string(existing) == content {
+		fmt.Printf("No changes to %s\n", output)
+		return false, nil
+	}
+
+	printDiffSummary(output, string(existing), content, encoding)
+
+	fmt.Printf("Write changes to %s? [y/N]: ", output)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Printf("\nLeft %s unchanged\n", output)
+		return false, nil
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		fmt.Printf("Left %s unchanged\n", output)
+		return false, nil
+	}
+	return true, nil
+}
+
+// printDiffSummary prints added and removed sections, the line delta and the
+// token delta between old and new content
+func printDiffSummary(output, oldContent, newContent, encoding string) {
+	added, removed := diffSets(sectionTitles(oldContent), sectionTitles(newContent))
+	linesAdded, linesRemoved := diffSets(strings.Split(oldContent, "\n"), strings.Split(newContent, "\n"))
+
+	fmt.Printf("Changes to %s:\n", output)
+	for _, s := range added {
+		fmt.Printf("  + %s\n", s)
+	}
+	for _, s := range removed {
+		fmt.Printf("  - %s\n", s)
+	}
+	fmt.Printf("Sections: +%d -%d, lines: +%d -%d\n", len(added), len(removed), len(linesAdded), len(linesRemoved))
+
+	counter, err := tokens.NewCounter(encoding)
+	if err != nil {
+		return
+	}
+	oldCount, err := counter.Count(oldContent)
+	if err != nil {
+		fmt.Printf("Token delta unavailable: %v\n", err)
+		return
+	}
+	newCount, err := counter.Count(newContent)
+	if err != nil {
+		fmt.Printf("Token delta unavailable: %v\n", err)
+		return
+	}
+	fmt.Printf("Tokens: %d -> %d (%+d)\n", oldCount, newCount, newCount-oldCount)
+}
+
+// sectionTitles returns the per-file section titles found in generated
+// content: markdown level-two headings and XML document sources
+func sectionTitles(content string) []string {
+	var titles []string
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			titles = append(titles, strings.TrimPrefix(line, "## "))
+		case strings.HasPrefix(line, "<source>") && strings.HasSuffix(line, "</source>"):
+			titles = append(titles, strings.TrimSuffix(strings.TrimPrefix(line, "<source>"), "</source>"))
+		}
+	}
+	return titles
+}
+
+// diffSets compares two lists as multisets and returns the entries only in
+// newItems (added) and only in oldItems (removed)
+func diffSets(oldItems, newItems []string) (added, removed []string) {
+	counts := make(map[string]int)
+	for _, item := range oldItems {
+		counts[item]++
+	}
+	for _, item := range newItems {
+		if counts[item] > 0 {
+			counts[item]--
+			continue
+		}
+		added = append(added, item)
+	}
+
+	remaining := make(map[string]int)
+	for _, item := range newItems {
+		remaining[item]++
+	}
+	for _, item := range oldItems {
+		if remaining[item] > 0 {
+			remaining[item]--
+			continue
+		}
+		removed = append(removed, item)
+	}
+
+	return added, removed
+}
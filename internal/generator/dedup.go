@@ -0,0 +1,31 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// dedupFiles replaces the content of every file identical to an earlier file
+// in output order with a short stub pointing at that file. Empty files are
+// left alone. It returns the number of files deduplicated.
+func dedupFiles(files []processor.FileInfo) int {
+	first := make(map[[sha256.Size]byte]string)
+	count := 0
+	for i, file := range files {
+		if file.Content == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(file.Content))
+		original, ok := first[sum]
+		if !ok {
+			first[sum] = file.RelPath
+			continue
+		}
+		files[i].DuplicateOf = original
+		files[i].Content = fmt.Sprintf("(same content as %s)", original)
+		count++
+	}
+	return count
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/processor/chunks"
+	"github.com/dwrtz/sink/internal/processor/json"
+	"github.com/dwrtz/sink/internal/processor/jsonl"
+	"github.com/dwrtz/sink/internal/processor/markdown"
+	"github.com/dwrtz/sink/internal/processor/messages"
+	"github.com/dwrtz/sink/internal/processor/plain"
+	"github.com/dwrtz/sink/internal/processor/xml"
+)
+
+// Formatter renders processed files into an output document
+type Formatter interface {
+	Generate(files []processor.FileInfo) (string, error)
+}
+
+// FormatterFactory creates a formatter configured from cfg
+type FormatterFactory func(cfg *config.Config) Formatter
+
+// defaultFormat is used when an output target does not set a format
+const defaultFormat = "markdown"
+
+var formatters = map[string]FormatterFactory{
+	"markdown": func(cfg *config.Config) Formatter {
+		return markdown.NewGenerator(markdown.Config{
+			NoCodeBlock:      cfg.NoCodeblock,
+			LineNumbers:      cfg.LineNumbers,
+			StripComments:    cfg.StripComments,
+			GroupByDirectory: cfg.GroupByDir,
+			Tree:             cfg.Tree,
+			Tokens:           cfg.FileTokens,
+		})
+	},
+	"xml": func(cfg *config.Config) Formatter {
+		return xml.NewGenerator()
+	},
+	"jsonl": func(cfg *config.Config) Formatter {
+		return jsonl.NewGenerator()
+	},
+	"json": func(cfg *config.Config) Formatter {
+		return json.NewGenerator(json.Config{TokenEncoding: cfg.TokenEncoding})
+	},
+	"messages": func(cfg *config.Config) Formatter {
+		// Generate reports errors reading the instructions file before
+		// any formatter runs
+		instructions, _ := instructionsText(cfg)
+		return messages.NewGenerator(messages.Config{
+			Model:              cfg.Model,
+			SystemPrompt:       cfg.SystemPrompt,
+			Instructions:       instructions,
+			InstructionsBefore: cfg.InstructionsPosition != "after",
+			ChunkTokens:        cfg.MessageTokens,
+			TokenEncoding:      cfg.TokenEncoding,
+		})
+	},
+	"chunks": func(cfg *config.Config) Formatter {
+		return chunks.NewGenerator(chunks.Config{
+			MaxTokens:     cfg.ChunkTokens,
+			Overlap:       cfg.ChunkOverlap,
+			TokenEncoding: cfg.TokenEncoding,
+		})
+	},
+	"plain": func(cfg *config.Config) Formatter {
+		return plain.NewGenerator(plain.Config{
+			LineNumbers:   cfg.LineNumbers,
+			StripComments: cfg.StripComments,
+		})
+	},
+}
+
+// RegisterFormatter makes a formatter available under name, replacing any
+// formatter previously registered with that name
+func RegisterFormatter(name string, factory FormatterFactory) {
+	formatters[name] = factory
+}
+
+// Formats returns the names of all registered formatters in sorted order
+func Formats() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckFormats reports an error if cfg names a format no formatter is
+// registered for
+func CheckFormats(cfg *config.Config) error {
+	valid := func(format string) bool {
+		_, ok := formatters[format]
+		return ok || format == ""
+	}
+	if !valid(cfg.Format) {
+		return fmt.Errorf("invalid format: %s (must be one of %s)", cfg.Format, strings.Join(Formats(), ", "))
+	}
+	for _, target := range cfg.Outputs {
+		if !valid(target.Format) {
+			return fmt.Errorf("invalid format %q for output %s (must be one of %s)", target.Format, target.Path, strings.Join(Formats(), ", "))
+		}
+	}
+	return nil
+}
+
+// NewFormatter returns the formatter registered for format, using markdown
+// when format is empty
+func NewFormatter(format string, cfg *config.Config) (Formatter, error) {
+	if format == "" {
+		format = defaultFormat
+	}
+	factory, ok := formatters[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format: %s (must be one of %s)", format, strings.Join(Formats(), ", "))
+	}
+	return factory(cfg), nil
+}
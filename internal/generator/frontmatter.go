@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/gitinfo"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterData is the metadata emitted as YAML front matter
+type frontMatterData struct {
+	Title       string `yaml:"title"`
+	GeneratedAt string `yaml:"generated-at"`
+	Repo        string `yaml:"repo"`
+	Branch      string `yaml:"branch,omitempty"`
+	Commit      string `yaml:"commit,omitempty"`
+	Files       int    `yaml:"files"`
+	Tokens      int    `yaml:"tokens,omitempty"`
+	ConfigHash  string `yaml:"config-hash"`
+	// Files left out for exceeding max-file-size or being unreadable
+	Skipped []frontMatterSkip `yaml:"skipped,omitempty"`
+}
+
+type frontMatterSkip struct {
+	Path   string `yaml:"path"`
+	Size   int64  `yaml:"size"`
+	Reason string `yaml:"reason"`
+}
+
+// addFrontMatter prepends a YAML front matter block describing the generated
+// document. Git metadata and the token count are omitted when unavailable.
+func addFrontMatter(content, repoRoot string, cfg *config.Config, fileCount int, skipped []processor.SkippedFile, now time.Time) (string, error) {
+	repo := filepath.Base(repoRoot)
+	data := frontMatterData{
+		Title:       fmt.Sprintf("%s codebase context", repo),
+		GeneratedAt: now.Format(time.RFC3339),
+		Repo:        repo,
+		Files:       fileCount,
+		ConfigHash:  cfg.Hash(),
+	}
+	for _, s := range skipped {
+		// Files deselected by patterns are only in the skipped-files report
+		if s.Reason != processor.SkipTooLarge && s.Reason != processor.SkipUnreadable {
+			continue
+		}
+		data.Skipped = append(data.Skipped, frontMatterSkip{Path: s.RelPath, Size: s.Size, Reason: s.Reason})
+	}
+
+	if info, err := gitinfo.Load(repoRoot); err == nil {
+		data.Branch = info.Branch
+		data.Commit = info.Commit
+	}
+
+	if counter, err := tokens.NewCounter(cfg.TokenEncoding); err == nil {
+		if count, err := counter.Count(content); err == nil {
+			data.Tokens = count
+		}
+	}
+
+	header, err := yaml.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render front matter: %w", err)
+	}
+
+	return "---\n" + string(header) + "---\n\n" + content, nil
+}
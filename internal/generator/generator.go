@@ -2,92 +2,620 @@ package generator
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/dwrtz/sink/internal/analyzer"
+	"github.com/dwrtz/sink/internal/cache"
 	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/gitinfo"
+	"github.com/dwrtz/sink/internal/hooks"
+	"github.com/dwrtz/sink/internal/logging"
 	"github.com/dwrtz/sink/internal/processor"
-	"github.com/dwrtz/sink/internal/processor/markdown"
 	"github.com/dwrtz/sink/internal/processor/template"
+	"github.com/dwrtz/sink/internal/progress"
 	"github.com/dwrtz/sink/internal/tokens"
+	"github.com/dwrtz/sink/internal/utils"
 )
 
+// Document is the generated content for a single output target
+type Document struct {
+	Target  config.OutputTarget
+	Content string
+	Omitted []Omission
+	Files   int // Files included in the document, counting truncated files
+	// The included files as rendered, with their token counts
+	Included []processor.FileInfo
+	// Files and directories skipped while scanning; set on the first
+	// document only, like Omitted
+	Skipped []processor.SkippedFile
+	// Resolved path WriteDocuments wrote the document to; empty if it was
+	// printed or only copied
+	Written string
+	// Manifest to record once the documents are delivered; set on the
+	// first document only
+	changes *changelog
+}
+
+// RunGeneration generates every output target for path and writes each one
+// to its output file or stdout
 func RunGeneration(cfg *config.Config, path string) error {
-	fp, err := processor.NewFileProcessor(processor.Config{
-		RepoRoot:        path,
-		FilterPatterns:  cfg.FilterPatterns,
-		ExcludePatterns: cfg.ExcludePatterns,
-		CaseSensitive:   cfg.CaseSensitive,
-		SyntaxMap:       cfg.SyntaxMap,
-	})
+	docs, err := Generate(cfg, path)
+	if err != nil {
+		return err
+	}
+	return WriteDocuments(docs, cfg, path)
+}
+
+// WriteDocuments writes generated documents to their output files or stdout,
+// reports omissions and token counts, and records the changelog manifest
+// unless an overwrite was declined
+func WriteDocuments(docs []Document, cfg *config.Config, path string) error {
+	// Nothing is written when a document is over the limit
+	for _, doc := range docs {
+		if err := checkTokenLimit(doc, cfg); err != nil {
+			return err
+		}
+	}
+
+	w := statusWriter(docs, cfg)
+	declined := false
+	for i, doc := range docs {
+		// With the clipboard enabled, documents without an output path are
+		// only copied rather than printed
+		if doc.Target.Path != "" || !cfg.Clipboard {
+			written, err := writeOutput(w, doc.Content, doc.Target.Path, path, cfg)
+			if err != nil {
+				return err
+			}
+			docs[i].Written = written
+			declined = declined || doc.Target.Path != "" && written == ""
+		}
+		printOmissions(w, doc.Omitted, cfg.MaxTokens)
+
+		if err := reportTokens(w, doc.Content, cfg); err != nil {
+			return err
+		}
+	}
+
+	if len(docs) > 0 && (cfg.ReportSkipped || cfg.SkippedReport != "") {
+		if err := reportSkipped(w, docs[0].Skipped, cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Clipboard {
+		if err := copyToClipboard(w, docs); err != nil {
+			return err
+		}
+	}
+
+	if declined {
+		return nil
+	}
+	return RecordChangelog(docs, cfg, path)
+}
+
+// ProcessFiles scans path and returns the selected files in output order
+func ProcessFiles(cfg *config.Config, path string) ([]processor.FileInfo, error) {
+	files, _, err := processFiles(cfg, path)
+	return files, err
+}
+
+// processFiles is ProcessFiles that also returns the skipped files
+func processFiles(cfg *config.Config, path string) ([]processor.FileInfo, []processor.SkippedFile, error) {
+	var fileCache *cache.Cache
+	if cfg.Cache {
+		fileCache = loadCache(cfg, path)
+	}
+
+	reporter := NewProgress(cfg)
+	reporter.Start()
+	defer reporter.Stop()
+
+	fp, err := newFileProcessor(cfg, path, fileCache, reporter)
 	if err != nil {
-		return fmt.Errorf("failed to create file processor: %w", err)
+		return nil, nil, err
 	}
 
 	files, err := fp.Process()
 	if err != nil {
-		return fmt.Errorf("failed to process files: %w", err)
+		return nil, nil, fmt.Errorf("failed to process files: %w", err)
+	}
+
+	if err := setFileTokens(files, cfg.TokenEncoding, fileCache, reporter); err != nil {
+		return nil, nil, err
+	}
+
+	if fileCache != nil {
+		if err := fileCache.Save(); err != nil {
+			logging.Warn("failed to save cache", "error", err)
+		}
 	}
 
-	content, err := generateContent(files, cfg)
+	if err := orderFiles(files, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	return files, fp.Skipped(), nil
+}
+
+// ListFiles returns the files that would be included for path, in output
+// order, without reading their contents
+func ListFiles(cfg *config.Config, path string) ([]processor.FileInfo, error) {
+	fp, err := NewFileProcessor(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Token counts need file contents
+	if cfg.Sort == "tokens" {
+		return ProcessFiles(cfg, path)
+	}
+
+	files, err := fp.List()
 	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	if err := orderFiles(files, cfg); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// orderFiles sorts files by the configured sort key, then applies the
+// ordering preset and finally the priority patterns. Each step keeps the
+// previous order among files it ranks equally.
+func orderFiles(files []processor.FileInfo, cfg *config.Config) error {
+	var counts []int
+	if cfg.Sort == "tokens" {
+		counts = make([]int, len(files))
+		for i, file := range files {
+			counts[i] = file.Tokens
+		}
+	}
+
+	if err := processor.SortFiles(files, cfg.Sort, cfg.Reverse, counts); err != nil {
 		return err
 	}
+	if err := processor.OrderFiles(files, cfg.Order); err != nil {
+		return err
+	}
+	processor.PrioritizeFiles(files, cfg.PriorityPatterns, cfg.CaseSensitive)
+	return nil
+}
 
-	if cfg.Output != "" {
-		if err := os.MkdirAll(filepath.Dir(cfg.Output), 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+// NewFileProcessor creates a file processor for path that selects files
+// according to cfg
+func NewFileProcessor(cfg *config.Config, path string) (*processor.FileProcessor, error) {
+	return newFileProcessor(cfg, path, nil, nil)
+}
+
+// NewProgress returns a reporter printing progress to stderr while files are
+// processed, or nil if cfg.Quiet is set
+func NewProgress(cfg *config.Config) *progress.Reporter {
+	if cfg.Quiet {
+		return nil
+	}
+	return progress.New(os.Stderr)
+}
+
+// newFileProcessor is NewFileProcessor reusing the processed contents of
+// unchanged files from fileCache, and reporting progress to reporter, if set
+func newFileProcessor(cfg *config.Config, path string, fileCache *cache.Cache, reporter *progress.Reporter) (*processor.FileProcessor, error) {
+	var onlyFiles map[string]bool
+	if cfg.Since != "" {
+		changed, err := gitinfo.ChangedFiles(path, cfg.Since, cfg.Untracked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files changed since %s: %w", cfg.Since, err)
 		}
-		if err := os.WriteFile(cfg.Output, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
+		onlyFiles = changed
+	}
+	if cfg.OnlyFiles != nil {
+		selected := make(map[string]bool)
+		for _, rel := range cfg.OnlyFiles {
+			abs := filepath.Join(path, filepath.FromSlash(rel))
+			if onlyFiles == nil || onlyFiles[abs] {
+				selected[abs] = true
+			}
 		}
-		fmt.Printf("Output written to: %s\n", cfg.Output)
-	} else {
-		fmt.Println(content)
+		onlyFiles = selected
 	}
 
-	// Handle token counting and pricing if enabled
-	if cfg.ShowTokens || cfg.ShowPrice {
-		counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	var maxFileSize int64
+	if cfg.MaxFileSize != "" {
+		size, err := utils.ParseSize(cfg.MaxFileSize)
 		if err != nil {
-			return fmt.Errorf("failed to create token counter: %w", err)
+			return nil, fmt.Errorf("invalid max-file-size: %w", err)
 		}
+		maxFileSize = size
+	}
 
-		count, err := counter.Count(content)
+	fp, err := processor.NewFileProcessor(processor.Config{
+		RepoRoot:          path,
+		FilterPatterns:    cfg.FilterPatterns,
+		ExcludePatterns:   cfg.ExcludePatterns,
+		PrunePatterns:     cfg.PrunePatterns,
+		CaseSensitive:     cfg.CaseSensitive,
+		SyntaxMap:         cfg.SyntaxMap,
+		LanguageOverrides: cfg.LanguageOverrides,
+		IncludeGenerated:  cfg.IncludeGenerated,
+		NoTests:           cfg.NoTests,
+		TestsOnly:         cfg.TestsOnly,
+		OnlyFiles:         onlyFiles,
+		OutputFiles:       outputGlobs(cfg, path),
+		Redact:            cfg.Redact,
+		RedactPatterns:    cfg.RedactPatterns,
+		Outline:           cfg.Outline,
+		PreHook:           cfg.PreHook,
+		Plugins:           filePlugins,
+		MaxFileSize:       maxFileSize,
+		Cache:             fileCache,
+		Concurrency:       cfg.Concurrency,
+		Progress:          reporter,
+		ReportSkipped:     cfg.ReportSkipped || cfg.SkippedReport != "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file processor: %w", err)
+	}
+	return fp, nil
+}
+
+// Generate scans path once and renders every configured output target
+func Generate(cfg *config.Config, path string) ([]Document, error) {
+	files, skipped, err := processFiles(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare against the previous generation before content is annotated
+	var changes *changelog
+	if cfg.Changelog {
+		changes, err = buildChangelog(files, cfg, path)
 		if err != nil {
-			return fmt.Errorf("failed to count tokens: %w", err)
+			return nil, err
+		}
+	}
+	if changes != nil && (cfg.Blame || cfg.Dedup) {
+		changes.files = append([]processor.FileInfo(nil), files...)
+	}
+
+	if cfg.Dedup {
+		dedupFiles(files)
+	}
+
+	if cfg.Blame {
+		if err := annotateBlame(files, cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Dedup || cfg.Blame {
+		// Count what is rendered: duplicate stubs and annotated lines
+		if err := setFileTokens(files, cfg.TokenEncoding, nil, nil); err != nil {
+			return nil, err
 		}
+	}
+
+	instructions, err := instructionsSection(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := decoration{cfg: cfg, repoRoot: path, instructions: instructions, changes: changes, skipped: skipped}
 
-		if cfg.ShowTokens {
-			fmt.Printf("\nToken count: %d\n", count)
+	var docs []Document
+	for _, target := range cfg.OutputTargets() {
+		content, kept, omitted, err := generateWithinBudget(files, cfg, target, path, d)
+		if err != nil {
+			return nil, err
 		}
 
-		if cfg.ShowPrice {
-			price, err := counter.EstimatePrice(count, cfg.OutputTokens, cfg.Model)
+		parts := []part{{files: kept, content: content}}
+		if cfg.SplitTokens > 0 {
+			parts, err = splitParts(kept, cfg, target, path, d)
 			if err != nil {
-				return fmt.Errorf("failed to estimate price: %w", err)
+				return nil, err
 			}
-			fmt.Printf("\nEstimated price for %s: $%.4f\n", cfg.Model, price)
 		}
+
+		for i, p := range parts {
+			partTarget := target
+			if len(parts) > 1 {
+				partTarget.Path = partPath(target.Path, i+1)
+			}
+
+			content, err := d.apply(p.content, target, kept, p.files, i, len(parts))
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg.PostHook != "" {
+				format := partTarget.Format
+				if format == "" {
+					format = "markdown"
+				}
+				content, err = hooks.Document(cfg.PostHook, path, content, partTarget.Path, format)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			doc := Document{Target: partTarget, Content: content, Files: len(p.files), Included: p.files}
+			if i == 0 {
+				doc.Omitted = omitted
+				doc.Skipped = skipped
+				doc.changes = changes
+			}
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// decoration adds the sections Generate places around the rendered files:
+// TODOs, the changelog, instructions and front matter
+type decoration struct {
+	cfg          *config.Config
+	repoRoot     string
+	instructions string
+	changes      *changelog
+	skipped      []processor.SkippedFile
+}
+
+// apply decorates content, the rendered files of part i of n of target.
+// kept are the files of every part.
+func (d decoration) apply(content string, target config.OutputTarget, kept, files []processor.FileInfo, i, n int) (string, error) {
+	isMarkdown := target.Format == "" || target.Format == "markdown"
+	last := i == n-1
+
+	// The TODOs and changelog cover the whole output, so they go in the
+	// last part
+	if d.cfg.Todos && isMarkdown && last {
+		a := analyzer.New()
+		if todos := a.FormatTodosMarkdown(a.FindTodos(kept)); todos != "" {
+			content = strings.TrimRight(content, "\n") + "\n\n" + todos
+		}
+	}
+	if d.changes != nil && d.changes.section != "" && isMarkdown && last {
+		content = strings.TrimRight(content, "\n") + "\n\n" + d.changes.section
+	}
+	// Instructions go in the first part, or the last when placed after the
+	// files
+	after := d.cfg.InstructionsPosition == "after"
+	if d.instructions != "" && (isMarkdown || target.Format == "plain") && (i == 0 && !after || last && after) {
+		content = addInstructions(content, d.instructions, d.cfg)
+	}
+
+	if d.cfg.FrontMatter && isMarkdown {
+		return addFrontMatter(content, d.repoRoot, d.cfg, len(files), d.skipped, time.Now())
+	}
+	return content, nil
+}
+
+// writeOutput writes content to the resolved output path, or to stdout when
+// no output path is set, reports where it went to w and returns the path
+// written, if any
+func writeOutput(w io.Writer, content, output, repoRoot string, cfg *config.Config) (string, error) {
+	if output == "" {
+		fmt.Println(content)
+		return "", nil
+	}
+
+	output, content, err := finalOutput(content, output, repoRoot, cfg)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Confirm {
+		ok, err := confirmOverwrite(output, content, cfg.TokenEncoding)
+		if err != nil || !ok {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(w, "Output written to: %s\n", output)
+
+	return output, nil
+}
+
+// finalOutput resolves the output path and returns it along with the
+// content to write there, injected into the existing file when enabled
+func finalOutput(content, output, repoRoot string, cfg *config.Config) (string, string, error) {
+	output, err := ResolveOutputPath(output, repoRoot, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if cfg.Inject {
+		existing, err := os.ReadFile(output)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read output file for injection: %w", err)
+		}
+		content, err = injectContent(string(existing), content)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to inject into %s: %w", output, err)
+		}
+	}
+	return output, content, nil
+}
+
+// reportTokens prints token counts and price estimates to w if enabled, and
+// checks the output against the context window of the model when the
+// catalog knows it. The context is only checked with --tokens, --price or
+// --strict, or when the provider or model differ from the defaults, so the
+// default model never warns about a model the user did not pick.
+func reportTokens(w io.Writer, content string, cfg *config.Config) error {
+	defaults := config.DefaultConfig()
+	chosenModel := cfg.Provider != defaults.Provider || cfg.Model != defaults.Model
+	if !cfg.ShowTokens && !cfg.ShowPrice && !cfg.Strict && !chosenModel {
+		return nil
+	}
+
+	pricing, err := tokens.LoadPricing(cfg.Pricing)
+	if err != nil {
+		// Prices and --strict need the catalog; the context check alone
+		// is skipped without it
+		if cfg.ShowPrice || cfg.Strict {
+			return err
+		}
+		logging.Warn("cannot check the context window", "error", err)
+	}
+	// Models missing from the catalog have no known context window
+	model, known := pricing.Lookup(cfg.Provider, cfg.Model)
+	checkContext := known && model.Context > 0
+
+	if !cfg.ShowTokens && !cfg.ShowPrice && !checkContext {
+		return nil
+	}
+
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	count, err := counter.Count(content)
+	if err != nil {
+		return fmt.Errorf("failed to count tokens: %w", err)
+	}
+
+	overhead := 0
+	if cfg.ChatFormat {
+		overhead, err = tokens.ChatOverhead(cfg.Provider)
+		if err != nil {
+			return err
+		}
+		count += overhead
+	}
+
+	if cfg.ShowTokens {
+		if cfg.ChatFormat {
+			fmt.Fprintf(w, "\nToken count: %d (including %d %s chat format tokens)\n", count, overhead, cfg.Provider)
+		} else {
+			fmt.Fprintf(w, "\nToken count: %d\n", count)
+		}
+	}
+
+	if cfg.ShowPrice {
+		price, err := pricing.Estimate(cfg.Provider, cfg.Model, count, cfg.OutputTokens)
+		if err != nil {
+			return fmt.Errorf("failed to estimate price: %w", err)
+		}
+		fmt.Fprintf(w, "\nEstimated price for %s: $%.4f\n", cfg.Model, price)
+	}
+
+	if checkContext && count > model.Context {
+		if cfg.Strict {
+			return fmt.Errorf("output has %d tokens, exceeding the %d-token context window of %s", count, model.Context, cfg.Model)
+		}
+		logging.Warn("output exceeds the context window", "tokens", count, "context", model.Context, "model", cfg.Model)
 	}
 
 	return nil
 }
 
-func generateContent(files []processor.FileInfo, cfg *config.Config) (string, error) {
-	if cfg.TemplatePath != "" {
-		templateContent, err := os.ReadFile(cfg.TemplatePath)
+func generateContent(files []processor.FileInfo, cfg *config.Config, target config.OutputTarget, repoRoot string) (string, error) {
+	var partials map[string]string
+	var partialText string
+	if target.TemplateDir != "" {
+		var err error
+		partials, err = readTemplateDir(target.TemplateDir)
+		if err != nil {
+			return "", err
+		}
+		for _, text := range partials {
+			partialText += text
+		}
+	}
+
+	if target.TemplatePath != "" {
+		templateContent, err := os.ReadFile(target.TemplatePath)
 		if err != nil {
 			return "", fmt.Errorf("failed to read template: %w", err)
 		}
 		te := template.NewEngine(string(templateContent))
+		te.Vars = cfg.Vars
+		te.Git = templateGitInfo(repoRoot, string(templateContent)+partialText)
+		te.Partials = partials
 		return te.Execute(files)
 	}
 
-	mg := markdown.NewGenerator(markdown.Config{
-		NoCodeBlock:   cfg.NoCodeblock,
-		LineNumbers:   cfg.LineNumbers,
-		StripComments: cfg.StripComments,
-	})
-	return mg.Generate(files)
+	if target.FileTemplatePath != "" || target.DocumentTemplatePath != "" {
+		var texts [2]string
+		for i, p := range []string{target.FileTemplatePath, target.DocumentTemplatePath} {
+			if p == "" {
+				continue
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return "", fmt.Errorf("failed to read template: %w", err)
+			}
+			texts[i] = string(content)
+		}
+		te := template.NewDocumentEngine(texts[0], texts[1])
+		te.Vars = cfg.Vars
+		te.Git = templateGitInfo(repoRoot, texts[0]+texts[1]+partialText)
+		te.Partials = partials
+		return te.Execute(files)
+	}
+
+	if partials != nil {
+		main, ok := partials["main"]
+		if !ok {
+			return "", fmt.Errorf("template directory %s has no main.tmpl", target.TemplateDir)
+		}
+		te := template.NewEngine(main)
+		te.Vars = cfg.Vars
+		te.Git = templateGitInfo(repoRoot, partialText)
+		te.Partials = partials
+		return te.Execute(files)
+	}
+
+	formatter, err := NewFormatter(target.Format, cfg)
+	if err != nil {
+		return "", err
+	}
+	return formatter.Generate(files)
+}
+
+// templateGitInfo loads the metadata of the repository at repoRoot for
+// templates that refer to .Git. Reading the worktree status hashes every
+// file, so it is skipped for templates that don't use it. Returns nil
+// outside a git repository.
+func templateGitInfo(repoRoot, templateText string) *gitinfo.Info {
+	if !strings.Contains(templateText, ".Git") {
+		return nil
+	}
+	info, err := gitinfo.LoadStatus(repoRoot)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// readTemplateDir reads the *.tmpl files in dir, keyed by file name without
+// the extension
+func readTemplateDir(dir string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	partials := make(map[string]string, len(paths))
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		partials[strings.TrimSuffix(filepath.Base(p), ".tmpl")] = string(content)
+	}
+	return partials, nil
 }
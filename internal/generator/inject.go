@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	beginMarker = "<!-- sink:begin -->"
+	endMarker   = "<!-- sink:end -->"
+)
+
+// injectContent replaces the region between the sink begin and end markers
+// in document with content, leaving everything outside the markers untouched
+func injectContent(document, content string) (string, error) {
+	begin := strings.Index(document, beginMarker)
+	if begin < 0 {
+		return "", fmt.Errorf("missing %s marker", beginMarker)
+	}
+	end := strings.Index(document[begin:], endMarker)
+	if end < 0 {
+		return "", fmt.Errorf("missing %s marker after %s", endMarker, beginMarker)
+	}
+	end += begin
+
+	var result strings.Builder
+	result.WriteString(document[:begin+len(beginMarker)])
+	result.WriteString("\n")
+	result.WriteString(strings.TrimRight(content, "\n"))
+	result.WriteString("\n")
+	result.WriteString(document[end:])
+	return result.String(), nil
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestInjectContent(t *testing.T) {
+	cases := []struct {
+		document string
+		content  string
+		want     string
+		wantErr  bool
+	}{
+		{
+			document: "# Design\n\n<!-- sink:begin -->\nold\n<!-- sink:end -->\n\nMore prose\n",
+			content:  "new\n",
+			want:     "# Design\n\n<!-- sink:begin -->\nnew\n<!-- sink:end -->\n\nMore prose\n",
+		},
+		{
+			document: "<!-- sink:begin --><!-- sink:end -->",
+			content:  "generated",
+			want:     "<!-- sink:begin -->\ngenerated\n<!-- sink:end -->",
+		},
+		{
+			document: "no markers here",
+			content:  "generated",
+			wantErr:  true,
+		},
+		{
+			document: "<!-- sink:end -->\n<!-- sink:begin -->\n",
+			content:  "generated",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := injectContent(tc.document, tc.content)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("injectContent(%q) expected error", tc.document)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("injectContent(%q) unexpected error: %v", tc.document, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("injectContent(%q) = %q; want %q", tc.document, got, tc.want)
+		}
+	}
+}
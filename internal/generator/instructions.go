@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+)
+
+// instructionsHeading starts the section holding the task description
+const instructionsHeading = "# Instructions\n"
+
+// instructionsSection renders the configured task description as an
+// Instructions section, or returns "" if none is configured
+func instructionsSection(cfg *config.Config) (string, error) {
+	switch cfg.InstructionsPosition {
+	case "", "before", "after":
+	default:
+		return "", fmt.Errorf("invalid instructions position: %s (must be before or after)", cfg.InstructionsPosition)
+	}
+
+	text, err := instructionsText(cfg)
+	if err != nil || text == "" {
+		return "", err
+	}
+	return instructionsHeading + "\n" + text + "\n", nil
+}
+
+// instructionsText returns the inline instructions followed by the contents
+// of the instructions file, or "" if neither is set
+func instructionsText(cfg *config.Config) (string, error) {
+	var texts []string
+	if text := strings.TrimSpace(cfg.Instructions); text != "" {
+		texts = append(texts, text)
+	}
+	if cfg.InstructionsFile != "" {
+		data, err := os.ReadFile(cfg.InstructionsFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read instructions file: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// addInstructions places section before or after content, as configured
+func addInstructions(content, section string, cfg *config.Config) string {
+	if cfg.InstructionsPosition == "after" {
+		return strings.TrimRight(content, "\n") + "\n\n" + section
+	}
+	return section + "\n" + content
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// limitTopFiles is how many of the largest files are listed when a document
+// exceeds the token limit
+const limitTopFiles = 10
+
+// checkTokenLimit fails when doc has more than cfg.FailOverTokens tokens,
+// printing the overage and the files that contribute the most tokens to
+// stderr even in quiet mode, since they explain the failure
+func checkTokenLimit(doc Document, cfg *config.Config) error {
+	if cfg.FailOverTokens <= 0 {
+		return nil
+	}
+
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to create token counter: %w", err)
+	}
+	count, err := counter.Count(doc.Content)
+	if err != nil {
+		return fmt.Errorf("failed to count tokens: %w", err)
+	}
+	if count <= cfg.FailOverTokens {
+		return nil
+	}
+
+	over := count - cfg.FailOverTokens
+	fmt.Fprintf(os.Stderr, "\nOutput has %d tokens, %d over the limit of %d\n", count, over, cfg.FailOverTokens)
+
+	largest := append([]processor.FileInfo(nil), doc.Included...)
+	sort.SliceStable(largest, func(i, j int) bool {
+		return largest[i].Tokens > largest[j].Tokens
+	})
+	if len(largest) > limitTopFiles {
+		largest = largest[:limitTopFiles]
+	}
+	if len(largest) > 0 {
+		fmt.Fprintln(os.Stderr, "Largest files:")
+		for _, file := range largest {
+			fmt.Fprintf(os.Stderr, "  - %s (%d tokens)\n", file.RelPath, file.Tokens)
+		}
+	}
+
+	return fmt.Errorf("output exceeds the token limit of %d by %d tokens", cfg.FailOverTokens, over)
+}
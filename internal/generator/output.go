@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/gitinfo"
+)
+
+// outputPathData holds the values available to templated output paths
+type outputPathData struct {
+	Branch    string
+	SHA       string
+	Date      string
+	Time      string
+	Timestamp int64
+}
+
+// ResolveOutputPath expands placeholders such as {{.Branch}}, {{.SHA}} and
+// {{.Date}} in an output path. Paths without placeholders are returned as is.
+func ResolveOutputPath(output, repoRoot string, now time.Time) (string, error) {
+	if !strings.Contains(output, "{{") {
+		return output, nil
+	}
+
+	tmpl, err := template.New("output").Parse(output)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path template: %w", err)
+	}
+
+	data := outputPathData{
+		Branch:    "nogit",
+		SHA:       "nogit",
+		Date:      now.Format("2006-01-02"),
+		Time:      now.Format("150405"),
+		Timestamp: now.Unix(),
+	}
+	if info, err := gitinfo.Load(repoRoot); err == nil {
+		data.SHA = info.ShortCommit
+		data.Branch = "detached"
+		if info.Branch != "" {
+			// Branch names like feature/foo must not introduce directories
+			data.Branch = strings.ReplaceAll(info.Branch, "/", "-")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to resolve output path: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// globMeta escapes the characters filepath.Match treats specially
+var globMeta = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
+// outputGlobs returns globs matching the absolute paths of every output
+// file of cfg and their split parts, so a generation written inside the
+// scanned tree is not read back as input
+func outputGlobs(cfg *config.Config, repoRoot string) []string {
+	var globs []string
+	for _, target := range cfg.OutputTargets() {
+		if target.Path == "" {
+			continue
+		}
+		output, err := ResolveOutputPath(target.Path, repoRoot, time.Now())
+		if err != nil {
+			continue
+		}
+		abs, err := filepath.Abs(output)
+		if err != nil {
+			continue
+		}
+		if filepath.Separator == '/' {
+			abs = globMeta.Replace(abs)
+		}
+		ext := filepath.Ext(abs)
+		globs = append(globs, abs, strings.TrimSuffix(abs, ext)+".part*"+ext)
+	}
+	return globs
+}
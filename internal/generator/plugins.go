@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/plugins"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// filePlugins are the registered plugins that transform file contents, in
+// the order they run
+var filePlugins []*plugins.Plugin
+
+// RegisterPlugins registers the formats of plugins as formatters and runs
+// the transforms of plugins on every processed file. A plugin naming a
+// format that is already registered, by sink or another plugin, is left out
+// with an error.
+func RegisterPlugins(list []*plugins.Plugin) []error {
+	var errs []error
+	for _, p := range list {
+		if err := checkPluginFormats(p); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, format := range p.Formats {
+			RegisterFormatter(format, func(cfg *config.Config) Formatter {
+				return pluginFormatter{plugin: p, format: format}
+			})
+		}
+		if p.Transform {
+			filePlugins = append(filePlugins, p)
+		}
+	}
+	return errs
+}
+
+// checkPluginFormats reports an error if a format of p is already
+// registered
+func checkPluginFormats(p *plugins.Plugin) error {
+	for _, format := range p.Formats {
+		if _, ok := formatters[format]; ok {
+			return fmt.Errorf("plugin %s: format %s is already registered", p.Name, format)
+		}
+	}
+	return nil
+}
+
+// pluginFormatter renders a format with a plugin
+type pluginFormatter struct {
+	plugin *plugins.Plugin
+	format string
+}
+
+func (f pluginFormatter) Generate(files []processor.FileInfo) (string, error) {
+	input := make([]plugins.File, len(files))
+	for i, file := range files {
+		input[i] = plugins.File{
+			Path:     file.RelPath,
+			Language: file.Language,
+			Content:  file.Content,
+			Tokens:   file.Tokens,
+		}
+	}
+	return f.plugin.Document(f.format, input)
+}
+
+// pluginFingerprint names the transform plugins and hashes of their
+// modules, so replacing or reordering them starts a fresh cache
+func pluginFingerprint() string {
+	names := make([]string, len(filePlugins))
+	for i, p := range filePlugins {
+		names[i] = p.Name + "@" + p.Hash
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// maxSkippedExamples is how many paths are listed per reason in the printed
+// summary; the JSON report lists them all
+const maxSkippedExamples = 10
+
+type skippedEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+type skippedReport struct {
+	Counts  map[string]int `json:"counts"`
+	Skipped []skippedEntry `json:"skipped"`
+}
+
+// reportSkipped prints the skipped files grouped by reason to w if
+// cfg.ReportSkipped is set, and writes them to cfg.SkippedReport if set
+func reportSkipped(w io.Writer, skipped []processor.SkippedFile, cfg *config.Config) error {
+	byReason := make(map[string][]string)
+	report := skippedReport{Counts: make(map[string]int), Skipped: []skippedEntry{}}
+	for _, s := range skipped {
+		byReason[s.Reason] = append(byReason[s.Reason], s.RelPath)
+		report.Counts[s.Reason]++
+		report.Skipped = append(report.Skipped, skippedEntry{Path: s.RelPath, Reason: s.Reason, Size: s.Size})
+	}
+
+	if cfg.ReportSkipped {
+		printSkipped(w, byReason, len(skipped))
+	}
+
+	if cfg.SkippedReport == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode skipped files report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.SkippedReport), 0755); err != nil {
+		return fmt.Errorf("failed to create skipped files report directory: %w", err)
+	}
+	if err := os.WriteFile(cfg.SkippedReport, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write skipped files report: %w", err)
+	}
+	return nil
+}
+
+// printSkipped lists up to maxSkippedExamples paths per reason, largest
+// groups first
+func printSkipped(w io.Writer, byReason map[string][]string, total int) {
+	if total == 0 {
+		fmt.Fprintln(w, "\nNothing was skipped")
+		return
+	}
+
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if len(byReason[reasons[i]]) != len(byReason[reasons[j]]) {
+			return len(byReason[reasons[i]]) > len(byReason[reasons[j]])
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	fmt.Fprintf(w, "\nSkipped %d files and directories:\n", total)
+	for _, reason := range reasons {
+		paths := byReason[reason]
+		sort.Strings(paths)
+		fmt.Fprintf(w, "  %s (%d):\n", reason, len(paths))
+		for i, path := range paths {
+			if i == maxSkippedExamples {
+				fmt.Fprintf(w, "    ... and %d more\n", len(paths)-maxSkippedExamples)
+				break
+			}
+			fmt.Fprintf(w, "    - %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// part is a rendered chunk of an output target
+type part struct {
+	files   []processor.FileInfo
+	content string
+}
+
+// splitParts groups consecutive files into parts whose rendered output stays
+// within cfg.SplitTokens, including the sections d adds to each part. Each
+// part is rendered on its own, so per-part headers such as the markdown
+// table of contents only list its own files. A file that exceeds the limit
+// on its own is placed in a part by itself. The content of each part is
+// returned undecorated.
+func splitParts(files []processor.FileInfo, cfg *config.Config, target config.OutputTarget, repoRoot string, d decoration) ([]part, error) {
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	counts, err := countFileTokens(files, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []part
+	start := 0
+	for start < len(files) {
+		// Pack files by their content tokens, then back off one file at a
+		// time until the rendered part, including formatting, fits
+		end := start
+		used := 0
+		for end < len(files) && (end == start || used+counts[end] <= cfg.SplitTokens) {
+			used += counts[end]
+			end++
+		}
+
+		for {
+			content, err := generateContent(files[start:end], cfg, target, repoRoot)
+			if err != nil {
+				return nil, err
+			}
+			// Which sections a part gets depends on whether it is the
+			// first or the last, so decorate it as part i of i+1 if it ends
+			// the files and of i+2 otherwise
+			i, n := len(parts), len(parts)+1
+			if end < len(files) {
+				n++
+			}
+			decorated, err := d.apply(content, target, files, files[start:end], i, n)
+			if err != nil {
+				return nil, err
+			}
+			count, err := counter.Count(decorated)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count tokens: %w", err)
+			}
+
+			if count <= cfg.SplitTokens || end-start == 1 {
+				if count > cfg.SplitTokens {
+					logging.Warn("file alone exceeds the split size", "path", files[start].Path, "tokens", count)
+				}
+				parts = append(parts, part{files: files[start:end], content: content})
+				break
+			}
+			end--
+		}
+		start = end
+	}
+
+	if len(parts) == 0 {
+		content, err := generateContent(nil, cfg, target, repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part{content: content})
+	}
+
+	return parts, nil
+}
+
+// partPath inserts the part number before the extension of path, so
+// output.md becomes output.part1.md
+func partPath(path string, n int) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
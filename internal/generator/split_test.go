@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+func TestPartPath(t *testing.T) {
+	cases := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{path: "output.md", n: 1, want: "output.part1.md"},
+		{path: "docs/context.xml", n: 12, want: "docs/context.part12.xml"},
+		{path: "context-{{.Date}}.md", n: 2, want: "context-{{.Date}}.part2.md"},
+		{path: "output", n: 3, want: "output.part3"},
+		{path: "", n: 1, want: ""},
+	}
+
+	for _, tc := range cases {
+		if got := partPath(tc.path, tc.n); got != tc.want {
+			t.Errorf("partPath(%q, %d) = %q, want %q", tc.path, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestSplitPartsDecorated(t *testing.T) {
+	root := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 6; i++ {
+		files[fmt.Sprintf("f%d.go", i)] = "package f\n\n// " + strings.Repeat("x", 200) + "\n"
+	}
+	writeTree(t, root, files)
+
+	counter, err := tokens.NewCounter(config.DefaultConfig().TokenEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two undecorated files fit in a part with little room to spare
+	processed, _, err := processFiles(config.DefaultConfig(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := generateContent(processed[:2], config.DefaultConfig(), config.OutputTarget{}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairTokens, err := counter.Count(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		change func(cfg *config.Config)
+	}{
+		{name: "instructions before", change: func(cfg *config.Config) {
+			cfg.Instructions = strings.Repeat("Review the code. ", 20)
+		}},
+		{name: "instructions after", change: func(cfg *config.Config) {
+			cfg.Instructions = strings.Repeat("Review the code. ", 20)
+			cfg.InstructionsPosition = "after"
+		}},
+		{name: "front matter", change: func(cfg *config.Config) { cfg.FrontMatter = true }},
+	}
+
+	for _, tc := range cases {
+		cfg := config.DefaultConfig()
+		cfg.SplitTokens = pairTokens + 50
+		tc.change(cfg)
+		docs, err := Generate(cfg, root)
+		if err != nil {
+			t.Errorf("%s: Generate() error = %v", tc.name, err)
+			continue
+		}
+		if len(docs) < 2 {
+			t.Errorf("%s: got %d parts, want several", tc.name, len(docs))
+		}
+		for i, doc := range docs {
+			count, err := counter.Count(doc.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count > cfg.SplitTokens {
+				t.Errorf("%s: part %d has %d tokens, want at most %d", tc.name, i+1, count, cfg.SplitTokens)
+			}
+		}
+	}
+}
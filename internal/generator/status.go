@@ -0,0 +1,23 @@
+package generator
+
+import (
+	"io"
+	"os"
+
+	"github.com/dwrtz/sink/internal/config"
+)
+
+// statusWriter returns where WriteDocuments reports what it did. Reports go
+// to stderr when a document is printed to stdout, so piped output holds
+// nothing but the document, and are discarded with cfg.Quiet.
+func statusWriter(docs []Document, cfg *config.Config) io.Writer {
+	if cfg.Quiet {
+		return io.Discard
+	}
+	for _, doc := range docs {
+		if doc.Target.Path == "" && !cfg.Clipboard {
+			return os.Stderr
+		}
+	}
+	return os.Stdout
+}
@@ -0,0 +1,139 @@
+package gitinfo
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// blameLookahead bounds how far ahead in the committed file a working tree
+// line is searched for when aligning blame results with local edits
+const blameLookahead = 50
+
+// blameCache holds blame results keyed by commit hash and path, so repeated
+// generations (e.g. in watch mode) only blame each file once per commit
+var (
+	blameCache   = make(map[string][]*git.Line)
+	blameCacheMu sync.Mutex
+)
+
+// Blamer annotates file contents with git blame information
+type Blamer struct {
+	root   string
+	commit *object.Commit
+}
+
+// NewBlamer creates a Blamer for the repository containing path, blaming
+// against the HEAD commit
+func NewBlamer(path string) (*Blamer, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	return &Blamer{root: worktree.Filesystem.Root(), commit: commit}, nil
+}
+
+// Annotate prefixes each line of content with the abbreviated commit and
+// author that last changed it. Lines that differ from HEAD are marked as
+// uncommitted.
+func (b *Blamer) Annotate(path, content string) (string, error) {
+	lines, err := b.blame(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Don't annotate the empty line after a trailing newline
+	trailingNewline := strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+
+	var result strings.Builder
+	next := 0
+	for i, line := range strings.Split(content, "\n") {
+		if i > 0 {
+			result.WriteString("\n")
+		}
+
+		prefix := fmt.Sprintf("%-7s %-12s", "0000000", "uncommitted")
+		for k := next; k < len(lines) && k < next+blameLookahead; k++ {
+			if lines[k].Text == line {
+				prefix = fmt.Sprintf("%-7s %-12s", lines[k].Hash.String()[:7], truncate(lines[k].AuthorName, 12))
+				next = k + 1
+				break
+			}
+		}
+		result.WriteString(prefix)
+		result.WriteString(" | ")
+		result.WriteString(line)
+	}
+	if trailingNewline {
+		result.WriteString("\n")
+	}
+
+	return result.String(), nil
+}
+
+// blame returns the cached blame lines for path, computing them if needed
+func (b *Blamer) blame(path string) ([]*git.Line, error) {
+	rel, err := filepath.Rel(b.root, path)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+	key := b.commit.Hash.String() + ":" + rel
+
+	blameCacheMu.Lock()
+	lines, ok := blameCache[key]
+	blameCacheMu.Unlock()
+	if ok {
+		return lines, nil
+	}
+
+	// Files not yet committed have no blame; every line is uncommitted
+	result, err := git.Blame(b.commit, rel)
+	if err != nil && !errors.Is(err, object.ErrFileNotFound) {
+		return nil, fmt.Errorf("failed to blame %s: %w", rel, err)
+	}
+	if result != nil {
+		lines = result.Lines
+	}
+
+	blameCacheMu.Lock()
+	blameCache[key] = lines
+	blameCacheMu.Unlock()
+
+	return lines, nil
+}
+
+// truncate shortens s to at most n runes, so names are never cut inside a
+// multi-byte character and line up with fmt's rune-based padding
+func truncate(s string, n int) string {
+	runes := 0
+	for i := range s {
+		if runes == n {
+			return s[:i]
+		}
+		runes++
+	}
+	return s
+}
@@ -0,0 +1,23 @@
+package gitinfo
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"Ada", 12, "Ada"},
+		{"Grace Hopper Jr", 12, "Grace Hopper"},
+		{"José Gonçalves", 12, "José Gonçalv"},
+		{"山田太郎山田太郎山田太郎山田", 12, "山田太郎山田太郎山田太郎"},
+		{"", 12, ""},
+	}
+
+	for _, tc := range cases {
+		if got := truncate(tc.s, tc.n); got != tc.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
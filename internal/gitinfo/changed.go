@@ -0,0 +1,86 @@
+package gitinfo
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangedFiles returns the absolute paths of files that changed between ref
+// and HEAD in the repository containing path, along with files that have
+// uncommitted changes. Untracked files are included when untracked is set.
+// Deleted files are not included.
+func ChangedFiles(path, ref string, untracked bool) (map[string]bool, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	root := worktree.Filesystem.Root()
+
+	fromTree, err := resolveTree(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := resolveTree(repo, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s against HEAD: %w", ref, err)
+	}
+
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		// Deleted files have no destination
+		if change.To.Name != "" {
+			changed[filepath.Join(root, filepath.FromSlash(change.To.Name))] = true
+		}
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	for name, st := range status {
+		switch {
+		case st.Worktree == git.Untracked:
+			if !untracked {
+				continue
+			}
+		case st.Worktree == git.Deleted, st.Staging == git.Deleted && st.Worktree == git.Unmodified:
+			continue
+		case st.Worktree == git.Unmodified && st.Staging == git.Unmodified:
+			continue
+		}
+		changed[filepath.Join(root, filepath.FromSlash(name))] = true
+	}
+
+	return changed, nil
+}
+
+// resolveTree returns the tree of the commit that rev resolves to
+func resolveTree(repo *git.Repository, rev string) (*object.Tree, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit %s: %w", rev, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree of %s: %w", rev, err)
+	}
+	return tree, nil
+}
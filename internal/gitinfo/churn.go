@@ -0,0 +1,96 @@
+package gitinfo
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Churn describes how often a file has changed
+type Churn struct {
+	Commits    int       // Number of commits that changed the file
+	LastChange time.Time // Author time of the most recent of those commits
+}
+
+// FileChurn walks the history of HEAD in the repository containing path and
+// returns, by absolute file path, how many commits changed each file. Only
+// commits authored after since are counted, unless since is zero. Merge
+// commits are compared against their first parent; in shallow clones,
+// commits whose parent is missing are skipped.
+func FileChurn(path string, since time.Time) (map[string]*Churn, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	root := worktree.Filesystem.Root()
+
+	opts := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if !since.IsZero() {
+		opts.Since = &since
+	}
+	commits, err := repo.Log(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log: %w", err)
+	}
+	defer commits.Close()
+
+	churn := make(map[string]*Churn)
+	err = commits.ForEach(func(commit *object.Commit) error {
+		tree, err := commit.Tree()
+		if err != nil {
+			return fmt.Errorf("failed to load tree of %s: %w", commit.Hash, err)
+		}
+
+		var parentTree *object.Tree
+		if commit.NumParents() > 0 {
+			parent, err := commit.Parent(0)
+			if err != nil {
+				// The parent is missing in shallow clones
+				if errors.Is(err, plumbing.ErrObjectNotFound) {
+					return nil
+				}
+				return fmt.Errorf("failed to load parent of %s: %w", commit.Hash, err)
+			}
+			if parentTree, err = parent.Tree(); err != nil {
+				return fmt.Errorf("failed to load tree of %s: %w", parent.Hash, err)
+			}
+		}
+
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", commit.Hash, err)
+		}
+		for _, change := range changes {
+			name := change.To.Name
+			if name == "" {
+				name = change.From.Name
+			}
+			abs := filepath.Join(root, filepath.FromSlash(name))
+			c, ok := churn[abs]
+			if !ok {
+				c = &Churn{}
+				churn[abs] = c
+			}
+			c.Commits++
+			if commit.Author.When.After(c.LastChange) {
+				c.LastChange = commit.Author.When
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return churn, nil
+}
@@ -0,0 +1,72 @@
+// Package hooks runs the user's external commands that transform file
+// contents and generated documents
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// fileInput is what a pre-hook receives on stdin
+type fileInput struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Shell returns a command running command with the platform's shell
+func Shell(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// File runs a pre-hook on a file's content. The hook receives the path
+// relative to dir and the content as a JSON object on stdin, and prints
+// the content to use instead.
+func File(command, dir, relPath, content string) (string, error) {
+	input, err := json.Marshal(fileInput{Path: relPath, Content: content})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pre-hook input: %w", err)
+	}
+	out, err := run(command, dir, input, "SINK_FILE="+relPath)
+	if err != nil {
+		return "", fmt.Errorf("pre-hook failed for %s: %w", relPath, err)
+	}
+	return out, nil
+}
+
+// Document runs a post-hook on a generated document. The hook receives the
+// document on stdin, with its output path and format in SINK_OUTPUT and
+// SINK_FORMAT, and prints the document to use instead.
+func Document(command, dir, content, output, format string) (string, error) {
+	out, err := run(command, dir, []byte(content), "SINK_OUTPUT="+output, "SINK_FORMAT="+format)
+	if err != nil {
+		return "", fmt.Errorf("post-hook failed: %w", err)
+	}
+	return out, nil
+}
+
+// run runs command in dir with stdin and extra environment variables, and
+// returns its stdout. Its stderr is included in the error if it fails.
+func run(command, dir string, stdin []byte, env ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := Shell(command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,264 @@
+// Package importgraph builds a graph of which files import which for Go,
+// Python and JavaScript/TypeScript sources and ranks files by centrality.
+package importgraph
+
+import (
+	"bufio"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+const (
+	// Probability of following an import rather than jumping to a file
+	damping = 0.85
+	// Entry points are this many times more likely to be jumped to
+	entryWeight = 10
+	iterations  = 50
+)
+
+// Graph holds the import edges between files, by index into the files it
+// was built from
+type Graph struct {
+	// Imports[i] lists the files imported by file i
+	Imports [][]int
+	// Entry[i] is set for entry points such as Go main packages
+	Entry []bool
+}
+
+// Build resolves the imports of files to other files in the list. Imports of
+// packages outside the repository are ignored.
+func Build(files []processor.FileInfo, repoRoot string) *Graph {
+	g := &Graph{
+		Imports: make([][]int, len(files)),
+		Entry:   make([]bool, len(files)),
+	}
+
+	byPath := make(map[string]int, len(files))
+	byDir := make(map[string][]int)
+	for i, file := range files {
+		rel := filepath.ToSlash(file.RelPath)
+		byPath[rel] = i
+		if file.Language == "go" {
+			byDir[path.Dir(rel)] = append(byDir[path.Dir(rel)], i)
+		}
+	}
+	module := modulePath(repoRoot)
+
+	for i, file := range files {
+		rel := filepath.ToSlash(file.RelPath)
+		var targets []int
+		switch file.Language {
+		case "go":
+			imports, isMain := goImports(file.Content)
+			g.Entry[i] = isMain
+			for _, imp := range imports {
+				if module == "" || (imp != module && !strings.HasPrefix(imp, module+"/")) {
+					continue
+				}
+				dir := strings.TrimPrefix(strings.TrimPrefix(imp, module), "/")
+				if dir == "" {
+					dir = "."
+				}
+				targets = append(targets, byDir[dir]...)
+			}
+		case "python":
+			g.Entry[i] = path.Base(rel) == "__main__.py" || strings.Contains(file.Content, "__name__ == \"__main__\"") || strings.Contains(file.Content, "__name__ == '__main__'")
+			for _, imp := range pythonImports(file.Content) {
+				if j, ok := resolvePython(imp, rel, byPath); ok {
+					targets = append(targets, j)
+				}
+			}
+		case "javascript", "jsx", "typescript", "tsx":
+			base := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+			g.Entry[i] = (base == "index" || base == "main") && !strings.Contains(path.Dir(rel), "/")
+			for _, imp := range jsImports(file.Content) {
+				if j, ok := resolveJS(imp, rel, byPath); ok {
+					targets = append(targets, j)
+				}
+			}
+		}
+
+		seen := map[int]bool{i: true}
+		for _, j := range targets {
+			if !seen[j] {
+				seen[j] = true
+				g.Imports[i] = append(g.Imports[i], j)
+			}
+		}
+	}
+	return g
+}
+
+// Rank returns a PageRank score for every file. Rank flows from importers to
+// the files they import, and random jumps favor entry points, so entry points
+// and the core packages they depend on outrank leaf utilities.
+func (g *Graph) Rank() []float64 {
+	n := len(g.Imports)
+	if n == 0 {
+		return nil
+	}
+
+	jump := make([]float64, n)
+	total := 0.0
+	for i := range jump {
+		jump[i] = 1
+		if g.Entry[i] {
+			jump[i] = entryWeight
+		}
+		total += jump[i]
+	}
+	for i := range jump {
+		jump[i] /= total
+	}
+
+	rank := make([]float64, n)
+	copy(rank, jump)
+	for iter := 0; iter < iterations; iter++ {
+		next := make([]float64, n)
+		dangling := 0.0
+		for i, imports := range g.Imports {
+			if len(imports) == 0 {
+				dangling += rank[i]
+				continue
+			}
+			share := rank[i] / float64(len(imports))
+			for _, j := range imports {
+				next[j] += damping * share
+			}
+		}
+		for i := range next {
+			next[i] += (1 - damping + damping*dangling) * jump[i]
+		}
+		rank = next
+	}
+	return rank
+}
+
+// modulePath reads the module path from the go.mod at the repository root
+func modulePath(repoRoot string) string {
+	f, err := os.Open(filepath.Join(repoRoot, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// goImports returns the import paths of a Go file and whether it belongs to
+// a main package
+func goImports(content string) ([]string, bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.ImportsOnly)
+	if err != nil {
+		return nil, false
+	}
+	var imports []string
+	for _, spec := range file.Imports {
+		if imp, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, imp)
+		}
+	}
+	return imports, file.Name.Name == "main"
+}
+
+var pythonImport = regexp.MustCompile(`(?m)^\s*(?:from\s+(\.*[\w.]*)\s+import\s+([\w., ]+)|import\s+([\w., ]+))`)
+
+// pythonImports returns the modules imported by a Python file. Relative
+// imports keep their leading dots; "from . import x" yields ".x".
+func pythonImports(content string) []string {
+	var imports []string
+	for _, m := range pythonImport.FindAllStringSubmatch(content, -1) {
+		if m[1] != "" {
+			imports = append(imports, m[1])
+			if strings.Trim(m[1], ".") == "" {
+				for _, name := range strings.Split(m[2], ",") {
+					if fields := strings.Fields(name); len(fields) > 0 {
+						imports = append(imports, m[1]+fields[0])
+					}
+				}
+			}
+			continue
+		}
+		for _, name := range strings.Split(m[3], ",") {
+			if fields := strings.Fields(name); len(fields) > 0 {
+				imports = append(imports, fields[0])
+			}
+		}
+	}
+	return imports
+}
+
+// resolvePython finds the file of a module imported from the file at rel,
+// looking relative to the importing file for relative imports and relative
+// to the repository root and a src directory otherwise
+func resolvePython(module, rel string, byPath map[string]int) (int, bool) {
+	var bases []string
+	if dots := len(module) - len(strings.TrimLeft(module, ".")); dots > 0 {
+		dir := path.Dir(rel)
+		for k := 1; k < dots; k++ {
+			dir = path.Dir(dir)
+		}
+		bases = []string{dir}
+		module = module[dots:]
+	} else {
+		bases = []string{".", "src"}
+	}
+
+	modPath := strings.ReplaceAll(module, ".", "/")
+	for _, base := range bases {
+		p := path.Join(base, modPath)
+		for _, candidate := range []string{p + ".py", path.Join(p, "__init__.py")} {
+			if i, ok := byPath[candidate]; ok {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+var jsImport = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*)['"]([^'"]+)['"]`)
+
+// jsImports returns the relative module specifiers imported by a JavaScript
+// or TypeScript file
+func jsImports(content string) []string {
+	var imports []string
+	for _, m := range jsImport.FindAllStringSubmatch(content, -1) {
+		if strings.HasPrefix(m[1], "./") || strings.HasPrefix(m[1], "../") {
+			imports = append(imports, m[1])
+		}
+	}
+	return imports
+}
+
+var jsExtensions = []string{"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", "/index.ts", "/index.tsx", "/index.js", "/index.jsx"}
+
+// resolveJS finds the file of a relative specifier imported from rel,
+// trying the extensions and index files bundlers resolve
+func resolveJS(specifier, rel string, byPath map[string]int) (int, bool) {
+	p := path.Join(path.Dir(rel), specifier)
+	// TypeScript sources are imported with the extension of the compiled file
+	trimmed := strings.TrimSuffix(p, path.Ext(p))
+	for _, base := range []string{p, trimmed} {
+		for _, ext := range jsExtensions {
+			// Cleaning turns "./index.ts" for the root directory into "index.ts"
+			if i, ok := byPath[path.Clean(base+ext)]; ok {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
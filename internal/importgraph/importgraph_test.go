@@ -0,0 +1,139 @@
+package importgraph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+func TestRank(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.23\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []processor.FileInfo{
+		{RelPath: "cmd/app/main.go", Language: "go", Content: "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/core\"\n)\n\nfunc main() { fmt.Println(core.Run()) }\n"},
+		{RelPath: "core/core.go", Language: "go", Content: "package core\n\nimport \"example.com/app/util\"\n\nfunc Run() string { return util.Trim(\" x \") }\n"},
+		{RelPath: "util/util.go", Language: "go", Content: "package util\n\nimport \"strings\"\n\nfunc Trim(s string) string { return strings.TrimSpace(s) }\n"},
+		{RelPath: "tools/gen.go", Language: "go", Content: "package tools\n\nimport \"example.com/app/core\"\n\nvar _ = core.Run\n"},
+		{RelPath: "scripts/lint.go", Language: "go", Content: "package scripts\n"},
+	}
+	const mainFile, coreFile, utilFile, toolsFile, lintFile = 0, 1, 2, 3, 4
+
+	g := Build(files, root)
+	wantImports := [][]int{{coreFile}, {utilFile}, nil, {coreFile}, nil}
+	if !reflect.DeepEqual(g.Imports, wantImports) {
+		t.Errorf("Imports = %v, want %v", g.Imports, wantImports)
+	}
+	if !reflect.DeepEqual(g.Entry, []bool{true, false, false, false, false}) {
+		t.Errorf("Entry = %v, want only the main package", g.Entry)
+	}
+
+	rank := g.Rank()
+	for _, leaf := range []int{toolsFile, lintFile} {
+		for _, important := range []int{mainFile, coreFile, utilFile} {
+			if rank[important] <= rank[leaf] {
+				t.Errorf("%s ranks %f, not above leaf %s at %f", files[important].RelPath, rank[important], files[leaf].RelPath, rank[leaf])
+			}
+		}
+	}
+}
+
+func TestResolvePython(t *testing.T) {
+	byPath := map[string]int{
+		"app.py":              0,
+		"pkg/__init__.py":     1,
+		"pkg/mod.py":          2,
+		"pkg/sub/helpers.py":  3,
+		"src/lib/__init__.py": 4,
+		"src/lib/io.py":       5,
+	}
+	cases := []struct {
+		module string
+		rel    string
+		want   string
+	}{
+		{"pkg.mod", "app.py", "pkg/mod.py"},
+		{"pkg", "app.py", "pkg/__init__.py"},
+		{"lib.io", "app.py", "src/lib/io.py"},
+		{"lib", "pkg/mod.py", "src/lib/__init__.py"},
+		{".mod", "pkg/__init__.py", "pkg/mod.py"},
+		{".", "pkg/mod.py", "pkg/__init__.py"},
+		{"..mod", "pkg/sub/helpers.py", "pkg/mod.py"},
+		{".helpers", "pkg/sub/x.py", "pkg/sub/helpers.py"},
+		{"os.path", "app.py", ""},
+		{".missing", "pkg/mod.py", ""},
+	}
+
+	for _, tc := range cases {
+		i, ok := resolvePython(tc.module, tc.rel, byPath)
+		got := ""
+		if ok {
+			got = pathOf(byPath, i)
+		}
+		if got != tc.want {
+			t.Errorf("resolvePython(%q, %q) = %q, want %q", tc.module, tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestResolveJS(t *testing.T) {
+	byPath := map[string]int{
+		"index.ts":             0,
+		"src/util.ts":          1,
+		"src/lib/index.js":     2,
+		"src/view.tsx":         3,
+		"src/components/a.jsx": 4,
+		"src/data/config.json": 5,
+	}
+	cases := []struct {
+		specifier string
+		rel       string
+		want      string
+	}{
+		{"./src/util", "index.ts", "src/util.ts"},
+		{"./util.js", "src/view.tsx", "src/util.ts"},
+		{"./lib", "src/util.ts", "src/lib/index.js"},
+		{"../lib/index", "src/components/a.jsx", "src/lib/index.js"},
+		{"../view", "src/components/a.jsx", "src/view.tsx"},
+		{"./data/config.json", "src/util.ts", "src/data/config.json"},
+		{"../..", "src/components/a.jsx", "index.ts"},
+		{"./missing", "src/util.ts", ""},
+	}
+
+	for _, tc := range cases {
+		i, ok := resolveJS(tc.specifier, tc.rel, byPath)
+		got := ""
+		if ok {
+			got = pathOf(byPath, i)
+		}
+		if got != tc.want {
+			t.Errorf("resolveJS(%q, %q) = %q, want %q", tc.specifier, tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	python := "import os, pkg.mod as m\nfrom . import a, b\nfrom ..up import c\n"
+	if got, want := pythonImports(python), []string{"os", "pkg.mod", ".", ".a", ".b", "..up"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pythonImports() = %q, want %q", got, want)
+	}
+
+	js := "import x from './x';\nimport React from 'react';\nconst y = require(\"../y\");\nconst z = await import('./z');\nexport * from './w';\n"
+	if got, want := jsImports(js), []string{"./x", "../y", "./z", "./w"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("jsImports() = %q, want %q", got, want)
+	}
+}
+
+func pathOf(byPath map[string]int, i int) string {
+	for p, j := range byPath {
+		if j == i {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// anthropicVersion is the Messages API version requests are made against
+const anthropicVersion = "2023-06-01"
+
+// defaultAnthropicMaxTokens is sent when the request sets no limit, which
+// the Messages API requires
+const defaultAnthropicMaxTokens = 4096
+
+// anthropic talks to the Messages API
+type anthropic struct {
+	key string
+	url string
+}
+
+func newAnthropic() (Provider, error) {
+	key, err := apiKey("ANTHROPIC_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	return &anthropic{key: key, url: baseURL("ANTHROPIC_BASE_URL", "https://api.anthropic.com")}, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+	Stream    bool               `json:"stream"`
+}
+
+type anthropicEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *anthropic) Stream(ctx context.Context, req Request, onText func(string) error) error {
+	body := anthropicRequest{Model: req.Model, System: req.System, MaxTokens: req.MaxTokens, Stream: true}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = defaultAnthropicMaxTokens
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.key)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "anthropic"); err != nil {
+		return err
+	}
+
+	return readEvents(resp.Body, func(data string) (bool, error) {
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return false, fmt.Errorf("failed to decode anthropic response: %w", err)
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				return false, onText(event.Delta.Text)
+			}
+		case "message_stop":
+			return true, nil
+		case "error":
+			return false, fmt.Errorf("anthropic stream failed: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return false, nil
+	})
+}
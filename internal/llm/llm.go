@@ -0,0 +1,124 @@
+// Package llm sends prompts to hosted or local language models and streams
+// back their answers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Message is a single turn of a conversation
+type Message struct {
+	Role    string // user or assistant
+	Content string
+}
+
+// Request is a conversation to send to a model
+type Request struct {
+	Model     string
+	System    string
+	Messages  []Message
+	MaxTokens int
+	// Tokens in the system prompt and messages as counted by sink, for
+	// providers that size the context window to the prompt
+	PromptTokens int
+}
+
+// Provider streams a model's answer to a request, calling onText with each
+// piece of text as it arrives
+type Provider interface {
+	Stream(ctx context.Context, req Request, onText func(string) error) error
+}
+
+// Embedder turns texts into embedding vectors, one per input in order
+type Embedder interface {
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// ProviderFactory creates a provider from the environment
+type ProviderFactory func() (Provider, error)
+
+var providers = map[string]ProviderFactory{
+	"openai":    newOpenAI,
+	"anthropic": newAnthropic,
+	"ollama":    newOllama,
+}
+
+// DefaultModels are used for providers when no model of theirs is configured
+var DefaultModels = map[string]string{
+	"openai":    "gpt-4o",
+	"anthropic": "claude-3-5-sonnet-latest",
+	"ollama":    "llama3",
+}
+
+// DefaultEmbeddingModels are used for providers when no embedding model is
+// given
+var DefaultEmbeddingModels = map[string]string{
+	"openai": "text-embedding-3-small",
+	"ollama": "nomic-embed-text",
+}
+
+// New returns the provider registered under name
+func New(name string) (Provider, error) {
+	factory, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s (must be one of %s)", name, strings.Join(Providers(), ", "))
+	}
+	return factory()
+}
+
+// NewEmbedder returns the provider registered under name if it can embed
+// texts
+func NewEmbedder(name string) (Embedder, error) {
+	provider, err := New(name)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %s has no embeddings API", name)
+	}
+	return embedder, nil
+}
+
+// Providers returns the names of all registered providers in sorted order
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apiKey reads a required API key from the environment
+func apiKey(variable string) (string, error) {
+	key := os.Getenv(variable)
+	if key == "" {
+		return "", fmt.Errorf("%s is not set", variable)
+	}
+	return key, nil
+}
+
+// baseURL returns the URL in variable if set, or fallback
+func baseURL(variable, fallback string) string {
+	if url := os.Getenv(variable); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return fallback
+}
+
+// checkResponse turns an unsuccessful response into an error that includes
+// the body, which carries the provider's explanation
+func checkResponse(resp *http.Response, provider string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s request failed: %s: %s", provider, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/logging"
+)
+
+const (
+	// defaultOllamaContext is Ollama's own default context window, which
+	// requests never go below
+	defaultOllamaContext = 2048
+	// ollamaAnswerReserve is left for the answer when the request sets no
+	// limit
+	ollamaAnswerReserve = 2048
+)
+
+// ollama talks to a local Ollama server at OLLAMA_HOST, so the prompt never
+// leaves the machine
+type ollama struct {
+	url string
+}
+
+func newOllama() (Provider, error) {
+	host := os.Getenv("OLLAMA_HOST")
+	if host == "" {
+		return &ollama{url: "http://localhost:11434"}, nil
+	}
+	// Ollama accepts a bare host:port
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return &ollama{url: strings.TrimRight(host, "/")}, nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	NumCtx     int `json:"num_ctx"`
+	NumPredict int `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Options  ollamaOptions   `json:"options"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaChunk struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done bool `json:"done"`
+	// Tokens in the prompt as counted by the model's own tokenizer, set
+	// on the last chunk
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	Error           string `json:"error"`
+}
+
+func (p *ollama) Stream(ctx context.Context, req Request, onText func(string) error) error {
+	body := ollamaRequest{
+		Model:   req.Model,
+		Options: ollamaOptions{NumCtx: ollamaContext(req), NumPredict: req.MaxTokens},
+		Stream:  true,
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call ollama (is it running?): %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "ollama"); err != nil {
+		return err
+	}
+
+	// The answer streams as one JSON object per line
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to decode ollama response: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama request failed: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			if err := onText(chunk.Message.Content); err != nil {
+				return err
+			}
+		}
+		if chunk.Done {
+			logPromptTokens(req, body.Options.NumCtx, chunk.PromptEvalCount)
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ollama response: %w", err)
+	}
+	return nil
+}
+
+// ollamaContext sizes the context window to fit the prompt and the answer.
+// Ollama silently drops the start of prompts longer than its window, which
+// is only 2048 tokens by default.
+func ollamaContext(req Request) int {
+	answer := req.MaxTokens
+	if answer <= 0 {
+		answer = ollamaAnswerReserve
+	}
+	// sink's count comes from a tiktoken encoding, which can differ from
+	// the model's tokenizer by a few percent
+	return max(req.PromptTokens+req.PromptTokens/10+answer, defaultOllamaContext)
+}
+
+// logPromptTokens reports the model's own count of the prompt, and warns if
+// the prompt filled the context window and was likely cut short
+func logPromptTokens(req Request, numCtx, counted int) {
+	if counted == 0 {
+		return
+	}
+	logging.Debug("prompt tokens", "estimated", req.PromptTokens, "counted", counted, "context", numCtx)
+	if counted >= numCtx {
+		logging.Warn("prompt filled the model's context window and was probably truncated",
+			"tokens", counted, "context", numCtx)
+	}
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+func (p *ollama) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	data, err := json.Marshal(ollamaEmbedRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/api/embed", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call ollama (is it running?): %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "ollama"); err != nil {
+		return nil, err
+	}
+
+	var result ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	if len(result.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(result.Embeddings), len(inputs))
+	}
+	return result.Embeddings, nil
+}
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxEventSize bounds a single line of a streamed response. Lines carry JSON
+// objects that can exceed bufio's default limit.
+const maxEventSize = 1024 * 1024
+
+// readEvents calls onData with the data of each server-sent event in r until
+// r is exhausted or onData returns an error or stop
+func readEvents(r io.Reader, onData func(data string) (stop bool, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line ends the event
+			if len(data) > 0 {
+				stop, err := onData(strings.Join(data, "\n"))
+				if err != nil || stop {
+					return err
+				}
+				data = data[:0]
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		_, err := onData(strings.Join(data, "\n"))
+		return err
+	}
+	return nil
+}
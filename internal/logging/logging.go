@@ -0,0 +1,95 @@
+// Package logging provides the logger shared by sink's commands and
+// services. Logs always go to stderr, so they never mix with documents
+// written to stdout.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// level is shared by every logger, so SetLevel applies to loggers created
+// before it is called
+var level = new(slog.LevelVar)
+
+var logger = newLogger(os.Stderr, "text")
+
+func newLogger(w io.Writer, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// Logger returns the shared logger
+func Logger() *slog.Logger {
+	return logger
+}
+
+// For returns the shared logger with every record tagged with component
+func For(component string) *slog.Logger {
+	return logger.With("component", component)
+}
+
+// SetLevel sets the minimum level of records that are logged
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// SetFormat switches the log format between text (key=value lines) and json
+// (one object per line). Loggers returned by For earlier keep their format.
+func SetFormat(format string) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+	logger = newLogger(os.Stderr, format)
+	return nil
+}
+
+// ValidateFormat checks that format is a supported log format
+func ValidateFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		return nil
+	}
+	return fmt.Errorf("invalid log format %q (must be text or json)", format)
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q (must be debug, info, warn or error)", name)
+}
+
+// Debug logs at debug level with the shared logger
+func Debug(msg string, args ...any) {
+	logger.Debug(msg, args...)
+}
+
+// Info logs at info level with the shared logger
+func Info(msg string, args ...any) {
+	logger.Info(msg, args...)
+}
+
+// Warn logs at warn level with the shared logger
+func Warn(msg string, args ...any) {
+	logger.Warn(msg, args...)
+}
+
+// Error logs at error level with the shared logger
+func Error(msg string, args ...any) {
+	logger.Error(msg, args...)
+}
@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// blobPath returns the location of a stored file snapshot
+func blobPath(repoRoot, hash string) string {
+	return filepath.Join(repoRoot, Dir, "blobs", hash[:2], hash)
+}
+
+// SaveBlobs stores a content-addressed snapshot of each file so later runs
+// can diff against it
+func SaveBlobs(repoRoot string, files []processor.FileInfo) error {
+	for _, file := range files {
+		p := blobPath(repoRoot, Hash(file.Content))
+		if _, err := os.Stat(p); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return fmt.Errorf("failed to create blob directory: %w", err)
+		}
+		if err := os.WriteFile(p, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write blob for %s: %w", file.RelPath, err)
+		}
+	}
+	return nil
+}
+
+// PruneBlobs removes stored snapshots of file versions m does not include
+func (m *Manifest) PruneBlobs(repoRoot string) error {
+	keep := make(map[string]bool)
+	for _, entry := range m.Files {
+		keep[entry.Hash] = true
+	}
+
+	dir := filepath.Join(repoRoot, Dir, "blobs")
+	shards, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read blob directory: %w", err)
+	}
+	for _, shard := range shards {
+		shardDir := filepath.Join(dir, shard.Name())
+		blobs, err := os.ReadDir(shardDir)
+		if err != nil {
+			continue
+		}
+		kept := 0
+		for _, blob := range blobs {
+			if keep[blob.Name()] {
+				kept++
+				continue
+			}
+			if err := os.Remove(filepath.Join(shardDir, blob.Name())); err != nil {
+				return fmt.Errorf("failed to remove blob: %w", err)
+			}
+		}
+		if kept == 0 {
+			os.Remove(shardDir)
+		}
+	}
+	return nil
+}
+
+// LoadBlob reads a stored file snapshot by content hash
+func LoadBlob(repoRoot, hash string) (string, error) {
+	data, err := os.ReadFile(blobPath(repoRoot, hash))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
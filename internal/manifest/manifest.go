@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// Dir is the directory, relative to the repository root, where sink keeps
+// its state between runs
+const Dir = ".sink"
+
+// Entry records a single file included in a generation
+type Entry struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// Manifest records the files included in a generation
+type Manifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []Entry   `json:"files"`
+}
+
+// Changes lists the differences between two manifests
+type Changes struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty reports whether there are no changes
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// path returns the manifest file location for a repository
+func path(repoRoot string) string {
+	return filepath.Join(repoRoot, Dir, "manifest.json")
+}
+
+// Hash returns the content hash used to identify file versions
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Build creates a manifest for the given files
+func Build(files []processor.FileInfo, now time.Time) *Manifest {
+	m := &Manifest{GeneratedAt: now}
+	for _, file := range files {
+		m.Files = append(m.Files, Entry{
+			Path: filepath.ToSlash(file.RelPath),
+			Hash: Hash(file.Content),
+			Size: file.Size,
+		})
+	}
+	return m
+}
+
+// Load reads the manifest of the previous generation. It returns nil without
+// an error if no manifest exists yet.
+func Load(repoRoot string) (*Manifest, error) {
+	data, err := os.ReadFile(path(repoRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to the repository's sink directory
+func (m *Manifest) Save(repoRoot string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(repoRoot, Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", Dir, err)
+	}
+	if err := os.WriteFile(path(repoRoot), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Compare returns the files added, removed and modified in next relative to m
+func (m *Manifest) Compare(next *Manifest) Changes {
+	previous := make(map[string]string)
+	for _, entry := range m.Files {
+		previous[entry.Path] = entry.Hash
+	}
+
+	var changes Changes
+	current := make(map[string]bool)
+	for _, entry := range next.Files {
+		current[entry.Path] = true
+		hash, ok := previous[entry.Path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, entry.Path)
+		case hash != entry.Hash:
+			changes.Modified = append(changes.Modified, entry.Path)
+		}
+	}
+	for _, entry := range m.Files {
+		if !current[entry.Path] {
+			changes.Removed = append(changes.Removed, entry.Path)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes
+}
+
+// Lookup returns the entry for path, if present
+func (m *Manifest) Lookup(path string) (Entry, bool) {
+	for _, entry := range m.Files {
+		if entry.Path == path {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the regeneration
+// duration histogram
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry collects regeneration metrics and exposes them in the Prometheus
+// text exposition format
+type Registry struct {
+	mu            sync.Mutex
+	successes     uint64
+	failures      uint64
+	bucketCounts  []uint64
+	durationSum   float64
+	durationCount uint64
+	lastSuccess   time.Time
+	files         int
+	tokens        int
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{bucketCounts: make([]uint64, len(durationBuckets))}
+}
+
+// Generation describes the outcome of a single regeneration
+type Generation struct {
+	Duration time.Duration
+	Files    int // Files included in the largest output target
+	Tokens   int // Tokens across all output targets, or -1 if not counted
+	Err      error
+}
+
+// Observe records a regeneration
+func (r *Registry) Observe(g Generation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seconds := g.Duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			r.bucketCounts[i]++
+		}
+	}
+	r.durationSum += seconds
+	r.durationCount++
+
+	if g.Err != nil {
+		r.failures++
+		return
+	}
+	r.successes++
+	r.lastSuccess = time.Now()
+	r.files = g.Files
+	if g.Tokens >= 0 {
+		r.tokens = g.Tokens
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	fmt.Fprintln(cw, "# HELP sink_regenerations_total Number of regenerations by result.")
+	fmt.Fprintln(cw, "# TYPE sink_regenerations_total counter")
+	fmt.Fprintf(cw, "sink_regenerations_total{result=\"success\"} %d\n", r.successes)
+	fmt.Fprintf(cw, "sink_regenerations_total{result=\"failure\"} %d\n", r.failures)
+
+	fmt.Fprintln(cw, "# HELP sink_regeneration_duration_seconds Time spent regenerating output.")
+	fmt.Fprintln(cw, "# TYPE sink_regeneration_duration_seconds histogram")
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(cw, "sink_regeneration_duration_seconds_bucket{le=\"%g\"} %d\n", bound, r.bucketCounts[i])
+	}
+	fmt.Fprintf(cw, "sink_regeneration_duration_seconds_bucket{le=\"+Inf\"} %d\n", r.durationCount)
+	fmt.Fprintf(cw, "sink_regeneration_duration_seconds_sum %g\n", r.durationSum)
+	fmt.Fprintf(cw, "sink_regeneration_duration_seconds_count %d\n", r.durationCount)
+
+	var lastSuccess float64
+	if !r.lastSuccess.IsZero() {
+		lastSuccess = float64(r.lastSuccess.UnixNano()) / 1e9
+	}
+	fmt.Fprintln(cw, "# HELP sink_last_success_timestamp_seconds Unix time of the last successful regeneration.")
+	fmt.Fprintln(cw, "# TYPE sink_last_success_timestamp_seconds gauge")
+	fmt.Fprintf(cw, "sink_last_success_timestamp_seconds %f\n", lastSuccess)
+
+	fmt.Fprintln(cw, "# HELP sink_files Files included in the last successful regeneration.")
+	fmt.Fprintln(cw, "# TYPE sink_files gauge")
+	fmt.Fprintf(cw, "sink_files %d\n", r.files)
+
+	fmt.Fprintln(cw, "# HELP sink_tokens Tokens in the output of the last successful regeneration.")
+	fmt.Fprintln(cw, "# TYPE sink_tokens gauge")
+	fmt.Fprintf(cw, "sink_tokens %d\n", r.tokens)
+
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics endpoint
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// ListenAndServe serves /metrics on addr until ctx is cancelled
+func (r *Registry) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	return nil
+}
+
+// countingWriter tracks bytes written and the first write error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,203 @@
+// Package plugins runs WebAssembly modules from the user's plugin directory
+// that transform file contents and render documents in formats of their own
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Plugin is a compiled WASI command module. Every call runs the module's
+// _start in a fresh instance, with the operation in its arguments, input on
+// stdin and the result read from stdout. Plugins get no filesystem or
+// network access.
+//
+// Called with "describe", a plugin prints a JSON object such as
+// {"transform":true,"formats":["org"]} naming what it implements.
+// With "transform", it receives {"path":...,"content":...} and prints the
+// file's new content; with "format" and a format name, it receives a JSON
+// array of files and prints the document.
+type Plugin struct {
+	// File name without the .wasm extension
+	Name string
+	Path string
+	// Whether the plugin transforms file contents, and the formats it renders
+	Transform bool
+	Formats   []string
+	// Hash of the module, so changing it starts a fresh cache
+	Hash string
+
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// File is a processed file passed to a plugin's formatter
+type File struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+	Tokens   int    `json:"tokens"`
+}
+
+// description is what a plugin prints when called with "describe"
+type description struct {
+	Transform bool     `json:"transform"`
+	Formats   []string `json:"formats"`
+}
+
+// fileInput is what a plugin's transform receives on stdin
+type fileInput struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Load compiles every .wasm file in dir, in name order, and asks each what
+// it implements. Plugins that fail to load are left out, with an error for
+// each. A missing dir has no plugins.
+func Load(dir string) ([]*Plugin, []error) {
+	if dir == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	sort.Strings(paths)
+
+	ctx := context.Background()
+	runtimeConfig := wazero.NewRuntimeConfig()
+	// Compiling is slow next to running, so keep compiled modules across runs
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		if compiled, err := wazero.NewCompilationCacheWithDir(filepath.Join(cacheDir, "sink", "wasm")); err == nil {
+			runtimeConfig = runtimeConfig.WithCompilationCache(compiled)
+		}
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	var plugins []*Plugin
+	var errs []error
+	for _, path := range paths {
+		p, err := load(ctx, runtime, path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	if len(plugins) == 0 {
+		runtime.Close(ctx)
+	}
+	return plugins, errs
+}
+
+// load compiles the module at path and runs its describe operation
+func load(ctx context.Context, runtime wazero.Runtime, path string) (*Plugin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin: %w", err)
+	}
+	module, err := runtime.CompileModule(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile plugin %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	p := &Plugin{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".wasm"),
+		Path:    path,
+		Hash:    hex.EncodeToString(sum[:])[:16],
+		runtime: runtime,
+		module:  module,
+	}
+
+	out, err := p.run(nil, nil, "describe")
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to describe itself: %w", p.Name, err)
+	}
+	var desc description
+	if err := json.Unmarshal([]byte(out), &desc); err != nil {
+		return nil, fmt.Errorf("plugin %s printed an invalid description: %w", p.Name, err)
+	}
+	p.Transform = desc.Transform
+	p.Formats = desc.Formats
+	return p, nil
+}
+
+// File runs the plugin's transform on a file's content, given its path
+// relative to the repository root, and returns the content to use instead
+func (p *Plugin) File(relPath, content string) (string, error) {
+	input, err := json.Marshal(fileInput{Path: relPath, Content: content})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plugin input: %w", err)
+	}
+	out, err := p.run(input, map[string]string{"SINK_FILE": relPath}, "transform")
+	if err != nil {
+		return "", fmt.Errorf("plugin %s failed for %s: %w", p.Name, relPath, err)
+	}
+	return out, nil
+}
+
+// Document runs the plugin's formatter for format on files and returns the
+// rendered document
+func (p *Plugin) Document(format string, files []File) (string, error) {
+	if files == nil {
+		files = []File{}
+	}
+	input, err := json.Marshal(files)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plugin input: %w", err)
+	}
+	out, err := p.run(input, map[string]string{"SINK_FORMAT": format}, "format", format)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s failed to format %s: %w", p.Name, format, err)
+	}
+	return out, nil
+}
+
+// run instantiates the module with args after its name, stdin and extra
+// environment variables, and returns its stdout. Its stderr is included in
+// the error if it exits non-zero.
+func (p *Plugin) run(stdin []byte, env map[string]string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	moduleConfig := wazero.NewModuleConfig().
+		// Unnamed, so several instances can run at once
+		WithName("").
+		WithArgs(append([]string{p.Name}, args...)...).
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	for key, value := range env {
+		moduleConfig = moduleConfig.WithEnv(key, value)
+	}
+
+	ctx := context.Background()
+	mod, err := p.runtime.InstantiateModule(ctx, p.module, moduleConfig)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if err != nil {
+		if exit, ok := err.(*sys.ExitError); ok {
+			err = fmt.Errorf("exit code %d", exit.ExitCode())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,72 @@
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildPlugin compiles testdata/shout for WASI into a plugin directory
+func buildPlugin(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a wasm plugin is slow")
+	}
+	dir := t.TempDir()
+	cmd := exec.Command("go", "build", "-o", filepath.Join(dir, "shout.wasm"), "./testdata/shout")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build wasip1 plugin: %v\n%s", err, out)
+	}
+	return dir
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	loaded, errs := Load(filepath.Join(t.TempDir(), "plugins"))
+	if errs != nil || loaded != nil {
+		t.Fatalf("Load of a missing dir = %v, %v; want nil, nil", loaded, errs)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := buildPlugin(t)
+	if err := os.WriteFile(filepath.Join(dir, "broken.wasm"), []byte("not wasm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, errs := Load(dir)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.wasm") {
+		t.Errorf("Load errors = %v, want one for broken.wasm", errs)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("loaded %d plugins, want 1", len(loaded))
+	}
+	p := loaded[0]
+	if p.Name != "shout" || !p.Transform || len(p.Formats) != 1 || p.Formats[0] != "shout" {
+		t.Fatalf("plugin = %+v, want shout transforming and formatting shout", p)
+	}
+
+	got, err := p.File("main.go", "package main\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "PACKAGE MAIN\n" {
+		t.Errorf("File = %q, want %q", got, "PACKAGE MAIN\n")
+	}
+
+	_, err = p.File("fail.txt", "x")
+	if err == nil || !strings.Contains(err.Error(), "exit code 3") || !strings.Contains(err.Error(), "refusing fail.txt") {
+		t.Errorf("File(fail.txt) error = %v, want exit code 3 with stderr", err)
+	}
+
+	doc, err := p.Document("shout", []File{{Path: "main.go", Language: "go"}, {Path: "README.md", Language: "markdown"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "shout shout\nmain.go (go)\nREADME.md (markdown)\n"
+	if doc != want {
+		t.Errorf("Document = %q, want %q", doc, want)
+	}
+}
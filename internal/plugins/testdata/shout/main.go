@@ -0,0 +1,46 @@
+// Command shout is a test plugin that upper-cases files and renders a
+// "shout" format listing each file's path and language
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	switch os.Args[1] {
+	case "describe":
+		fmt.Print(`{"transform":true,"formats":["shout"]}`)
+	case "transform":
+		var file struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(os.Stdin).Decode(&file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if file.Path == "fail.txt" {
+			fmt.Fprintln(os.Stderr, "refusing fail.txt")
+			os.Exit(3)
+		}
+		fmt.Print(strings.ToUpper(file.Content))
+	case "format":
+		var files []struct {
+			Path     string `json:"path"`
+			Language string `json:"language"`
+		}
+		if err := json.NewDecoder(os.Stdin).Decode(&files); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s %s\n", os.Args[2], os.Getenv("SINK_FORMAT"))
+		for _, f := range files {
+			fmt.Printf("%s (%s)\n", f.Path, f.Language)
+		}
+	default:
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,125 @@
+package chunks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// DefaultMaxTokens is the chunk size used when none is configured
+const DefaultMaxTokens = 512
+
+type Config struct {
+	// Most tokens per chunk (0 = DefaultMaxTokens). A single line longer
+	// than this forms a chunk of its own.
+	MaxTokens int
+	// Tokens of lines at the end of a chunk that are repeated at the start
+	// of the next one
+	Overlap       int
+	TokenEncoding string
+}
+
+// record is the JSON representation of a single chunk
+type record struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Language  string `json:"language"`
+	Chunk     int    `json:"chunk"` // Position of the chunk in its file, from 0
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Tokens    int    `json:"tokens"`
+	Content   string `json:"content"`
+}
+
+// Generator renders files as newline-delimited JSON, one record per chunk
+// of lines, for loading into a vector store
+type Generator struct {
+	config Config
+}
+
+func NewGenerator(config Config) *Generator {
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = DefaultMaxTokens
+	}
+	return &Generator{config: config}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	counter, err := tokens.NewCounter(g.config.TokenEncoding)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	var content strings.Builder
+	for _, file := range files {
+		// A duplicate's stub has nothing worth retrieving
+		if file.DuplicateOf != "" {
+			continue
+		}
+		lines := strings.SplitAfter(strings.TrimRight(file.Content, "\n"), "\n")
+		counts := make([]int, len(lines))
+		for i, line := range lines {
+			if counts[i], err = counter.Count(line); err != nil {
+				return "", fmt.Errorf("failed to count tokens: %w", err)
+			}
+		}
+
+		for i, span := range g.spans(counts) {
+			text := strings.Join(lines[span[0]:span[1]], "")
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			count, err := counter.Count(text)
+			if err != nil {
+				return "", fmt.Errorf("failed to count tokens: %w", err)
+			}
+			line, err := json.Marshal(record{
+				ID:        fmt.Sprintf("%s:%d-%d", file.RelPath, span[0]+1, span[1]),
+				Path:      file.RelPath,
+				Language:  file.Language,
+				Chunk:     i,
+				StartLine: span[0] + 1,
+				EndLine:   span[1],
+				Tokens:    count,
+				Content:   text,
+			})
+			if err != nil {
+				return "", err
+			}
+			content.Write(line)
+			content.WriteString("\n")
+		}
+	}
+
+	return content.String(), nil
+}
+
+// spans groups lines with the given token counts into [start, end) ranges
+// of at most MaxTokens tokens, each starting with the last Overlap tokens
+// of lines of the one before
+func (g *Generator) spans(counts []int) [][2]int {
+	var spans [][2]int
+	for start := 0; start < len(counts); {
+		end, used := start, 0
+		for end < len(counts) && (end == start || used+counts[end] <= g.config.MaxTokens) {
+			used += counts[end]
+			end++
+		}
+		spans = append(spans, [2]int{start, end})
+		if end == len(counts) {
+			break
+		}
+
+		// Back up over the overlap, always moving past the previous start
+		next, overlap := end, 0
+		for next-1 > start && overlap+counts[next-1] <= g.config.Overlap {
+			next--
+			overlap += counts[next]
+		}
+		start = next
+	}
+	return spans
+}
@@ -0,0 +1,56 @@
+package chunks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	cases := []struct {
+		name      string
+		maxTokens int
+		overlap   int
+		counts    []int
+		want      [][2]int
+	}{
+		{
+			name:      "fits in one chunk",
+			maxTokens: 10,
+			counts:    []int{3, 3, 3},
+			want:      [][2]int{{0, 3}},
+		},
+		{
+			name:      "split without overlap",
+			maxTokens: 6,
+			counts:    []int{3, 3, 3, 3},
+			want:      [][2]int{{0, 2}, {2, 4}},
+		},
+		{
+			name:      "overlap repeats trailing lines",
+			maxTokens: 6,
+			overlap:   3,
+			counts:    []int{3, 3, 3, 3},
+			want:      [][2]int{{0, 2}, {1, 3}, {2, 4}},
+		},
+		{
+			name:      "long line gets a chunk of its own",
+			maxTokens: 5,
+			counts:    []int{2, 9, 2},
+			want:      [][2]int{{0, 1}, {1, 2}, {2, 3}},
+		},
+		{
+			name:      "overlap larger than chunks still advances",
+			maxTokens: 2,
+			overlap:   10,
+			counts:    []int{1, 1, 1, 1},
+			want:      [][2]int{{0, 2}, {1, 3}, {2, 4}},
+		},
+	}
+
+	for _, tc := range cases {
+		g := NewGenerator(Config{MaxTokens: tc.maxTokens, Overlap: tc.overlap})
+		if got := g.spans(tc.counts); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: spans(%v) = %v, want %v", tc.name, tc.counts, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package comments
+
+import (
+	"strings"
+)
+
+// quote describes a string literal delimiter
+type quote struct {
+	open, close string
+	// Backslash escapes the next character
+	escapes bool
+}
+
+// syntax describes the comment and literal tokens of a language
+type syntax struct {
+	lineComments  []string    // Line comment markers
+	blockComments [][2]string // Block comment delimiters
+	quotes        []quote     // String literals, longest delimiters first
+	// Line comment markers only count at the start of a word, as in shell
+	// and YAML where "a#b" is not a comment
+	wordStart bool
+	// Quotes only open a literal at the start of a value, as in YAML where
+	// "it's" is a plain scalar
+	quoteWordStart bool
+	// ' starts a character literal only when it is closed right after one
+	// (possibly escaped) character, so Rust lifetimes are not strings
+	charLiterals bool
+	// / may start a regular expression literal, as in JavaScript
+	regexLiterals bool
+	// url(...) holds an unquoted URL, as in CSS, so // in it is not a comment
+	urlLiterals bool
+	// Triple-quoted strings opening a module, class or function body are
+	// docstrings and are stripped
+	docstrings bool
+}
+
+var (
+	cQuotes = []quote{
+		{`"`, `"`, true},
+		{`'`, `'`, true},
+	}
+	cLike = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	}
+	jsLike = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        append([]quote{{"`", "`", true}}, cQuotes...),
+		regexLiterals: true,
+	}
+	hashLike = syntax{
+		lineComments: []string{"#"},
+		quotes:       cQuotes,
+		wordStart:    true,
+	}
+	// Backslash escapes nothing in shell single quotes, so 'C:\' is closed
+	shellLike = syntax{
+		lineComments: []string{"#"},
+		quotes:       []quote{{`"`, `"`, true}, {`'`, `'`, false}},
+		wordStart:    true,
+	}
+)
+
+// syntaxes maps fence languages to their comment syntax
+var syntaxes = map[string]syntax{
+	"go": {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        append([]quote{{"`", "`", false}}, cQuotes...),
+	},
+	"javascript": jsLike,
+	"jsx":        jsLike,
+	"typescript": jsLike,
+	"tsx":        jsLike,
+	"python": {
+		lineComments: []string{"#"},
+		quotes: []quote{
+			{`"""`, `"""`, true},
+			{`'''`, `'''`, true},
+			{`"`, `"`, true},
+			{`'`, `'`, true},
+		},
+		docstrings: true,
+	},
+	"c":          cLike,
+	"cpp":        cLike,
+	"objectivec": cLike,
+	"java":       cLike,
+	"csharp":     cLike,
+	"scss": {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+		urlLiterals:   true,
+	},
+	"swift": {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        append([]quote{{`"""`, `"""`, true}}, cQuotes...),
+	},
+	"kotlin": {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        append([]quote{{`"""`, `"""`, false}}, cQuotes...),
+	},
+	"rust": {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quote{{`"`, `"`, true}},
+		charLiterals:  true,
+	},
+	"php": {
+		lineComments:  []string{"//", "#"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	},
+	"css": {
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	},
+	"sql": {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quote{{`'`, `'`, false}, {`"`, `"`, false}},
+	},
+	"ruby": {
+		lineComments: []string{"#"},
+		quotes:       append([]quote{{"`", "`", true}}, cQuotes...),
+	},
+	"bash": shellLike,
+	"sh":   shellLike,
+	"zsh":  shellLike,
+	"yaml": {
+		lineComments:   []string{"#"},
+		quotes:         cQuotes,
+		wordStart:      true,
+		quoteWordStart: true,
+	},
+	"toml": hashLike,
+	"html": {
+		blockComments: [][2]string{{"<!--", "-->"}},
+	},
+	"xml": {
+		blockComments: [][2]string{{"<!--", "-->"}},
+	},
+}
+
+// lexer walks content once, copying code and literals to out and skipping
+// comments
+type lexer struct {
+	syntax
+	src string
+	pos int
+	out strings.Builder
+	// Output line numbers a comment was removed from
+	stripped map[int]bool
+	outLine  int
+	// Last non-space byte written to out
+	prev byte
+	// Nesting of brackets in code
+	depth int
+	// Blank out comments and literals instead of copying and dropping them
+	mask bool
+}
+
+func (s syntax) strip(content string) string {
+	l := &lexer{syntax: s, src: content, stripped: make(map[int]bool)}
+	l.run()
+	return l.cleanup()
+}
+
+func (s syntax) mask(content string) string {
+	l := &lexer{syntax: s, src: content, mask: true}
+	l.run()
+	return l.out.String()
+}
+
+func (l *lexer) run() {
+	for l.pos < len(l.src) {
+		if l.comment() || l.literal() {
+			continue
+		}
+		switch l.src[l.pos] {
+		case '(', '[', '{':
+			l.depth++
+		case ')', ']', '}':
+			l.depth = max(l.depth-1, 0)
+		}
+		l.emit(l.src[l.pos : l.pos+1])
+		l.pos++
+	}
+}
+
+// comment skips a comment starting at the current position
+func (l *lexer) comment() bool {
+	rest := l.src[l.pos:]
+	for _, marker := range l.lineComments {
+		if !strings.HasPrefix(rest, marker) {
+			continue
+		}
+		if l.wordStart && l.pos > 0 && !isSpace(l.src[l.pos-1]) {
+			continue
+		}
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		l.skip(end)
+		return true
+	}
+	for _, delims := range l.blockComments {
+		if !strings.HasPrefix(rest, delims[0]) {
+			continue
+		}
+		end := strings.Index(rest[len(delims[0]):], delims[1])
+		if end < 0 {
+			end = len(rest)
+		} else {
+			end += len(delims[0]) + len(delims[1])
+		}
+		l.skip(end)
+		return true
+	}
+	return false
+}
+
+// literal copies a string, character or regular expression literal
+// starting at the current position
+func (l *lexer) literal() bool {
+	rest := l.src[l.pos:]
+	if l.charLiterals && rest[0] == '\'' {
+		if n := charLiteral(rest); n > 0 {
+			l.emitLiteral(rest[:n])
+			l.pos += n
+		} else {
+			l.emit("'")
+			l.pos++
+		}
+		return true
+	}
+	if l.urlLiterals && strings.HasPrefix(rest, "url(") && !l.afterWord() {
+		if n := urlLiteral(rest); n > 0 {
+			l.emit(rest[:n])
+			l.pos += n
+			return true
+		}
+	}
+	if l.regexLiterals && rest[0] == '/' && l.regexAllowed() {
+		if n := regexLiteral(rest); n > 0 {
+			l.emitLiteral(rest[:n])
+			l.pos += n
+			return true
+		}
+	}
+	for _, q := range l.quotes {
+		if !strings.HasPrefix(rest, q.open) {
+			continue
+		}
+		if l.quoteWordStart && !l.atValueStart() {
+			return false
+		}
+		n := len(q.open) + literalEnd(rest[len(q.open):], q)
+		if l.docstrings && len(q.open) == 3 && l.atBodyStart() {
+			l.skip(n)
+		} else {
+			l.emitLiteral(rest[:n])
+			l.pos += n
+		}
+		return true
+	}
+	return false
+}
+
+// literalEnd returns the length of a literal body including its closing
+// delimiter, or of the rest of s if it is unterminated
+func literalEnd(s string, q quote) int {
+	for i := 0; i < len(s); i++ {
+		if q.escapes && s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], q.close) {
+			return i + len(q.close)
+		}
+	}
+	return len(s)
+}
+
+// charLiteral returns the length of a character literal such as 'a' or
+// '\n' at the start of s, or 0 if the quote does not start one
+func charLiteral(s string) int {
+	if len(s) > 2 && s[1] == '\\' {
+		if end := strings.IndexByte(s[2:], '\''); end >= 0 && !strings.Contains(s[2:2+end], "\n") {
+			return end + 3
+		}
+		return 0
+	}
+	for i, r := range s[1:] {
+		if r == '\n' || r == '\'' {
+			return 0
+		}
+		n := 1 + i + len(string(r))
+		if n < len(s) && s[n] == '\'' {
+			return n + 1
+		}
+		return 0
+	}
+	return 0
+}
+
+// regexLiteral returns the length of a regular expression literal at the
+// start of s, or 0 if it is not terminated on the same line
+func regexLiteral(s string) int {
+	if len(s) < 2 || s[1] == '/' || s[1] == '*' {
+		return 0
+	}
+	inClass := false
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0
+		case '/':
+			if !inClass {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// urlLiteral returns the length of an unquoted url(...) at the start of s,
+// or 0 if it is not closed on the same line
+func urlLiteral(s string) int {
+	end := strings.IndexAny(s, ")\n")
+	if end < 0 || s[end] != ')' {
+		return 0
+	}
+	return end + 1
+}
+
+// regexKeywords are the keywords an expression, and so a regular
+// expression, may follow
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "in": true, "of": true,
+	"void": true, "yield": true, "await": true, "delete": true, "throw": true,
+	"new": true,
+}
+
+// regexAllowed reports whether a / at the current position starts a regular
+// expression rather than a division, judging by the preceding token
+func (l *lexer) regexAllowed() bool {
+	if l.prev == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", l.prev) >= 0 {
+		return true
+	}
+	return regexKeywords[l.prevWord()]
+}
+
+// prevWord returns the identifier before the current position, skipping
+// whitespace, or "" if there is none or it is a property name after a dot
+func (l *lexer) prevWord() string {
+	code := strings.TrimRight(l.src[:l.pos], " \t\r\n")
+	start := len(code)
+	for start > 0 && isWordByte(code[start-1]) {
+		start--
+	}
+	if start > 0 && code[start-1] == '.' {
+		return ""
+	}
+	return code[start:]
+}
+
+// afterWord reports whether the current position continues an identifier
+func (l *lexer) afterWord() bool {
+	return l.pos > 0 && isWordByte(l.src[l.pos-1])
+}
+
+// atBodyStart reports whether a string at the current position is the
+// first statement of the file or of a block: alone at the start of a line
+// right after a ':' block opener, outside any brackets
+func (l *lexer) atBodyStart() bool {
+	lineStart := strings.LastIndexByte(l.src[:l.pos], '\n') + 1
+	if strings.TrimSpace(l.src[lineStart:l.pos]) != "" {
+		return false
+	}
+	return l.prev == 0 || (l.prev == ':' && l.depth == 0)
+}
+
+// atValueStart reports whether the current position starts a word, so a
+// quote there opens a literal
+func (l *lexer) atValueStart() bool {
+	return l.pos == 0 || isSpace(l.src[l.pos-1]) || strings.IndexByte("[{,:", l.src[l.pos-1]) >= 0
+}
+
+// emit copies code to the output
+func (l *lexer) emit(s string) {
+	l.out.WriteString(s)
+	l.outLine += strings.Count(s, "\n")
+	if t := strings.TrimRight(s, " \t\r\n"); t != "" {
+		l.prev = t[len(t)-1]
+	}
+}
+
+// emitLiteral copies a literal to the output, keeping only its delimiters
+// when masking
+func (l *lexer) emitLiteral(s string) {
+	if l.mask && len(s) > 2 {
+		s = s[:1] + blank(s[1:len(s)-1]) + s[len(s)-1:]
+	}
+	l.emit(s)
+}
+
+// skip drops n bytes of comment, or blanks them when masking
+func (l *lexer) skip(n int) {
+	if l.mask {
+		l.out.WriteString(blank(l.src[l.pos : l.pos+n]))
+	} else {
+		l.stripped[l.outLine] = true
+	}
+	l.pos += n
+}
+
+// blank replaces every byte of s but newlines with a space
+func blank(s string) string {
+	b := []byte(s)
+	for i := range b {
+		if b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// cleanup removes lines that held nothing but comments and the whitespace
+// left before trailing comments
+func (l *lexer) cleanup() string {
+	lines := strings.Split(l.out.String(), "\n")
+	kept := lines[:0]
+	for i, line := range lines {
+		if l.stripped[i] {
+			line = strings.TrimRight(line, " \t\r")
+			if line == "" {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '$' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
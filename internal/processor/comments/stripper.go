@@ -1,56 +1,35 @@
 package comments
 
 import (
-	"regexp"
 	"strings"
 )
 
+// Supported reports whether StripComments removes comments for language
+func Supported(language string) bool {
+	_, ok := syntaxes[language]
+	return ok
+}
+
+// StripComments removes comments from content using a tokenizer for the
+// language, so comment markers inside string literals, character literals
+// and regular expressions are left alone. Lines that held nothing but a
+// comment are dropped. Content in unsupported languages is returned as is.
 func StripComments(content, language string) string {
-	switch language {
-	case "go":
-		return stripGoComments(content)
-	case "python":
-		return stripPythonComments(content)
-	case "javascript":
-		return stripJavaScriptComments(content)
-	// Add more languages as needed
-	default:
+	s, ok := syntaxes[language]
+	if !ok {
 		return content
 	}
+	return strings.TrimSpace(s.strip(content))
 }
 
-func stripGoComments(content string) string {
-	// Strip line comments
-	lineComments := regexp.MustCompile(`//.*`)
-	content = lineComments.ReplaceAllString(content, "")
-
-	// Strip block comments
-	blockComments := regexp.MustCompile(`(?s)/\*.*?\*/`)
-	content = blockComments.ReplaceAllString(content, "")
-
-	return strings.TrimSpace(content)
-}
-
-func stripPythonComments(content string) string {
-	// Strip line comments
-	lineComments := regexp.MustCompile(`#.*`)
-	content = lineComments.ReplaceAllString(content, "")
-
-	// Strip triple-quoted strings (docstrings)
-	// Matches """...""" or '''...'''
-	docStrings := regexp.MustCompile(`(?s)(\"\"\".*?\"\"\"|\'\'\'.*?\'\'\')`)
-	content = docStrings.ReplaceAllString(content, "")
-
-	return strings.TrimSpace(content)
-}
-
-func stripJavaScriptComments(content string) string {
-	// Similar to Go comments
-	lineComments := regexp.MustCompile(`//.*`)
-	content = lineComments.ReplaceAllString(content, "")
-
-	blockComments := regexp.MustCompile(`(?s)/\*.*?\*/`)
-	content = blockComments.ReplaceAllString(content, "")
-
-	return strings.TrimSpace(content)
+// Mask returns content with comments and the contents of string, character
+// and regular expression literals replaced by spaces, keeping newlines and
+// byte offsets intact, so callers can scan the code structurally. It
+// reports false for unsupported languages.
+func Mask(content, language string) (string, bool) {
+	s, ok := syntaxes[language]
+	if !ok {
+		return "", false
+	}
+	return s.mask(content), true
 }
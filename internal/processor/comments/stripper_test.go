@@ -0,0 +1,97 @@
+package comments
+
+import "testing"
+
+func TestStripComments(t *testing.T) {
+	cases := []struct {
+		name     string
+		language string
+		content  string
+		want     string
+	}{
+		{
+			name:     "go comment markers in strings",
+			language: "go",
+			content:  "// Package main\npackage main\n\nvar u = \"http://example.com\" // home\nvar r = `/* raw */` /* block */ + \"x\"\n",
+			want:     "package main\n\nvar u = \"http://example.com\"\nvar r = `/* raw */`  + \"x\"",
+		},
+		{
+			name:     "go escaped quote",
+			language: "go",
+			content:  "s := \"a\\\"//b\" // c",
+			want:     "s := \"a\\\"//b\"",
+		},
+		{
+			name:     "python docstring and hash in string",
+			language: "python",
+			content:  "def f():\n    \"\"\"Doc.\"\"\"\n    x = \"\"\"keep\"\"\"\n    return '#' # c\n",
+			want:     "def f():\n    x = \"\"\"keep\"\"\"\n    return '#'",
+		},
+		{
+			name:     "python strings after return and in calls",
+			language: "python",
+			content:  "def q():\n    return \"\"\"SELECT * FROM t\"\"\"  # query\n\ndef p():\n    print(\n        \"\"\"text\"\"\")\n    d = {\n        \"k\":\n            \"\"\"v\"\"\"}\n",
+			want:     "def q():\n    return \"\"\"SELECT * FROM t\"\"\"\n\ndef p():\n    print(\n        \"\"\"text\"\"\")\n    d = {\n        \"k\":\n            \"\"\"v\"\"\"}",
+		},
+		{
+			name:     "python module and class docstrings",
+			language: "python",
+			content:  "# header\n\"\"\"Module.\"\"\"\nclass A:  # c\n    '''Class.'''\n    x = 1\n",
+			want:     "class A:\n    x = 1",
+		},
+		{
+			name:     "javascript regex and template literal",
+			language: "javascript",
+			content:  "const r = /\\/\\/x/g; // c\nconst u = `http://${host}`;\nconst d = a / b / c;",
+			want:     "const r = /\\/\\/x/g;\nconst u = `http://${host}`;\nconst d = a / b / c;",
+		},
+		{
+			name:     "javascript regex after keywords",
+			language: "javascript",
+			content:  "function f(x) {\n  return /https?:\\/\\//.test(x); // c\n}\nif (typeof /a/ === \"object\") throw /b/;\nconst n = obj.return / 2; // d",
+			want:     "function f(x) {\n  return /https?:\\/\\//.test(x);\n}\nif (typeof /a/ === \"object\") throw /b/;\nconst n = obj.return / 2;",
+		},
+		{
+			name:     "scss url",
+			language: "scss",
+			content:  "// icons\n.a { background: url(http://example.com/x.png); } // c\n",
+			want:     ".a { background: url(http://example.com/x.png); }",
+		},
+		{
+			name:     "shell single quotes do not escape",
+			language: "bash",
+			content:  "dir='C:\\' # windows\necho \"a\\\"#b\" # c\n",
+			want:     "dir='C:\\'\necho \"a\\\"#b\"",
+		},
+		{
+			name:     "rust lifetimes",
+			language: "rust",
+			content:  "fn f<'a>(x: &'a str) -> char { '\"' } // c",
+			want:     "fn f<'a>(x: &'a str) -> char { '\"' }",
+		},
+		{
+			name:     "shell parameter expansion",
+			language: "bash",
+			content:  "# setup\necho $# ${#args} # count",
+			want:     "echo $# ${#args}",
+		},
+		{
+			name:     "yaml apostrophe in plain scalar",
+			language: "yaml",
+			content:  "a: it's # c\nb: 'x # y' # z\nc: [\"d\",'e'] # f\n",
+			want:     "a: it's\nb: 'x # y'\nc: [\"d\",'e']",
+		},
+		{
+			name:     "unsupported language",
+			language: "unknown",
+			content:  "// kept\n",
+			want:     "// kept\n",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := StripComments(tc.content, tc.language); got != tc.want {
+			t.Errorf("%s: StripComments() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
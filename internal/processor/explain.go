@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/filter"
+	"github.com/dwrtz/sink/internal/utils"
+)
+
+// Step is a single stage of the decision whether a file is processed
+type Step struct {
+	Stage  string
+	Passed bool
+	Detail string
+}
+
+// Explain reports each stage of the decision whether path is processed, in
+// the order Process applies them, stopping at the first stage that rejects
+// it. The returned bool reports whether the file would be included.
+func (fp *FileProcessor) Explain(path string) ([]Step, bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	relPath, err := filepath.Rel(fp.fs.Root(), absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil, false, fmt.Errorf("%s is not inside %s", path, fp.fs.Root())
+	}
+
+	info, err := fp.fs.Stat(relPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.IsDir() {
+		return nil, false, fmt.Errorf("%s is a directory", path)
+	}
+
+	var steps []Step
+	reject := func(stage, detail string) ([]Step, bool, error) {
+		return append(steps, Step{Stage: stage, Detail: detail}), false, nil
+	}
+	pass := func(stage, detail string) {
+		steps = append(steps, Step{Stage: stage, Passed: true, Detail: detail})
+	}
+
+	// Directories are checked during the walk before any file inside them
+	ignores := fp.ignorer.Explainer(relPath)
+	parts := filter.PathParts(relPath)
+	for i := 1; i < len(parts); i++ {
+		dir := filepath.Join(parts[:i]...)
+		name := parts[i-1]
+		if name == ".git" || name == ".sink" {
+			return reject("directory", fmt.Sprintf("%s is always skipped", dir))
+		}
+		if pattern, ok := filter.FirstMatch(dir, fp.config.PrunePatterns, fp.config.CaseSensitive); ok {
+			return reject("directory", fmt.Sprintf("%s matches prune pattern %q", dir, pattern))
+		}
+		if match, err := ignores.Explain(dir); err == nil && match != nil && match.Ignored {
+			return reject("directory", fmt.Sprintf("%s is ignored by %s", dir, describeIgnore(match)))
+		}
+		if pattern, ok := filter.FirstMatch(dir, fp.config.ExcludePatterns, fp.config.CaseSensitive); ok {
+			return reject("directory", fmt.Sprintf("%s matches exclude pattern %q", dir, pattern))
+		}
+	}
+	if len(parts) > 1 {
+		pass("directory", "no parent directory is pruned, ignored or excluded")
+	}
+
+	if fp.config.OnlyFiles != nil {
+		if !fp.config.OnlyFiles[absPath] {
+			return reject("changed files", "not changed since the given ref")
+		}
+		pass("changed files", "changed since the given ref")
+	}
+
+	if utils.IsBinaryFile(absPath) {
+		return reject("binary", "file looks binary")
+	}
+	pass("binary", "text file")
+
+	match, err := ignores.Explain(relPath)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case match == nil:
+		pass("gitignore", "no pattern matches")
+	case match.Ignored:
+		return reject("gitignore", "ignored by "+describeIgnore(match))
+	default:
+		pass("gitignore", "re-included by "+describeIgnore(match))
+	}
+
+	if fp.attributes.IsLinguistExcluded(relPath) {
+		if !fp.config.IncludeGenerated {
+			return reject("gitattributes", "marked linguist-generated or linguist-vendored")
+		}
+		pass("gitattributes", "marked generated or vendored, but include-generated is set")
+	} else {
+		pass("gitattributes", "not marked generated or vendored")
+	}
+
+	if fp.config.NoTests || fp.config.TestsOnly {
+		isTest := filter.IsTestFile(relPath)
+		switch {
+		case isTest && fp.config.NoTests:
+			return reject("tests", "test file excluded by no-tests")
+		case !isTest && fp.config.TestsOnly:
+			return reject("tests", "not a test file and tests-only is set")
+		case isTest:
+			pass("tests", "test file included by tests-only")
+		default:
+			pass("tests", "not a test file")
+		}
+	}
+
+	if len(fp.config.FilterPatterns) == 0 {
+		pass("filter", "no filter patterns configured")
+	} else if pattern, ok := filter.FirstMatch(relPath, fp.config.FilterPatterns, fp.config.CaseSensitive); ok {
+		pass("filter", fmt.Sprintf("matches filter pattern %q", pattern))
+	} else {
+		return reject("filter", fmt.Sprintf("matches none of the filter patterns %s", strings.Join(fp.config.FilterPatterns, ", ")))
+	}
+
+	if pattern, ok := filter.FirstMatch(relPath, fp.config.ExcludePatterns, fp.config.CaseSensitive); ok {
+		return reject("exclude", fmt.Sprintf("matches exclude pattern %q", pattern))
+	}
+	pass("exclude", "matches no exclude pattern")
+
+	if fp.config.MaxFileSize > 0 {
+		if info.Size() > fp.config.MaxFileSize {
+			return reject("size", fmt.Sprintf("%d bytes exceeds max-file-size of %d bytes", info.Size(), fp.config.MaxFileSize))
+		}
+		pass("size", fmt.Sprintf("%d bytes is within max-file-size", info.Size()))
+	}
+
+	return steps, true, nil
+}
+
+// describeIgnore formats the source of a gitignore match
+func describeIgnore(match *filter.IgnoreMatch) string {
+	if match.Pattern == "" {
+		return match.Source
+	}
+	return fmt.Sprintf("%q (%s:%d)", match.Pattern, match.Source, match.Line)
+}
@@ -5,11 +5,21 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/dwrtz/sink/internal/cache"
 	"github.com/dwrtz/sink/internal/filter"
+	"github.com/dwrtz/sink/internal/hooks"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/plugins"
+	"github.com/dwrtz/sink/internal/processor/outline"
+	"github.com/dwrtz/sink/internal/processor/redact"
+	"github.com/dwrtz/sink/internal/progress"
 	"github.com/dwrtz/sink/internal/utils"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
@@ -17,32 +27,107 @@ import (
 
 type FileInfo struct {
 	Path     string
+	RelPath  string // Path relative to the repository root
 	Ext      string
 	Content  string
 	Language string
 	Size     int64
 	Created  time.Time
 	Modified time.Time
+	// RelPath of an earlier file with identical content when deduplicated;
+	// Content is then a stub referring to it
+	DuplicateOf string
+	// Tokens in Content, counted with the configured encoding; zero for
+	// files listed without reading their contents
+	Tokens int
 }
 
 type Config struct {
 	RepoRoot        string
 	FilterPatterns  []string
 	ExcludePatterns []string
+	PrunePatterns   []string // Matched against directories only
 	CaseSensitive   bool
 	SyntaxMap       map[string]string
+	// Path globs mapped to a language that overrides extension-based detection
+	LanguageOverrides map[string]string
+	// Include files marked linguist-generated or linguist-vendored
+	IncludeGenerated bool
+	// Exclude test files, or include nothing but test files
+	NoTests   bool
+	TestsOnly bool
+	// If set, only these absolute file paths are processed
+	OnlyFiles map[string]bool
+	// Globs of absolute paths this run writes to, such as the output file
+	// and its split parts, which are never read as input
+	OutputFiles []string
+	// Replace secrets found by the built-in rules, and matches of
+	// RedactPatterns, in file contents
+	Redact         bool
+	RedactPatterns []string
+	// Reduce files in supported languages to imports, type definitions and
+	// function signatures
+	Outline bool
+	// Shell command run on each file's content before redaction and
+	// outlining; see hooks.File
+	PreHook string
+	// WebAssembly plugins run in order on each file's content after
+	// PreHook; only those with Transform set are used
+	Plugins []*plugins.Plugin
+	// Skip files larger than this many bytes (0 = unlimited)
+	MaxFileSize int64
+	// Processed contents of unchanged files from earlier runs; nil to
+	// always read and process every file
+	Cache *cache.Cache
+	// Number of files read and processed at once (0 = number of CPUs)
+	Concurrency int
+	// Record every skipped file and directory with the reason, not just
+	// those larger than MaxFileSize
+	ReportSkipped bool
+	// Told about files as they are found and processed; nil for no progress
+	// reporting. The caller starts and stops it.
+	Progress *progress.Reporter
 }
 
+// SkippedFile is a file, or a directory with a trailing slash, that was not
+// processed
+type SkippedFile struct {
+	RelPath string
+	Size    int64
+	Reason  string
+}
+
+// Reasons a file or directory is skipped
+const (
+	SkipBinary     = "binary"
+	SkipIgnored    = "gitignored"
+	SkipGenerated  = "generated or vendored"
+	SkipTests      = "test file selection"
+	SkipFiltered   = "no filter pattern matched"
+	SkipExcluded   = "excluded by pattern"
+	SkipPruned     = "pruned"
+	SkipOutput     = "output file"
+	SkipTooLarge   = "larger than max-file-size"
+	SkipUnreadable = "unreadable"
+)
+
 type FileProcessor struct {
-	config  Config
-	fs      billy.Filesystem
-	ignorer *filter.GitignoreFilter
+	config     Config
+	fs         billy.Filesystem
+	ignorer    *filter.GitignoreFilter
+	attributes *filter.AttributesFilter
+	redactor   *redact.Redactor // nil when nothing is redacted
+	skipped    []SkippedFile
 }
 
 // sentinel error so we can detect when to skip a “file”
 var errSkipFile = errors.New("skip this file or directory")
 
 func NewFileProcessor(config Config) (*FileProcessor, error) {
+	if config.NoTests && config.TestsOnly {
+		return nil, fmt.Errorf("cannot exclude tests and include only tests at the same time")
+	}
+
 	// Create filesystem relative to repo root
 	fs := osfs.New(config.RepoRoot)
 
@@ -56,26 +141,161 @@ func NewFileProcessor(config Config) (*FileProcessor, error) {
 		return nil, err
 	}
 
+	attributes, err := filter.NewAttributesFilter(config.RepoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var redactor *redact.Redactor
+	if config.Redact || len(config.RedactPatterns) > 0 {
+		redactor, err = redact.New(config.Redact, config.RedactPatterns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &FileProcessor{
-		config:  config,
-		fs:      fs,
-		ignorer: ignorer,
+		config:     config,
+		fs:         fs,
+		ignorer:    ignorer,
+		attributes: attributes,
+		redactor:   redactor,
 	}, nil
 }
 
 func (fp *FileProcessor) Process() ([]FileInfo, error) {
+	var paths []string
+	err := fp.walk(func(path string) error {
+		paths = append(paths, path)
+		fp.config.Progress.Found()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fp.config.Progress.SetTotal(len(paths))
+
+	workers := fp.config.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Files are read and processed in parallel, and collected in walk order
+	results := make([]FileInfo, len(paths))
+	errs := make([]error, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = fp.processFile(paths[i])
+				fp.config.Progress.Advance(results[i].Size)
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	files := make([]FileInfo, 0, len(paths))
+	for i, fileErr := range errs {
+		if fileErr != nil {
+			// We intentionally skip files with our sentinel error
+			if errors.Is(fileErr, errSkipFile) {
+				continue
+			}
+			// Files we may not read, or that vanished or are dangling
+			// symlinks, are skipped rather than failing the run
+			if errors.Is(fileErr, fs.ErrPermission) || errors.Is(fileErr, fs.ErrNotExist) {
+				logging.Warn("skipping unreadable file", "path", paths[i], "error", fileErr)
+				relPath, _ := filepath.Rel(fp.fs.Root(), paths[i])
+				fp.skip(relPath, 0, SkipUnreadable)
+				continue
+			}
+			// For other errors, return up the chain
+			logging.Error("failed to process file", "path", paths[i], "error", fileErr)
+			return nil, fileErr
+		}
+		files = append(files, results[i])
+	}
+
+	return files, nil
+}
+
+// List returns the files that Process would include without reading their
+// contents. Every field but Content is set.
+func (fp *FileProcessor) List() ([]FileInfo, error) {
 	var files []FileInfo
 
+	err := fp.walk(func(path string) error {
+		relPath, err := filepath.Rel(fp.fs.Root(), path)
+		if err != nil {
+			return err
+		}
+
+		// Skip symlinks to directories, like processFile does
+		info, err := fp.fs.Stat(relPath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		files = append(files, FileInfo{
+			Path:     path,
+			RelPath:  relPath,
+			Ext:      filepath.Ext(path),
+			Language: fp.detectLanguage(path, relPath, nil),
+			Size:     info.Size(),
+			Created:  info.ModTime(),
+			Modified: info.ModTime(),
+		})
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Skipped returns the files skipped by the last Process or List call for
+// exceeding MaxFileSize or being unreadable, and with ReportSkipped, every
+// other skipped file and directory
+func (fp *FileProcessor) Skipped() []SkippedFile {
+	return fp.skipped
+}
+
+// skip records a skipped path. Reasons other than size and unreadable files
+// are only recorded with ReportSkipped.
+func (fp *FileProcessor) skip(relPath string, size int64, reason string) {
+	if reason == "" || (!fp.config.ReportSkipped && reason != SkipTooLarge && reason != SkipUnreadable) {
+		return
+	}
+	fp.skipped = append(fp.skipped, SkippedFile{RelPath: relPath, Size: size, Reason: reason})
+}
+
+// walk calls fn for every file in the repository that passes the directory
+// and file checks
+func (fp *FileProcessor) walk(fn func(path string) error) error {
+	fp.skipped = nil
+
 	// Walk the entire repository from root
-	err := filepath.WalkDir(fp.config.RepoRoot, func(path string, d fs.DirEntry, err error) error {
+	return filepath.WalkDir(fp.config.RepoRoot, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// If it's a directory, skip .git or any directory that matches excludes
 		if d.IsDir() {
-			// Skip .git directory entirely
-			if d.Name() == ".git" {
+			// Skip .git and sink's own state directory entirely
+			if d.Name() == ".git" || d.Name() == ".sink" {
 				return filepath.SkipDir
 			}
 
@@ -84,19 +304,28 @@ func (fp *FileProcessor) Process() ([]FileInfo, error) {
 				return err
 			}
 
+			// Prune matching subtrees before doing any other work on them
+			if relPath != "." && len(fp.config.PrunePatterns) > 0 &&
+				filter.MatchesAny(relPath, fp.config.PrunePatterns, fp.config.CaseSensitive) {
+				fp.skip(relPath+"/", 0, SkipPruned)
+				return filepath.SkipDir
+			}
+
 			// Check if directory is ignored by gitignore
 			ignored, ignErr := fp.ignorer.IsIgnored(relPath)
 			if ignErr != nil {
-				fmt.Printf("Error checking if directory is ignored: %v\n", ignErr)
+				logging.Error("failed to check if directory is ignored", "path", relPath, "error", ignErr)
 				return ignErr
 			}
 			if ignored {
+				fp.skip(relPath+"/", 0, SkipIgnored)
 				return filepath.SkipDir
 			}
 
 			// Check directory against exclude patterns
 			if len(fp.config.ExcludePatterns) > 0 &&
 				filter.MatchesAny(relPath, fp.config.ExcludePatterns, fp.config.CaseSensitive) {
+				fp.skip(relPath+"/", 0, SkipExcluded)
 				return filepath.SkipDir
 			}
 
@@ -104,31 +333,26 @@ func (fp *FileProcessor) Process() ([]FileInfo, error) {
 		}
 
 		// If we got here, we have a non-dir (d.IsDir() == false), or a symlink, etc.
-		if !fp.shouldProcessFile(path) {
+		if reason, skip := fp.skipReason(path); skip {
 			// Don’t abort entire walk, just skip
+			if relPath, err := filepath.Rel(fp.fs.Root(), path); err == nil {
+				fp.skip(relPath, 0, reason)
+			}
 			return nil
 		}
 
-		fileInfo, fileErr := fp.processFile(path)
-		if fileErr != nil {
-			// We intentionally skip files with our sentinel error
-			if errors.Is(fileErr, errSkipFile) {
+		if fp.config.MaxFileSize > 0 {
+			// Stat follows symlinks, unlike the walk's DirEntry
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() && info.Size() > fp.config.MaxFileSize {
+				relPath, _ := filepath.Rel(fp.fs.Root(), path)
+				fp.skip(relPath, info.Size(), SkipTooLarge)
 				return nil
 			}
-			// For other errors, return up the chain
-			fmt.Printf("Error processing file %s: %v\n", path, fileErr)
-			return fileErr
 		}
 
-		files = append(files, fileInfo)
-		return nil
+		return fn(path)
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return files, nil
 }
 
 func (fp *FileProcessor) processFile(path string) (FileInfo, error) {
@@ -148,6 +372,24 @@ func (fp *FileProcessor) processFile(path string) (FileInfo, error) {
 		return FileInfo{}, errSkipFile
 	}
 
+	cached := func(entry cache.Entry) FileInfo {
+		return FileInfo{
+			Path:     path,
+			RelPath:  relPath,
+			Ext:      filepath.Ext(path),
+			Content:  entry.Content,
+			Language: entry.Language,
+			Size:     info.Size(),
+			Created:  info.ModTime(),
+			Modified: info.ModTime(),
+		}
+	}
+	if fp.config.Cache != nil {
+		if entry, ok := fp.config.Cache.Lookup(relPath, info.Size(), info.ModTime()); ok {
+			return cached(entry), nil
+		}
+	}
+
 	// Try opening as a file
 	file, err := fp.fs.Open(relPath)
 	if err != nil {
@@ -164,11 +406,59 @@ func (fp *FileProcessor) processFile(path string) (FileInfo, error) {
 		return FileInfo{}, err
 	}
 
+	// Files touched without changing, such as by a checkout, are reused by
+	// their content hash
+	var sourceHash string
+	if fp.config.Cache != nil {
+		sourceHash = cache.Hash(content)
+		if entry, ok := fp.config.Cache.LookupSource(relPath, sourceHash, info.Size(), info.ModTime()); ok {
+			return cached(entry), nil
+		}
+	}
+
+	text := string(content)
+	if fp.config.PreHook != "" {
+		text, err = hooks.File(fp.config.PreHook, fp.config.RepoRoot, filepath.ToSlash(relPath), text)
+		if err != nil {
+			return FileInfo{}, err
+		}
+	}
+	for _, plugin := range fp.config.Plugins {
+		if !plugin.Transform {
+			continue
+		}
+		text, err = plugin.File(filepath.ToSlash(relPath), text)
+		if err != nil {
+			return FileInfo{}, err
+		}
+	}
+	if fp.redactor != nil {
+		text, _ = fp.redactor.Redact(text)
+	}
+
+	language := fp.detectLanguage(path, relPath, content)
+	if fp.config.Outline {
+		if outlined, ok := outline.Outline(text, language); ok {
+			text = outlined
+		}
+	}
+
+	if fp.config.Cache != nil {
+		fp.config.Cache.Store(relPath, cache.Entry{
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			SourceHash: sourceHash,
+			Content:    text,
+			Language:   language,
+		})
+	}
+
 	return FileInfo{
 		Path:     path,
+		RelPath:  relPath,
 		Ext:      filepath.Ext(path),
-		Content:  string(content),
-		Language: fp.detectLanguage(path),
+		Content:  text,
+		Language: language,
 		Size:     info.Size(),
 		Created:  info.ModTime(),
 		Modified: info.ModTime(),
@@ -180,70 +470,71 @@ func isDirOpenError(err error) bool {
 	return strings.Contains(err.Error(), "is a directory")
 }
 
-// shouldProcessFile determines whether a path should be processed based on
-// binary check and filter/exclude patterns.
-func (fp *FileProcessor) shouldProcessFile(path string) bool {
+// skipReason determines whether a path should be skipped based on binary
+// check and filter/exclude patterns, and why. Files outside OnlyFiles are
+// skipped with an empty reason.
+func (fp *FileProcessor) skipReason(path string) (string, bool) {
+	// Restrict to an explicit set of files, e.g. those changed since a ref
+	if fp.config.OnlyFiles != nil {
+		absPath, err := filepath.Abs(path)
+		if err != nil || !fp.config.OnlyFiles[absPath] {
+			return "", true
+		}
+	}
+
+	// A previous generation written inside the tree is not input
+	if len(fp.config.OutputFiles) > 0 {
+		if absPath, err := filepath.Abs(path); err == nil {
+			for _, pattern := range fp.config.OutputFiles {
+				if matched, _ := filepath.Match(pattern, absPath); matched {
+					return SkipOutput, true
+				}
+			}
+		}
+	}
+
 	// Check if file is binary
 	if utils.IsBinaryFile(path) {
-		return false
+		return SkipBinary, true
 	}
 
 	relPath, err := filepath.Rel(fp.fs.Root(), path)
 	if err != nil {
-		return false
+		return "", true
 	}
 
 	// Check if file is ignored by gitignore patterns
 	ignored, err := fp.ignorer.IsIgnored(relPath)
-	if err != nil || ignored {
-		return false
+	if err != nil {
+		return SkipUnreadable, true
+	}
+	if ignored {
+		return SkipIgnored, true
+	}
+
+	// Check if file is marked generated or vendored in .gitattributes
+	if !fp.config.IncludeGenerated && fp.attributes.IsLinguistExcluded(relPath) {
+		return SkipGenerated, true
 	}
 
-	// If no filter patterns specified, only exclude patterns matter
-	if len(fp.config.FilterPatterns) == 0 {
-		// Check exclude patterns if any
-		if len(fp.config.ExcludePatterns) > 0 {
-			return !filter.MatchesAny(relPath, fp.config.ExcludePatterns, fp.config.CaseSensitive)
+	// Check per-language test file conventions
+	if fp.config.NoTests || fp.config.TestsOnly {
+		if filter.IsTestFile(relPath) != fp.config.TestsOnly {
+			return SkipTests, true
 		}
-		return true
 	}
 
 	// If we have filter patterns, file must match at least one
-	if !filter.MatchesAny(relPath, fp.config.FilterPatterns, fp.config.CaseSensitive) {
-		return false
+	if len(fp.config.FilterPatterns) > 0 &&
+		!filter.MatchesAny(relPath, fp.config.FilterPatterns, fp.config.CaseSensitive) {
+		return SkipFiltered, true
 	}
 
 	// Finally check exclude patterns
-	if len(fp.config.ExcludePatterns) > 0 {
-		return !filter.MatchesAny(relPath, fp.config.ExcludePatterns, fp.config.CaseSensitive)
+	if len(fp.config.ExcludePatterns) > 0 &&
+		filter.MatchesAny(relPath, fp.config.ExcludePatterns, fp.config.CaseSensitive) {
+		return SkipExcluded, true
 	}
 
-	return true
-}
-
-func (fp *FileProcessor) detectLanguage(path string) string {
-	ext := filepath.Ext(path)
-
-	// Check syntax map first
-	if lang, ok := fp.config.SyntaxMap[ext]; ok {
-		return lang
-	}
-
-	// Fall back to a small set of known file types
-	switch ext {
-	case ".go":
-		return "go"
-	case ".py":
-		return "python"
-	case ".js":
-		return "javascript"
-	case ".java":
-		return "java"
-	case ".cpp", ".hpp", ".cc", ".hh":
-		return "cpp"
-	case ".c", ".h":
-		return "c"
-	default:
-		return "unknown"
-	}
+	return "", false
 }
@@ -0,0 +1,75 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+type Config struct {
+	// Token encoding used for per-file and total token counts
+	TokenEncoding string
+}
+
+// file is the JSON representation of a single file
+type file struct {
+	Path        string `json:"path"`
+	RelPath     string `json:"rel_path"`
+	Ext         string `json:"ext"`
+	Language    string `json:"language"`
+	Size        int64  `json:"size"`
+	Tokens      int    `json:"tokens"`
+	Content     string `json:"content"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+// document is the top-level JSON object
+type document struct {
+	Files       []file `json:"files"`
+	TotalSize   int64  `json:"total_size"`
+	TotalTokens int    `json:"total_tokens"`
+}
+
+// Generator renders files as a single indented JSON document
+type Generator struct {
+	config Config
+}
+
+func NewGenerator(config Config) *Generator {
+	return &Generator{config: config}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	counter, err := tokens.NewCounter(g.config.TokenEncoding)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	doc := document{Files: make([]file, 0, len(files))}
+	for _, f := range files {
+		count, err := counter.Count(f.Content)
+		if err != nil {
+			return "", fmt.Errorf("failed to count tokens in %s: %w", f.Path, err)
+		}
+		doc.Files = append(doc.Files, file{
+			Path:        f.Path,
+			RelPath:     f.RelPath,
+			Ext:         f.Ext,
+			Language:    f.Language,
+			Size:        f.Size,
+			Tokens:      count,
+			Content:     f.Content,
+			DuplicateOf: f.DuplicateOf,
+		})
+		doc.TotalSize += f.Size
+		doc.TotalTokens += count
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
@@ -0,0 +1,47 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// record is the JSON representation of a single file
+type record struct {
+	Path        string `json:"path"`
+	Ext         string `json:"ext"`
+	Language    string `json:"language"`
+	Size        int64  `json:"size"`
+	Content     string `json:"content"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+// Generator renders files as newline-delimited JSON, one record per file
+type Generator struct{}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	var content strings.Builder
+
+	for _, file := range files {
+		line, err := json.Marshal(record{
+			Path:        file.Path,
+			Ext:         file.Ext,
+			Language:    file.Language,
+			Size:        file.Size,
+			Content:     file.Content,
+			DuplicateOf: file.DuplicateOf,
+		})
+		if err != nil {
+			return "", err
+		}
+		content.Write(line)
+		content.WriteString("\n")
+	}
+
+	return content.String(), nil
+}
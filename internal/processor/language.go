@@ -0,0 +1,243 @@
+package processor
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/filter"
+)
+
+// extensionLanguages maps unambiguous file extensions to code fence languages
+var extensionLanguages = map[string]string{
+	".go":         "go",
+	".py":         "python",
+	".pyi":        "python",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".jsx":        "jsx",
+	".ts":         "typescript",
+	".mts":        "typescript",
+	".cts":        "typescript",
+	".tsx":        "tsx",
+	".java":       "java",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".swift":      "swift",
+	".rs":         "rust",
+	".rb":         "ruby",
+	".php":        "php",
+	".cs":         "csharp",
+	".cpp":        "cpp",
+	".hpp":        "cpp",
+	".cc":         "cpp",
+	".hh":         "cpp",
+	".cxx":        "cpp",
+	".c":          "c",
+	".sh":         "bash",
+	".bash":       "bash",
+	".zsh":        "zsh",
+	".sql":        "sql",
+	".html":       "html",
+	".htm":        "html",
+	".css":        "css",
+	".scss":       "scss",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".toml":       "toml",
+	".xml":        "xml",
+	".md":         "markdown",
+	".markdown":   "markdown",
+	".proto":      "protobuf",
+	".dockerfile": "dockerfile",
+	".mk":         "makefile",
+}
+
+// filenameLanguages maps well-known file names without a telling extension
+// to code fence languages
+var filenameLanguages = map[string]string{
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"Makefile":       "makefile",
+	"GNUmakefile":    "makefile",
+	"makefile":       "makefile",
+	"CMakeLists.txt": "cmake",
+	"Gemfile":        "ruby",
+	"Rakefile":       "ruby",
+	"Jenkinsfile":    "groovy",
+	"go.mod":         "go-module",
+	".bashrc":        "bash",
+	".zshrc":         "zsh",
+}
+
+// ambiguousExtensions are shared by several languages and resolved by
+// looking at the content
+var ambiguousExtensions = map[string]func(content []byte) string{
+	".h":  detectHeader,
+	".m":  detectM,
+	".pl": detectPl,
+}
+
+// interpreterLanguages maps shebang interpreters to languages
+var interpreterLanguages = map[string]string{
+	"sh":      "sh",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"ksh":     "sh",
+	"dash":    "sh",
+	"python":  "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"rscript": "r",
+	"awk":     "awk",
+	"tclsh":   "tcl",
+	"fish":    "fish",
+}
+
+// detectLanguage determines the code fence language of a file from, in
+// order, path overrides, the syntax map, the file name and extension,
+// content heuristics for ambiguous extensions and the shebang line. Content
+// may be nil, in which case only path-based detection is used.
+func (fp *FileProcessor) detectLanguage(path, relPath string, content []byte) string {
+	// Path overrides take precedence over any extension-based detection
+	if lang, ok := fp.overrideLanguage(relPath); ok {
+		return lang
+	}
+
+	name := filepath.Base(path)
+	ext := filepath.Ext(path)
+
+	// Check syntax map first; file names take precedence over extensions
+	if lang, ok := fp.config.SyntaxMap[name]; ok {
+		return lang
+	}
+	if lang, ok := fp.config.SyntaxMap[ext]; ok {
+		return lang
+	}
+
+	if lang, ok := filenameLanguages[name]; ok {
+		return lang
+	}
+	if lang, ok := extensionLanguages[strings.ToLower(ext)]; ok {
+		return lang
+	}
+
+	if detect, ok := ambiguousExtensions[strings.ToLower(ext)]; ok {
+		if content == nil {
+			// Fall back to the most common language for the extension
+			return detect([]byte{})
+		}
+		return detect(content)
+	}
+
+	if lang, ok := shebangLanguage(content); ok {
+		return lang
+	}
+
+	return "unknown"
+}
+
+// shebangLanguage returns the language of the interpreter named on a
+// "#!" first line, handling /usr/bin/env and versioned interpreters such as
+// python3.12
+func shebangLanguage(content []byte) (string, bool) {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return "", false
+	}
+	line := string(content[2:])
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as -S
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return "", false
+		}
+		interpreter = filepath.Base(fields[0])
+	}
+
+	interpreter = strings.ToLower(strings.TrimRight(interpreter, "0123456789."))
+	lang, ok := interpreterLanguages[interpreter]
+	return lang, ok
+}
+
+var (
+	objectiveCMarkers = regexp.MustCompile(`(?m)^\s*(@interface|@implementation|@protocol|@end|#import)\b`)
+	cppMarkers        = regexp.MustCompile(`(?m)\bnamespace\s+\w+|\btemplate\s*<|\bstd::|^\s*class\s+\w+[^;]*$|^\s*(public|private|protected):`)
+	matlabMarkers     = regexp.MustCompile(`(?m)^\s*(function\b.*=|%|end\s*$)`)
+	prologMarkers     = regexp.MustCompile(`(?m)^\s*:-|^\w+(\(.*\))?\s*:-`)
+)
+
+// detectHeader tells C, C++ and Objective-C headers apart
+func detectHeader(content []byte) string {
+	switch {
+	case objectiveCMarkers.Match(content):
+		return "objectivec"
+	case cppMarkers.Match(content):
+		return "cpp"
+	default:
+		return "c"
+	}
+}
+
+// detectM tells Objective-C and MATLAB sources apart
+func detectM(content []byte) string {
+	if !objectiveCMarkers.Match(content) && matlabMarkers.Match(content) {
+		return "matlab"
+	}
+	return "objectivec"
+}
+
+// detectPl tells Perl and Prolog sources apart
+func detectPl(content []byte) string {
+	if prologMarkers.Match(content) && !bytes.Contains(content, []byte("use strict")) {
+		return "prolog"
+	}
+	return "perl"
+}
+
+// overrideLanguage returns the language forced for relPath by the most
+// specific matching override pattern. Longer patterns are considered more
+// specific; ties are broken alphabetically so the result is deterministic.
+func (fp *FileProcessor) overrideLanguage(relPath string) (string, bool) {
+	if len(fp.config.LanguageOverrides) == 0 {
+		return "", false
+	}
+
+	patterns := make([]string, 0, len(fp.config.LanguageOverrides))
+	for pattern := range fp.config.LanguageOverrides {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		if filter.MatchesAny(relPath, []string{pattern}, fp.config.CaseSensitive) {
+			return fp.config.LanguageOverrides[pattern], true
+		}
+	}
+	return "", false
+}
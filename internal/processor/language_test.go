@@ -0,0 +1,77 @@
+package processor
+
+import "testing"
+
+func TestAmbiguousExtensions(t *testing.T) {
+	cases := []struct {
+		name    string
+		ext     string
+		content string
+		want    string
+	}{
+		{"empty header", ".h", "", "c"},
+		{"c header", ".h", "#include <stdio.h>\nint add(int a, int b);\n", "c"},
+		{"c struct header", ".h", "struct point { int x; };\ntypedef struct point point;\n", "c"},
+		{"cpp namespace", ".h", "namespace geo {\nint area();\n}\n", "cpp"},
+		{"cpp template", ".h", "template <typename T>\nT max(T a, T b);\n", "cpp"},
+		{"cpp class", ".h", "class Shape {\npublic:\n  virtual ~Shape();\n};\n", "cpp"},
+		{"cpp std", ".h", "#include <string>\nstd::string name();\n", "cpp"},
+		{"objective-c interface", ".h", "#import <Foundation/Foundation.h>\n@interface Shape : NSObject\n@end\n", "objectivec"},
+		{"objective-c protocol", ".h", "@protocol Drawable\n- (void)draw;\n@end\n", "objectivec"},
+		{"empty m", ".m", "", "objectivec"},
+		{"objective-c implementation", ".m", "#import \"Shape.h\"\n@implementation Shape\n@end\n", "objectivec"},
+		{"matlab function", ".m", "function y = square(x)\n  y = x.^2;\nend\n", "matlab"},
+		{"matlab comment", ".m", "% plot the data\nplot(x, y)\n", "matlab"},
+		{"objective-c with percent format", ".m", "@implementation A\n%d\n@end\n", "objectivec"},
+		{"empty pl", ".pl", "", "perl"},
+		{"perl script", ".pl", "use strict;\nmy $x = 1;\nprint \"$x\\n\";\n", "perl"},
+		{"prolog rule", ".pl", "parent(tom, bob).\ngrandparent(X, Z) :- parent(X, Y), parent(Y, Z).\n", "prolog"},
+		{"prolog directive", ".pl", ":- module(family, [parent/2]).\n", "prolog"},
+		{"perl with strict and prolog-like line", ".pl", "use strict;\nfoo :- bar\n", "perl"},
+	}
+
+	for _, tc := range cases {
+		detect, ok := ambiguousExtensions[tc.ext]
+		if !ok {
+			t.Errorf("%s: no detector for %s", tc.name, tc.ext)
+			continue
+		}
+		if got := detect([]byte(tc.content)); got != tc.want {
+			t.Errorf("%s: detect(%q) = %q, want %q", tc.name, tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestShebangLanguage(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{"no shebang", "echo hi\n", "", false},
+		{"empty", "", "", false},
+		{"bare shebang", "#!\n", "", false},
+		{"sh", "#!/bin/sh\necho hi\n", "sh", true},
+		{"bash", "#!/bin/bash\n", "bash", true},
+		{"bash with options", "#!/bin/bash -eu\n", "bash", true},
+		{"env python", "#!/usr/bin/env python\n", "python", true},
+		{"env versioned python", "#!/usr/bin/env python3.12\n", "python", true},
+		{"absolute versioned python", "#!/usr/local/bin/python3\n", "python", true},
+		{"env -S", "#!/usr/bin/env -S node --experimental-modules\n", "javascript", true},
+		{"env -S with several options", "#!/usr/bin/env -i -S deno run --allow-net\n", "typescript", true},
+		{"env without interpreter", "#!/usr/bin/env\n", "", false},
+		{"env with only options", "#!/usr/bin/env -S\n", "", false},
+		{"space after bang", "#! /usr/bin/perl -w\n", "perl", true},
+		{"uppercase interpreter", "#!/usr/bin/env Rscript\n", "r", true},
+		{"crlf line ending", "#!/bin/zsh\r\n", "zsh", true},
+		{"unknown interpreter", "#!/usr/bin/env frobnicate\n", "", false},
+	}
+
+	for _, tc := range cases {
+		got, ok := shebangLanguage([]byte(tc.content))
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%s: shebangLanguage(%q) = %q, %v, want %q, %v", tc.name, tc.content, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package markdown
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/dwrtz/sink/internal/processor"
@@ -10,9 +11,14 @@ import (
 )
 
 type Config struct {
-	NoCodeBlock   bool
-	LineNumbers   bool
-	StripComments bool
+	NoCodeBlock      bool
+	LineNumbers      bool
+	StripComments    bool
+	GroupByDirectory bool
+	// Prepend a directory tree of the included files
+	Tree bool
+	// Show the token count of each file in its metadata
+	Tokens bool
 }
 
 type Generator struct {
@@ -26,6 +32,16 @@ func NewGenerator(config Config) *Generator {
 func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
 	var content strings.Builder
 
+	if g.config.Tree {
+		content.WriteString("# Directory Structure\n\n")
+		content.WriteString(fmt.Sprintf("```\n%s```\n\n", processor.BuildTree(files).Render()))
+	}
+
+	if g.config.GroupByDirectory {
+		content.WriteString(g.generateGrouped(files))
+		return content.String(), nil
+	}
+
 	// Generate table of contents
 	content.WriteString("# Table of Contents\n")
 	for _, file := range files {
@@ -35,25 +51,100 @@ func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
 
 	// Generate content for each file
 	for _, file := range files {
-		content.WriteString(g.generateFileSection(file))
+		content.WriteString(g.generateFileSection(file, "##"))
 	}
 
 	return content.String(), nil
 }
 
-func (g *Generator) generateFileSection(file processor.FileInfo) string {
+// directoryGroup holds the files of a single directory in output order
+type directoryGroup struct {
+	dir   string
+	files []processor.FileInfo
+}
+
+// groupByDirectory groups files by their directory, ordering groups by the
+// first appearance of each directory
+func groupByDirectory(files []processor.FileInfo) []*directoryGroup {
+	var groups []*directoryGroup
+	index := make(map[string]*directoryGroup)
+	for _, file := range files {
+		dir := filepath.ToSlash(filepath.Dir(file.RelPath))
+		group, ok := index[dir]
+		if !ok {
+			group = &directoryGroup{dir: dir}
+			index[dir] = group
+			groups = append(groups, group)
+		}
+		group.files = append(group.files, file)
+	}
+	return groups
+}
+
+// generateGrouped renders files under one section per directory, each with
+// a short summary of the files it contains
+func (g *Generator) generateGrouped(files []processor.FileInfo) string {
+	var content strings.Builder
+	groups := groupByDirectory(files)
+
+	// Generate table of contents
+	content.WriteString("# Table of Contents\n")
+	for _, group := range groups {
+		content.WriteString(fmt.Sprintf("- %s/\n", group.dir))
+		for _, file := range group.files {
+			content.WriteString(fmt.Sprintf("  - %s\n", file.Path))
+		}
+	}
+	content.WriteString("\n")
+
+	for _, group := range groups {
+		var size int64
+		languages := make(map[string]bool)
+		var languageList []string
+		for _, file := range group.files {
+			size += file.Size
+			if !languages[file.Language] {
+				languages[file.Language] = true
+				languageList = append(languageList, file.Language)
+			}
+		}
+
+		content.WriteString(fmt.Sprintf("## Directory: %s\n\n", group.dir))
+		content.WriteString(fmt.Sprintf("- Files: %d\n", len(group.files)))
+		content.WriteString(fmt.Sprintf("- Size: %d bytes\n", size))
+		content.WriteString(fmt.Sprintf("- Languages: %s\n\n", strings.Join(languageList, ", ")))
+
+		for _, file := range group.files {
+			content.WriteString(g.generateFileSection(file, "###"))
+		}
+	}
+
+	return content.String()
+}
+
+// generateFileSection renders a single file using heading as the level of
+// the file heading
+func (g *Generator) generateFileSection(file processor.FileInfo, heading string) string {
 	var section strings.Builder
 
 	// File header
-	section.WriteString(fmt.Sprintf("## File: %s\n\n", file.Path))
+	section.WriteString(fmt.Sprintf("%s File: %s\n\n", heading, file.Path))
 	section.WriteString(fmt.Sprintf("- Extension: %s\n", file.Ext))
 	section.WriteString(fmt.Sprintf("- Language: %s\n", file.Language))
 	section.WriteString(fmt.Sprintf("- Size: %d bytes\n", file.Size))
+	if g.config.Tokens {
+		section.WriteString(fmt.Sprintf("- Tokens: %d\n", file.Tokens))
+	}
 	section.WriteString(fmt.Sprintf("- Created: %s\n", file.Created.Format("2006-01-02 15:04:05")))
 	section.WriteString(fmt.Sprintf("- Modified: %s\n\n", file.Modified.Format("2006-01-02 15:04:05")))
 
 	// Code content
-	section.WriteString("### Code\n\n")
+	section.WriteString(fmt.Sprintf("%s# Code\n\n", heading))
+
+	if file.DuplicateOf != "" {
+		section.WriteString(fmt.Sprintf("Same content as `%s`.\n\n", file.DuplicateOf))
+		return section.String()
+	}
 
 	content := file.Content
 	if g.config.StripComments {
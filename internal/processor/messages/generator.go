@@ -0,0 +1,124 @@
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// DefaultSystemPrompt is the system message used when none is configured
+const DefaultSystemPrompt = "You are an expert software engineer. The user shares the contents of a codebase; use it to answer their questions and carry out their tasks."
+
+type Config struct {
+	// Model set in the request body; omitted if empty
+	Model        string
+	SystemPrompt string
+	// Sent as a user message after the files, or before them with
+	// InstructionsBefore
+	Instructions       string
+	InstructionsBefore bool
+	// Spread the files over user messages of at most this many tokens
+	// (0 = a single message)
+	ChunkTokens   int
+	TokenEncoding string
+}
+
+// Message is a single chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// request is the body of a chat completions request
+type request struct {
+	Model    string    `json:"model,omitempty"`
+	Messages []Message `json:"messages"`
+}
+
+// Generator renders files as an OpenAI-style chat completions request body
+// with a system message and the files in one or more user messages
+type Generator struct {
+	config Config
+}
+
+func NewGenerator(config Config) *Generator {
+	return &Generator{config: config}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	chunks, err := g.chunk(files)
+	if err != nil {
+		return "", err
+	}
+
+	system := g.config.SystemPrompt
+	if system == "" {
+		system = DefaultSystemPrompt
+	}
+	req := request{Model: g.config.Model, Messages: []Message{{Role: "system", Content: system}}}
+
+	instructions := strings.TrimSpace(g.config.Instructions)
+	if instructions != "" && g.config.InstructionsBefore {
+		req.Messages = append(req.Messages, Message{Role: "user", Content: instructions})
+	}
+	for i, chunk := range chunks {
+		if len(chunks) > 1 {
+			chunk = fmt.Sprintf("Codebase part %d of %d:\n\n%s", i+1, len(chunks), chunk)
+		}
+		req.Messages = append(req.Messages, Message{Role: "user", Content: chunk})
+	}
+	if instructions != "" && !g.config.InstructionsBefore {
+		req.Messages = append(req.Messages, Message{Role: "user", Content: instructions})
+	}
+
+	out, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// chunk renders the files and groups them into message contents of at most
+// ChunkTokens tokens. A file larger than that gets a message of its own.
+func (g *Generator) chunk(files []processor.FileInfo) ([]string, error) {
+	blocks := make([]string, 0, len(files))
+	for _, file := range files {
+		blocks = append(blocks, renderFile(file))
+	}
+	if g.config.ChunkTokens <= 0 || len(blocks) == 0 {
+		return []string{strings.Join(blocks, "\n")}, nil
+	}
+
+	counter, err := tokens.NewCounter(g.config.TokenEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token counter: %w", err)
+	}
+
+	var chunks []string
+	var current []string
+	used := 0
+	for _, block := range blocks {
+		count, err := counter.Count(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tokens: %w", err)
+		}
+		if len(current) > 0 && used+count > g.config.ChunkTokens {
+			chunks = append(chunks, strings.Join(current, "\n"))
+			current, used = nil, 0
+		}
+		current = append(current, block)
+		used += count
+	}
+	return append(chunks, strings.Join(current, "\n")), nil
+}
+
+// renderFile formats a file as its path followed by a fenced code block
+func renderFile(file processor.FileInfo) string {
+	if file.DuplicateOf != "" {
+		return fmt.Sprintf("File: %s\nSame content as %s\n", file.RelPath, file.DuplicateOf)
+	}
+	return fmt.Sprintf("File: %s\n````%s\n%s\n````\n", file.RelPath, file.Language, strings.TrimRight(file.Content, "\n"))
+}
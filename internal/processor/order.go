@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/filter"
+)
+
+// topLevelConfigFiles are project configuration files that describe how a
+// repository is built and run
+var topLevelConfigFiles = map[string]bool{
+	"go.mod":             true,
+	"package.json":       true,
+	"cargo.toml":         true,
+	"pyproject.toml":     true,
+	"setup.py":           true,
+	"requirements.txt":   true,
+	"gemfile":            true,
+	"pom.xml":            true,
+	"build.gradle":       true,
+	"makefile":           true,
+	"dockerfile":         true,
+	"docker-compose.yml": true,
+	"compose.yaml":       true,
+	"tsconfig.json":      true,
+}
+
+// architectureDocs are well-known project overview documents
+var architectureDocs = []string{"architecture", "design", "overview", "contributing"}
+
+// OrderFiles reorders files in place according to the named ordering preset.
+// An empty order keeps the walk order.
+func OrderFiles(files []FileInfo, order string) error {
+	switch order {
+	case "":
+		return nil
+	case "docs-first":
+		sort.SliceStable(files, func(i, j int) bool {
+			return docsFirstRank(files[i]) < docsFirstRank(files[j])
+		})
+		return nil
+	default:
+		return fmt.Errorf("unknown order: %s", order)
+	}
+}
+
+// SortFiles sorts files in place by key: path, size, mtime (oldest first),
+// tokens, or none to keep the walk order. Sorting by tokens requires the
+// token count of each file in counts. Reverse inverts the resulting order.
+func SortFiles(files []FileInfo, key string, reverse bool, counts []int) error {
+	var less func(i, j int) bool
+	switch key {
+	case "", "none":
+	case "path":
+		less = func(i, j int) bool { return files[i].RelPath < files[j].RelPath }
+	case "size":
+		less = func(i, j int) bool { return files[i].Size < files[j].Size }
+	case "mtime":
+		less = func(i, j int) bool { return files[i].Modified.Before(files[j].Modified) }
+	case "tokens":
+		if len(counts) != len(files) {
+			return fmt.Errorf("sorting by tokens requires a token count for every file")
+		}
+		less = func(i, j int) bool { return counts[i] < counts[j] }
+	default:
+		return fmt.Errorf("unknown sort key: %s", key)
+	}
+
+	idx := make([]int, len(files))
+	for i := range idx {
+		idx[i] = i
+	}
+	if less != nil {
+		sort.SliceStable(idx, func(a, b int) bool { return less(idx[a], idx[b]) })
+	}
+	if reverse {
+		for a, b := 0, len(idx)-1; a < b; a, b = a+1, b-1 {
+			idx[a], idx[b] = idx[b], idx[a]
+		}
+	}
+
+	sorted := make([]FileInfo, len(files))
+	for i, j := range idx {
+		sorted[i] = files[j]
+	}
+	copy(files, sorted)
+	return nil
+}
+
+// PrioritizeFiles stably reorders files so those matching earlier patterns
+// come first, followed by files matching no pattern. Patterns prefixed with
+// "!" mark low-priority files, which are placed after everything else. The
+// first matching pattern decides a file's position.
+func PrioritizeFiles(files []FileInfo, patterns []string, caseSensitive bool) {
+	if len(patterns) == 0 {
+		return
+	}
+
+	rank := func(file FileInfo) int {
+		for i, pattern := range patterns {
+			low := strings.HasPrefix(pattern, "!")
+			if filter.MatchesAny(file.RelPath, []string{strings.TrimPrefix(pattern, "!")}, caseSensitive) {
+				if low {
+					return len(patterns) + 1 + i
+				}
+				return i
+			}
+		}
+		return len(patterns)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return rank(files[i]) < rank(files[j])
+	})
+}
+
+// docsFirstRank ranks files for the docs-first preset: README files, then
+// architecture docs, then top-level configuration, then other docs, then
+// everything else
+func docsFirstRank(file FileInfo) int {
+	rel := filepath.ToSlash(file.RelPath)
+	base := strings.ToLower(filepath.Base(rel))
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	topLevel := !strings.Contains(rel, "/")
+	isDoc := file.Ext == ".md" || file.Ext == ".rst" || (file.Ext == ".txt" && name == "readme")
+
+	switch {
+	case name == "readme" && topLevel:
+		return 0
+	case isDoc && (topLevel || strings.HasPrefix(rel, "docs/")) && containsAny(name, architectureDocs):
+		return 1
+	case topLevel && topLevelConfigFiles[base]:
+		return 2
+	case name == "readme" || isDoc:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
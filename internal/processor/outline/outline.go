@@ -0,0 +1,300 @@
+package outline
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor/comments"
+	"github.com/dwrtz/sink/internal/symbols"
+)
+
+// Elided replaces function bodies
+const Elided = "{ ... }"
+
+// braceLanguages are outlined by eliding brace-delimited function bodies
+var braceLanguages = map[string]bool{
+	"javascript": true,
+	"jsx":        true,
+	"typescript": true,
+	"tsx":        true,
+	"java":       true,
+	"kotlin":     true,
+	"swift":      true,
+	"rust":       true,
+	"c":          true,
+	"cpp":        true,
+	"objectivec": true,
+	"csharp":     true,
+	"php":        true,
+}
+
+// Outline reduces content to its package and import statements, type
+// definitions and function signatures, with function bodies replaced by
+// Elided. Go is outlined from the Go parser's syntax tree. Other languages
+// with a tree-sitter grammar have the bodies found by symbols elided, and
+// Python is then reduced to its declarations like in builds without cgo,
+// where the other languages are outlined heuristically. It reports false,
+// and content should be kept as is, if the language is unsupported or the
+// source cannot be parsed.
+func Outline(content, language string) (string, bool) {
+	if language == "go" {
+		return outlineGo(content)
+	}
+	if symbols.Supported(language) {
+		if outlined, ok := outlineSymbols(content, language); ok {
+			if language == "python" {
+				// Bodies are elided exactly; statements and class
+				// attributes around them are dropped as without cgo
+				return outlinePython(outlined), true
+			}
+			return outlined, true
+		}
+	}
+
+	switch {
+	case language == "python":
+		return outlinePython(content), true
+	case braceLanguages[language]:
+		return outlineBraces(content, language)
+	default:
+		return "", false
+	}
+}
+
+// outlineSymbols elides the bodies of the functions and methods symbols
+// finds, keeping everything around them
+func outlineSymbols(content, language string) (string, bool) {
+	syms, err := symbols.Extract([]byte(content), language)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	written := 0 // Bytes of content copied to b
+	for _, sym := range syms {
+		if sym.Kind != "function" && sym.Kind != "method" || sym.BodyEnd == 0 || sym.BodyStart < written {
+			continue
+		}
+		if language == "python" {
+			// The body starts at its first statement, already indented
+			b.WriteString(content[written:sym.BodyStart])
+			b.WriteString("...")
+		} else {
+			b.WriteString(strings.TrimRight(content[written:sym.BodyStart], " \t\n"))
+			b.WriteString(" " + Elided)
+		}
+		written = sym.BodyEnd
+	}
+	b.WriteString(content[written:])
+	return b.String(), true
+}
+
+// outlineGo keeps the package clause, imports, type declarations and
+// function signatures, along with their doc comments
+func outlineGo(content string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+
+	var b bytes.Buffer
+	if file.Doc != nil {
+		for _, c := range file.Doc.List {
+			b.WriteString(c.Text + "\n")
+		}
+	}
+	b.WriteString("package " + file.Name.Name + "\n")
+
+	for _, decl := range file.Decls {
+		var node ast.Node
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.IMPORT && d.Tok != token.TYPE {
+				continue
+			}
+			node = d
+		case *ast.FuncDecl:
+			signature := *d
+			signature.Body = nil
+			node = &signature
+		}
+
+		// Doc comments are printed along with the declaration
+		b.WriteString("\n")
+		if err := gofmt.Fprint(&b, fset, &printer.CommentedNode{Node: node, Comments: file.Comments}); err != nil {
+			return "", false
+		}
+		if _, ok := node.(*ast.FuncDecl); ok {
+			b.WriteString(" " + Elided)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), true
+}
+
+// gofmt prints declarations the way gofmt formats them
+var gofmt = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+var (
+	pythonKeep = regexp.MustCompile(`^\s*(import\s|from\s|class\s|def\s|async\s+def\s|@)`)
+	pythonDef  = regexp.MustCompile(`^\s*(async\s+)?def\s`)
+)
+
+// outlinePython keeps imports, decorators, class statements and function
+// signatures; function bodies become "..."
+func outlinePython(content string) string {
+	lines := strings.Split(content, "\n")
+	var out []string
+	skipIndent := -1 // Lines indented deeper than this belong to an elided body
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if skipIndent >= 0 {
+			if strings.TrimSpace(line) == "" || indent > skipIndent {
+				continue
+			}
+			skipIndent = -1
+		}
+		if !pythonKeep.MatchString(line) {
+			continue
+		}
+		isDef := pythonDef.MatchString(line)
+
+		// Signatures and parenthesized imports may span several lines
+		out = append(out, line)
+		for depth := strings.Count(line, "(") - strings.Count(line, ")"); depth > 0 && i+1 < len(lines); {
+			i++
+			line = lines[i]
+			out = append(out, line)
+			depth += strings.Count(line, "(") - strings.Count(line, ")")
+		}
+
+		if isDef {
+			out = append(out, strings.Repeat(" ", indent+4)+"...")
+			skipIndent = indent
+		}
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// containerKeywords introduce declarations whose bodies are kept so the
+// signatures of their members are outlined
+var containerKeywords = map[string]bool{
+	"class":     true,
+	"struct":    true,
+	"interface": true,
+	"enum":      true,
+	"namespace": true,
+	"module":    true,
+	"impl":      true,
+	"trait":     true,
+	"mod":       true,
+	"extern":    true,
+	"object":    true,
+	"record":    true,
+	"protocol":  true,
+	"extension": true,
+	"union":     true,
+}
+
+var word = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+// outlineBraces elides the bodies of brace-delimited functions, methods and
+// top-level statements while keeping class, interface and namespace bodies
+func outlineBraces(content, language string) (string, bool) {
+	// Work on the code without comments so commented-out braces are ignored
+	content = comments.StripComments(content, language)
+	masked, ok := comments.Mask(content, language)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	written := 0     // Bytes of content copied to b
+	headerStart := 0 // Start of the statement the next brace belongs to
+	parens := 0
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '(':
+			parens++
+		case ')':
+			parens--
+		case ';', '}':
+			if parens <= 0 {
+				headerStart = i + 1
+			}
+		case '{':
+			if parens > 0 {
+				continue
+			}
+			header := masked[headerStart:i]
+			if !isFunctionHeader(header) {
+				headerStart = i + 1
+				continue
+			}
+			end := matchingBrace(masked, i)
+			b.WriteString(strings.TrimRight(content[written:i], " \t\n"))
+			b.WriteString(" " + Elided)
+			written = end + 1
+			i = end
+			headerStart = end + 1
+		}
+	}
+	b.WriteString(content[written:])
+	return b.String(), true
+}
+
+// isFunctionHeader reports whether the code before a brace declares a
+// function or control statement rather than a container or a literal
+func isFunctionHeader(header string) bool {
+	if !strings.Contains(header, "(") && !strings.Contains(header, "=>") {
+		return false
+	}
+	// Only look at words outside parentheses, so annotation arguments and
+	// parameter types are not taken for keywords
+	var outside strings.Builder
+	depth := 0
+	for _, r := range header {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0:
+			outside.WriteRune(r)
+		}
+		if r == '(' || r == ')' {
+			outside.WriteByte(' ')
+		}
+	}
+	for _, w := range word.FindAllString(outside.String(), -1) {
+		if containerKeywords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingBrace returns the offset of the brace closing the one at open, or
+// the last offset if it is unbalanced
+func matchingBrace(masked string, open int) int {
+	depth := 0
+	for i := open; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(masked) - 1
+}
@@ -0,0 +1,188 @@
+package outline
+
+import (
+	"testing"
+
+	"github.com/dwrtz/sink/internal/symbols"
+)
+
+func TestOutlineSymbols(t *testing.T) {
+	cases := []struct {
+		name     string
+		language string
+		content  string
+		want     string
+	}{
+		{
+			name:     "python decorators and methods",
+			language: "python",
+			content:  "@dec\ndef f(a,\n      b):\n    \"\"\"Doc.\"\"\"\n    return a\n\nclass C:\n    x = 1\n    def m(self):\n        pass\n",
+			want:     "@dec\ndef f(a,\n      b):\n    ...\nclass C:\n    def m(self):\n        ...\n",
+		},
+		{
+			name:     "python body with dedented string",
+			language: "python",
+			content:  "def q():\n    return \"\"\"\nimport os\n\"\"\"\n",
+			want:     "def q():\n    ...\n",
+		},
+		{
+			name:     "java methods and constructors",
+			language: "java",
+			content:  "class A {\n  int x;\n  public String toString() { return \"{\"; }\n  A(int y) {\n    x = y;\n  }\n}\n",
+			want:     "class A {\n  int x;\n  public String toString() { ... }\n  A(int y) { ... }\n}\n",
+		},
+	}
+
+	if !symbols.Supported("python") {
+		t.Skip("tree-sitter grammars need cgo")
+	}
+	for _, tc := range cases {
+		got, ok := Outline(tc.content, tc.language)
+		if !ok {
+			t.Errorf("%s: Outline() reported unsupported", tc.name)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: Outline() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestOutlineParity checks that Outline gives the same result with and
+// without tree-sitter grammars
+func TestOutlineParity(t *testing.T) {
+	cases := []struct {
+		language string
+		content  string
+		want     string
+	}{
+		{
+			language: "go",
+			content:  "package p\n\nimport \"fmt\"\n\nvar v = 1\n\nconst c = 2\n\n// T is a type.\ntype T struct{ A int }\n\n// F prints.\nfunc F(a int) error {\n\tfmt.Println(a)\n\treturn nil\n}\n\nfunc (t *T) M() { t.A++ }\n",
+			want:     "package p\n\nimport \"fmt\"\n\n// T is a type.\ntype T struct{ A int }\n\n// F prints.\nfunc F(a int) error { ... }\n\nfunc (t *T) M() { ... }\n",
+		},
+		{
+			language: "python",
+			content:  "import os\n\nx = 1\nprint(x)\n\n@dec\ndef f(a):\n    return a\n\nclass C(Base):\n    y = 2\n\n    def m(self):\n        pass\n",
+			want:     "import os\n@dec\ndef f(a):\n    ...\nclass C(Base):\n    def m(self):\n        ...\n",
+		},
+	}
+
+	heuristic := map[string]func(string) string{
+		"go": func(content string) string {
+			outlined, _ := outlineGo(content)
+			return outlined
+		},
+		"python": outlinePython,
+	}
+	for _, tc := range cases {
+		got, ok := Outline(tc.content, tc.language)
+		if !ok {
+			t.Errorf("Outline(%s) reported unsupported", tc.language)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Outline(%s) = %q, want %q", tc.language, got, tc.want)
+		}
+		if got := heuristic[tc.language](tc.content); got != tc.want {
+			t.Errorf("%s outlined without tree-sitter = %q, want %q", tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestOutlineGo(t *testing.T) {
+	content := "// Package p does things.\npackage p\n\nimport \"fmt\"\n\n// T is a type.\ntype T struct {\n\tA int\n}\n\nvar v = 1\n\n// F prints.\nfunc F(a int,\n\tb string) error {\n\ts := \"}{\" // }\n\tfmt.Println(s, `{`)\n\treturn nil\n}\n\nfunc (t *T) M() { t.A++ }\n"
+	want := "// Package p does things.\npackage p\n\nimport \"fmt\"\n\n// T is a type.\ntype T struct {\n\tA int\n}\n\n// F prints.\nfunc F(a int,\n\tb string) error { ... }\n\nfunc (t *T) M() { ... }\n"
+
+	got, ok := outlineGo(content)
+	if !ok {
+		t.Fatal("outlineGo() failed to parse")
+	}
+	if got != want {
+		t.Errorf("outlineGo() = %q, want %q", got, want)
+	}
+
+	if _, ok := outlineGo("package p\nfunc {"); ok {
+		t.Error("outlineGo() accepted invalid source")
+	}
+}
+
+func TestOutlinePython(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "decorators",
+			content: "import os\n\n@app.route(\"/\")\n@login_required\ndef index():\n    return \"home\"\n\nx = 1\n",
+			want:    "import os\n@app.route(\"/\")\n@login_required\ndef index():\n    ...\n",
+		},
+		{
+			name:    "multi-line signature",
+			content: "async def fetch(url,\n                timeout=10,\n                retries=3):\n    pass\n",
+			want:    "async def fetch(url,\n                timeout=10,\n                retries=3):\n    ...\n",
+		},
+		{
+			name:    "class with methods and nested function",
+			content: "class C(Base):\n    x = 1\n\n    def m(self):\n        def inner():\n            pass\n\n        return inner\n\n    @property\n    def p(self):\n        return 2\n",
+			want:    "class C(Base):\n    def m(self):\n        ...\n    @property\n    def p(self):\n        ...\n",
+		},
+		{
+			name:    "parenthesized import",
+			content: "from a import (\n    b,\n    c,\n)\n",
+			want:    "from a import (\n    b,\n    c,\n)\n",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := outlinePython(tc.content); got != tc.want {
+			t.Errorf("%s: outlinePython() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOutlineBraces(t *testing.T) {
+	cases := []struct {
+		name     string
+		language string
+		content  string
+		want     string
+	}{
+		{
+			name:     "braces in strings and comments",
+			language: "javascript",
+			content:  "function f(a) {\n  const s = \"}{\";\n  // }\n  /* { */\n  return `${a}}`;\n}\nconst x = 1;\n",
+			want:     "function f(a) { ... }\nconst x = 1;",
+		},
+		{
+			name:     "class bodies kept",
+			language: "java",
+			content:  "public class A {\n  private int x;\n  public void set(int y) {\n    if (y > 0) { x = y; }\n  }\n}\n",
+			want:     "public class A {\n  private int x;\n  public void set(int y) { ... }\n}",
+		},
+		{
+			name:     "multi-line signature",
+			language: "typescript",
+			content:  "export function build(\n  name: string,\n  opts: Options,\n): Result {\n  return make(name, opts);\n}\n",
+			want:     "export function build(\n  name: string,\n  opts: Options,\n): Result { ... }",
+		},
+		{
+			name:     "struct literal in body",
+			language: "rust",
+			content:  "impl S {\n    fn new() -> S {\n        S { a: 1 }\n    }\n}\n",
+			want:     "impl S {\n    fn new() -> S { ... }\n}",
+		},
+	}
+
+	for _, tc := range cases {
+		got, ok := outlineBraces(tc.content, tc.language)
+		if !ok {
+			t.Errorf("%s: outlineBraces() reported unsupported", tc.name)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: outlineBraces() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package plain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/processor/comments"
+	"github.com/dwrtz/sink/internal/processor/linenumbers"
+)
+
+type Config struct {
+	LineNumbers   bool
+	StripComments bool
+}
+
+// Generator renders files as plain text, each preceded by a header line with
+// its path and without any markup around the content
+type Generator struct {
+	config Config
+}
+
+func NewGenerator(config Config) *Generator {
+	return &Generator{config: config}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	var content strings.Builder
+
+	for i, file := range files {
+		if i > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString(fmt.Sprintf("==> %s <==\n", file.Path))
+
+		text := file.Content
+		if g.config.StripComments {
+			text = comments.StripComments(text, file.Language)
+		}
+		if g.config.LineNumbers {
+			text = linenumbers.AddLineNumbers(text)
+		}
+		content.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			content.WriteString("\n")
+		}
+	}
+
+	return content.String(), nil
+}
@@ -0,0 +1,156 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Placeholder replaces every detected secret
+const Placeholder = "[REDACTED]"
+
+// rule detects one kind of secret. Only the first submatch, if any, is
+// replaced, so surrounding context such as key names and quotes is kept.
+type rule struct {
+	re *regexp.Regexp
+	// Matches whose secret has lower Shannon entropy (bits per character)
+	// are left alone
+	minEntropy float64
+	// Require both letters and digits in the secret
+	mixed bool
+}
+
+// keyNames are identifiers whose assigned values are treated as secrets
+const keyNames = `(?:secret|token|passw(?:or)?d|pwd|api[_-]?key|access[_-]?key|auth[_-]?key|private[_-]?key|credentials?)`
+
+var rules = []rule{
+	// Private key blocks
+	{re: regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----\s*(.+?)\s*-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)},
+	// Well-known token formats
+	{re: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{re: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{re: regexp.MustCompile(`\b(xox[abeoprs]-[A-Za-z0-9-]{10,})`)},
+	{re: regexp.MustCompile(`\b(sk-[A-Za-z0-9_-]{20,})`)},
+	{re: regexp.MustCompile(`\b([sr]k_(?:live|test)_[A-Za-z0-9]{16,})\b`)},
+	{re: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})`)},
+	{re: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`)},
+	{re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{20,})`), minEntropy: 3},
+	// Passwords in connection strings
+	{re: regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^/\s:@]+:([^@\s/]+)@`)},
+	// Quoted values assigned to secret-looking keys, e.g. apiKey := "..."
+	{re: regexp.MustCompile(`(?i)` + keyNames + `["']?\s*(?::=|=>|[:=])\s*["']([^"'\s]{8,})["']`), minEntropy: 3},
+	// Unquoted values, as in .env files and YAML
+	{re: regexp.MustCompile(`(?im)^[ \t]*(?:export[ \t]+)?[a-z0-9_.-]*` + keyNames + `[ \t]*[:=][ \t]*([A-Za-z0-9+/=_.~-]{8,})[ \t]*$`), minEntropy: 3},
+	// Long high-entropy string literals
+	{re: regexp.MustCompile("[\"'`]([A-Za-z0-9+/=_-]{32,})[\"'`]"), minEntropy: 4.2, mixed: true},
+}
+
+// Redactor replaces secrets found by the built-in rules and by user-defined
+// patterns
+type Redactor struct {
+	rules []rule
+}
+
+// New creates a Redactor that applies the built-in rules if builtin is set,
+// followed by the given regular expressions. A pattern with a capturing
+// group has only its first group replaced; otherwise the whole match is.
+func New(builtin bool, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	if builtin {
+		r.rules = append(r.rules, rules...)
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, rule{re: re})
+	}
+	return r, nil
+}
+
+// Redact replaces API keys, tokens, private keys and high-entropy strings in
+// content with Placeholder and returns the number of replacements
+func Redact(content string) (string, int) {
+	return (&Redactor{rules: rules}).Redact(content)
+}
+
+// Redact replaces every match of the redactor's rules in content with
+// Placeholder and returns the number of replacements
+func (r *Redactor) Redact(content string) (string, int) {
+	total := 0
+	for _, rule := range r.rules {
+		var n int
+		content, n = rule.apply(content)
+		total += n
+	}
+	return content, total
+}
+
+func (r rule) apply(content string) (string, int) {
+	matches := r.re.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, 0
+	}
+
+	group := 0
+	if r.re.NumSubexp() > 0 {
+		group = 1
+	}
+
+	var b strings.Builder
+	last, count := 0, 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 || start == end {
+			continue
+		}
+		secret := content[start:end]
+		if secret == Placeholder || entropy(secret) < r.minEntropy || (r.mixed && !isMixed(secret)) {
+			continue
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(Placeholder)
+		last = end
+		count++
+	}
+	if count == 0 {
+		return content, 0
+	}
+	b.WriteString(content[last:])
+	return b.String(), count
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	freq := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		freq[r]++
+		n++
+	}
+	var h float64
+	for _, c := range freq {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func isMixed(s string) bool {
+	var letter, digit bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
@@ -2,29 +2,154 @@ package template
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
 	"text/template"
 
+	"github.com/dwrtz/sink/internal/gitinfo"
 	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/symbols"
+	sprig "github.com/go-task/slim-sprig/v3"
 )
 
+// DefaultFileTemplate renders a file like the built-in markdown format
+const DefaultFileTemplate = `## File: {{ .Path }}
+
+- Extension: {{ .Ext }}
+- Language: {{ .Language }}
+- Size: {{ .Size }} bytes
+- Created: {{ .Created.Format "2006-01-02 15:04:05" }}
+- Modified: {{ .Modified.Format "2006-01-02 15:04:05" }}
+
+### Code
+
+` + "````{{ .Language }}\n{{ .Content }}\n````" + `
+`
+
+// DefaultDocumentTemplate puts a table of contents before the rendered files
+const DefaultDocumentTemplate = `# Table of Contents
+{{- range .Files }}
+- {{ .Path }}
+{{- end }}
+
+{{ .Body }}`
+
+// FileData is what a file template is rendered with
+type FileData struct {
+	processor.FileInfo
+	Vars map[string]string
+	Git  *gitinfo.Info
+}
+
 type Engine struct {
+	// Values available to templates as .Vars
+	Vars map[string]string
+	// Repository metadata available to templates as .Git, nil outside a
+	// git repository
+	Git *gitinfo.Info
+	// Named templates that every template can include with
+	// {{ template "name" . }}
+	Partials map[string]string
+
 	templateText string
+	// Set for engines created with NewDocumentEngine
+	fileText     string
+	documentText string
 }
 
 func NewEngine(templateText string) *Engine {
 	return &Engine{templateText: templateText}
 }
 
+// NewDocumentEngine creates an engine that renders fileText once per file
+// and documentText around the results, which it receives as .Body. Empty
+// texts fall back to DefaultFileTemplate and DefaultDocumentTemplate.
+func NewDocumentEngine(fileText, documentText string) *Engine {
+	if fileText == "" {
+		fileText = DefaultFileTemplate
+	}
+	if documentText == "" {
+		documentText = DefaultDocumentTemplate
+	}
+	return &Engine{fileText: fileText, documentText: documentText}
+}
+
+// Funcs are available to every template: the slim-sprig set of string,
+// default value, list, arithmetic and date functions, plus sink's own
+var Funcs = funcMap()
+
+// unsafeFuncs are the slim-sprig functions that read the environment or
+// reach the network. Templates can come from a repository's config, so
+// like Helm they must not see secrets in the environment.
+var unsafeFuncs = []string{"env", "expandenv", "getHostByName"}
+
+func funcMap() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	for _, name := range unsafeFuncs {
+		delete(funcs, name)
+	}
+	// symbols lists the functions, types and classes declared in a file, or
+	// nothing if its language is unsupported
+	funcs["symbols"] = func(v any) []symbols.Symbol {
+		var file processor.FileInfo
+		switch f := v.(type) {
+		case processor.FileInfo:
+			file = f
+		case FileData:
+			file = f.FileInfo
+		default:
+			return nil
+		}
+		syms, err := symbols.Extract([]byte(file.Content), file.Language)
+		if err != nil {
+			return nil
+		}
+		return syms
+	}
+	return funcs
+}
+
+// parse parses text as the template name along with the partials
+func (e *Engine) parse(name, text string) (*template.Template, error) {
+	tmpl := template.New(name).Funcs(Funcs)
+
+	partials := make([]string, 0, len(e.Partials))
+	for partial := range e.Partials {
+		// A partial must not replace the template including it
+		if partial != name {
+			partials = append(partials, partial)
+		}
+	}
+	sort.Strings(partials)
+	for _, partial := range partials {
+		if _, err := tmpl.New(partial).Parse(e.Partials[partial]); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", partial, err)
+		}
+	}
+
+	return tmpl.Parse(text)
+}
+
 func (e *Engine) Execute(files []processor.FileInfo) (string, error) {
-	tmpl, err := template.New("markdown").Parse(e.templateText)
+	if e.fileText != "" {
+		return e.executeDocument(files)
+	}
+
+	tmpl, err := e.parse("markdown", e.templateText)
 	if err != nil {
 		return "", err
 	}
 
 	data := struct {
 		Files []processor.FileInfo
+		Tree  *processor.DirNode
+		Vars  map[string]string
+		Git   *gitinfo.Info
 	}{
 		Files: files,
+		Tree:  processor.BuildTree(files),
+		Vars:  e.Vars,
+		Git:   e.Git,
 	}
 
 	var buf bytes.Buffer
@@ -34,3 +159,46 @@ func (e *Engine) Execute(files []processor.FileInfo) (string, error) {
 
 	return buf.String(), nil
 }
+
+// executeDocument renders each file with the file template and wraps the
+// results in the document template
+func (e *Engine) executeDocument(files []processor.FileInfo) (string, error) {
+	fileTmpl, err := e.parse("file", e.fileText)
+	if err != nil {
+		return "", err
+	}
+	docTmpl, err := e.parse("document", e.documentText)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	for i, file := range files {
+		if i > 0 {
+			body.WriteString("\n")
+		}
+		if err := fileTmpl.Execute(&body, FileData{FileInfo: file, Vars: e.Vars, Git: e.Git}); err != nil {
+			return "", fmt.Errorf("failed to render %s: %w", file.RelPath, err)
+		}
+	}
+
+	data := struct {
+		Files []processor.FileInfo
+		Tree  *processor.DirNode
+		Body  string
+		Vars  map[string]string
+		Git   *gitinfo.Info
+	}{
+		Files: files,
+		Tree:  processor.BuildTree(files),
+		Body:  body.String(),
+		Vars:  e.Vars,
+		Git:   e.Git,
+	}
+
+	var buf bytes.Buffer
+	if err := docTmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
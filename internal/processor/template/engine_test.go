@@ -0,0 +1,24 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+func TestUnsafeFuncs(t *testing.T) {
+	t.Setenv("SINK_TEST_SECRET", "hunter2")
+	cases := []string{
+		`{{ env "SINK_TEST_SECRET" }}`,
+		`{{ expandenv "$SINK_TEST_SECRET" }}`,
+		`{{ getHostByName "localhost" }}`,
+	}
+
+	for _, tc := range cases {
+		out, err := NewEngine(tc).Execute([]processor.FileInfo{})
+		if err == nil || !strings.Contains(err.Error(), "not defined") {
+			t.Errorf("%s: got %q, %v, want an undefined function error", tc, out, err)
+		}
+	}
+}
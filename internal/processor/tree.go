@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirNode is a directory in the tree of processed files, with statistics
+// aggregated over everything beneath it
+type DirNode struct {
+	Name      string     // Directory name, "." for the root
+	Path      string     // Slash-separated path relative to the repository root
+	Depth     int        // Nesting depth, 0 for the root
+	Dirs      []*DirNode // Subdirectories sorted by name
+	Files     []FileInfo // Files directly in this directory, in processing order
+	FileCount int        // Number of files in this directory and below
+	Size      int64      // Total size in bytes of files in this directory and below
+}
+
+// BuildTree arranges files into a directory tree rooted at the repository root
+func BuildTree(files []FileInfo) *DirNode {
+	root := &DirNode{Name: ".", Path: "."}
+	index := map[string]*DirNode{".": root}
+
+	for _, file := range files {
+		dir := filepath.ToSlash(filepath.Dir(file.RelPath))
+		node := ensureDir(root, index, dir)
+		node.Files = append(node.Files, file)
+
+		// Aggregate statistics up to the root
+		for n := node; n != nil; n = index[parentPath(n.Path)] {
+			n.FileCount++
+			n.Size += file.Size
+			if n == root {
+				break
+			}
+		}
+	}
+
+	sortDirs(root)
+	return root
+}
+
+// ensureDir returns the node for dir, creating it and any missing parents
+func ensureDir(root *DirNode, index map[string]*DirNode, dir string) *DirNode {
+	if node, ok := index[dir]; ok {
+		return node
+	}
+
+	parent := ensureDir(root, index, parentPath(dir))
+	node := &DirNode{
+		Name:  dir[strings.LastIndex(dir, "/")+1:],
+		Path:  dir,
+		Depth: parent.Depth + 1,
+	}
+	parent.Dirs = append(parent.Dirs, node)
+	index[dir] = node
+	return node
+}
+
+// parentPath returns the slash-separated parent of a relative directory path
+func parentPath(dir string) string {
+	i := strings.LastIndex(dir, "/")
+	if i < 0 {
+		return "."
+	}
+	return dir[:i]
+}
+
+func sortDirs(node *DirNode) {
+	sort.Slice(node.Dirs, func(i, j int) bool {
+		return node.Dirs[i].Name < node.Dirs[j].Name
+	})
+	for _, child := range node.Dirs {
+		sortDirs(child)
+	}
+}
+
+// Render draws the tree as an indented listing in the style of the tree
+// command, with subdirectories before files
+func (n *DirNode) Render() string {
+	var b strings.Builder
+	b.WriteString(n.Name + "\n")
+	n.render(&b, "")
+	return b.String()
+}
+
+func (n *DirNode) render(b *strings.Builder, prefix string) {
+	count := len(n.Dirs) + len(n.Files)
+	i := 0
+	entry := func(name string) string {
+		i++
+		if i == count {
+			b.WriteString(prefix + "└── " + name + "\n")
+			return prefix + "    "
+		}
+		b.WriteString(prefix + "├── " + name + "\n")
+		return prefix + "│   "
+	}
+
+	for _, dir := range n.Dirs {
+		childPrefix := entry(dir.Name + "/")
+		dir.render(b, childPrefix)
+	}
+	for _, file := range n.Files {
+		entry(filepath.Base(file.RelPath))
+	}
+}
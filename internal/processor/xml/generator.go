@@ -0,0 +1,40 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+)
+
+// Generator renders files as a compact XML document using the
+// <documents>/<document> layout recommended for Claude prompts
+type Generator struct{}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+func (g *Generator) Generate(files []processor.FileInfo) (string, error) {
+	var content strings.Builder
+
+	content.WriteString("<documents>\n")
+	for i, file := range files {
+		content.WriteString(fmt.Sprintf("<document index=\"%d\">\n", i+1))
+		content.WriteString(fmt.Sprintf("<source>%s</source>\n", escape(file.Path)))
+		// File contents are left unescaped to keep the prompt compact and readable
+		content.WriteString(fmt.Sprintf("<document_content>\n%s\n</document_content>\n", file.Content))
+		content.WriteString("</document>\n")
+	}
+	content.WriteString("</documents>\n")
+
+	return content.String(), nil
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
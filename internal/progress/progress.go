@@ -0,0 +1,232 @@
+// Package progress prints a status line on stderr while files are read and
+// processed.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Nothing is printed for runs that finish sooner than this
+	startDelay = 500 * time.Millisecond
+	// How often the line is redrawn on a terminal, and printed otherwise
+	terminalInterval = 100 * time.Millisecond
+	logInterval      = 5 * time.Second
+
+	barWidth = 20
+)
+
+// Reporter prints a periodic status line with the number of files
+// processed, bytes read and the tokens they hold, estimated until they are
+// counted. On a
+// terminal the line is redrawn in place and cleared when done; otherwise a
+// line is printed every few seconds.
+type Reporter struct {
+	out      io.Writer
+	terminal bool
+
+	found atomic.Int64
+	total atomic.Int64 // -1 until the files to process are known
+	done  atomic.Int64
+	bytes atomic.Int64
+	// Set once token counting starts
+	counting atomic.Bool
+	counted  atomic.Int64
+	tokens   atomic.Int64
+
+	mu       sync.Mutex
+	stop     chan struct{} // nil when not running
+	finished chan struct{}
+}
+
+// New creates a reporter writing to out
+func New(out *os.File) *Reporter {
+	return &Reporter{out: out, terminal: isTerminal(out)}
+}
+
+// Start resets the counts and starts reporting. It does nothing on a nil
+// Reporter, or one already running.
+func (r *Reporter) Start() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+
+	r.found.Store(0)
+	r.total.Store(-1)
+	r.done.Store(0)
+	r.bytes.Store(0)
+	r.counting.Store(false)
+	r.counted.Store(0)
+	r.tokens.Store(0)
+	r.stop = make(chan struct{})
+	r.finished = make(chan struct{})
+	go r.run(r.stop, r.finished)
+}
+
+// Found records a file selected while scanning the repository
+func (r *Reporter) Found() {
+	if r != nil {
+		r.found.Add(1)
+	}
+}
+
+// SetTotal records the number of files that will be processed
+func (r *Reporter) SetTotal(n int) {
+	if r != nil {
+		r.total.Store(int64(n))
+	}
+}
+
+// Advance records a processed file of the given size
+func (r *Reporter) Advance(size int64) {
+	if r != nil {
+		r.done.Add(1)
+		r.bytes.Add(size)
+	}
+}
+
+// CountingTokens records that counting the tokens of n files started
+func (r *Reporter) CountingTokens(n int) {
+	if r != nil {
+		r.total.Store(int64(n))
+		r.counting.Store(true)
+	}
+}
+
+// Counted records the token count of a file
+func (r *Reporter) Counted(tokens int) {
+	if r != nil {
+		r.counted.Add(1)
+		r.tokens.Add(int64(tokens))
+	}
+}
+
+// Stop stops reporting and clears the status line from a terminal. It is
+// safe to call more than once, and on a nil Reporter.
+func (r *Reporter) Stop() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.finished
+	r.stop = nil
+}
+
+func (r *Reporter) run(stop, finished chan struct{}) {
+	defer close(finished)
+
+	select {
+	case <-stop:
+		return
+	case <-time.After(startDelay):
+	}
+
+	interval := logInterval
+	if r.terminal {
+		interval = terminalInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.print()
+		select {
+		case <-stop:
+			if r.terminal {
+				fmt.Fprint(r.out, "\r\033[K")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reporter) print() {
+	line := r.line()
+	if r.terminal {
+		fmt.Fprintf(r.out, "\r\033[K%s", line)
+	} else {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+// line renders the current status
+func (r *Reporter) line() string {
+	total := r.total.Load()
+	if total < 0 {
+		return fmt.Sprintf("Scanning: %d files found", r.found.Load())
+	}
+
+	bytes := r.bytes.Load()
+	phase, done, tokens := "Processing", r.done.Load(), "~"+formatCount(EstimateTokens(bytes))
+	if r.counting.Load() {
+		phase, done, tokens = "Counting tokens", r.counted.Load(), formatCount(r.tokens.Load())
+	}
+	percent := 100
+	if total > 0 {
+		percent = int(done * 100 / total)
+	}
+
+	status := fmt.Sprintf("%s: %d/%d files (%d%%), %s read, %s tokens",
+		phase, done, total, percent, formatBytes(bytes), tokens)
+	if !r.terminal {
+		return status
+	}
+	filled := percent * barWidth / 100
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "] " + status
+}
+
+// EstimateTokens roughly estimates the tokens in size bytes of source code,
+// at about four bytes per token
+func EstimateTokens(size int64) int64 {
+	return size / 4
+}
+
+// formatBytes renders a byte count in human readable units
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
+// formatCount abbreviates large counts, e.g. 1.2M or 850k
+func formatCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%dk", n/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// urlPrefixes are the prefixes that mark a path argument as a remote
+// repository rather than a local directory
+var urlPrefixes = []string{"https://", "http://", "ssh://", "git://", "file://", "git@"}
+
+// IsURL reports whether arg refers to a remote git repository
+func IsURL(arg string) bool {
+	for _, prefix := range urlPrefixes {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone clones url into a new temporary directory and returns the directory
+// along with a function that removes it. ref selects a branch or tag instead
+// of the default branch. A depth of 0 clones the full history.
+func Clone(url, ref string, depth int) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "sink-remote-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	opts := &git.CloneOptions{
+		URL:          url,
+		Depth:        depth,
+		SingleBranch: true,
+		Tags:         git.NoTags,
+	}
+
+	if ref == "" {
+		_, err = git.PlainClone(dir, false, opts)
+	} else {
+		// Try the ref as a branch first, then as a tag
+		opts.ReferenceName = plumbing.NewBranchReferenceName(ref)
+		_, err = git.PlainClone(dir, false, opts)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) || isNoMatchingRef(err) {
+			os.RemoveAll(dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				cleanup()
+				return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			opts.ReferenceName = plumbing.NewTagReferenceName(ref)
+			_, err = git.PlainClone(dir, false, opts)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+
+	return dir, cleanup, nil
+}
+
+// isNoMatchingRef reports whether err means the requested ref does not exist
+// on the remote
+func isNoMatchingRef(err error) bool {
+	var noMatch git.NoMatchingRefSpecError
+	return err != nil && errors.As(err, &noMatch)
+}
@@ -0,0 +1,175 @@
+// Package repomap builds a compressed map of a repository: its files grouped
+// by directory with the signatures of the symbols they declare, ranked by how
+// often other files refer to those symbols and trimmed to a token budget.
+package repomap
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/symbols"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// Entry is a file in the map
+type Entry struct {
+	RelPath string
+	Symbols []symbols.Symbol
+	// How many other files refer to the file's symbols
+	Score float64
+}
+
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// Rank extracts the symbols of every file and orders the files by how many
+// other files mention the names of those symbols, most referenced first.
+// Names declared in several files, such as Config or New, count for less.
+// Files without symbols come last, in path order.
+func Rank(files []processor.FileInfo) []Entry {
+	entries := make([]Entry, len(files))
+	mentions := make(map[string]int) // Files mentioning each identifier
+	declared := make(map[string]int) // Files declaring each symbol name
+	names := make([]map[string]bool, len(files))
+	for i, file := range files {
+		entries[i].RelPath = filepath.ToSlash(file.RelPath)
+		if symbols.Supported(file.Language) {
+			// Files that fail to parse are listed without symbols
+			entries[i].Symbols, _ = symbols.Extract([]byte(file.Content), file.Language)
+		}
+
+		seen := make(map[string]bool)
+		for _, name := range identifier.FindAllString(file.Content, -1) {
+			if !seen[name] {
+				seen[name] = true
+				mentions[name]++
+			}
+		}
+
+		names[i] = make(map[string]bool)
+		for _, sym := range entries[i].Symbols {
+			// Short names such as i or id say little about what is
+			// referenced, and unexported Go names are often plain words
+			if len(sym.Name) < 3 || (file.Language == "go" && !ast.IsExported(sym.Name)) {
+				continue
+			}
+			if !names[i][sym.Name] {
+				names[i][sym.Name] = true
+				declared[sym.Name]++
+			}
+		}
+	}
+
+	for i := range entries {
+		var score float64
+		for name := range names[i] {
+			// Every declaring file mentions the name itself
+			score += float64(mentions[name]-declared[name]) / float64(declared[name])
+		}
+		entries[i].Score = score
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (len(a.Symbols) > 0) != (len(b.Symbols) > 0) {
+			return len(a.Symbols) > 0
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.RelPath < b.RelPath
+	})
+	return entries
+}
+
+// Build renders the highest-ranked entries that fit in maxTokens tokens
+// (0 = no limit), grouped by directory in path order, and reports how many
+// files were left out. Files whose symbols do not fit are listed by name.
+func Build(entries []Entry, counter *tokens.Counter, maxTokens int) (string, int, error) {
+	var kept []Entry
+	used := 0
+	dirs := make(map[string]bool)
+	omitted := 0
+	for _, entry := range entries {
+		fits := false
+		for _, candidate := range []Entry{entry, {RelPath: entry.RelPath}} {
+			block := renderEntry(candidate)
+			dir := dirOf(candidate.RelPath)
+			if !dirs[dir] {
+				block = dir + "\n" + block
+			}
+			count, err := counter.Count(block)
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to count tokens: %w", err)
+			}
+			if maxTokens > 0 && used+count > maxTokens {
+				continue
+			}
+			used += count
+			dirs[dir] = true
+			kept = append(kept, candidate)
+			fits = true
+			break
+		}
+		if !fits {
+			omitted++
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		a, b := dirOf(kept[i].RelPath), dirOf(kept[j].RelPath)
+		if a != b {
+			return a < b
+		}
+		return kept[i].RelPath < kept[j].RelPath
+	})
+
+	var b strings.Builder
+	lastDir := ""
+	for i, entry := range kept {
+		if dir := dirOf(entry.RelPath); i == 0 || dir != lastDir {
+			b.WriteString(dir + "\n")
+			lastDir = dir
+		}
+		b.WriteString(renderEntry(entry))
+	}
+	return b.String(), omitted, nil
+}
+
+// dirOf returns the directory of relPath with a trailing slash, or "./" for
+// the repository root
+func dirOf(relPath string) string {
+	dir := filepath.ToSlash(filepath.Dir(relPath))
+	if dir == "." {
+		return "./"
+	}
+	return dir + "/"
+}
+
+// renderEntry lists a file and its symbols, indenting members under the
+// types that enclose them
+func renderEntry(entry Entry) string {
+	var b strings.Builder
+	b.WriteString("  " + filepath.Base(entry.RelPath) + "\n")
+	var enclosing []symbols.Symbol
+	for _, sym := range entry.Symbols {
+		for len(enclosing) > 0 && enclosing[len(enclosing)-1].EndLine < sym.StartLine {
+			enclosing = enclosing[:len(enclosing)-1]
+		}
+		b.WriteString(strings.Repeat("  ", len(enclosing)+2) + sym.Signature + "\n")
+		if sym.EndLine > sym.StartLine && sym.BodyStart > 0 && !isFunction(sym) {
+			enclosing = append(enclosing, sym)
+		}
+	}
+	return b.String()
+}
+
+// isFunction reports whether sym is a function or method, whose body is
+// never searched for nested symbols
+func isFunction(sym symbols.Symbol) bool {
+	return sym.Kind == "function" || sym.Kind == "method"
+}
@@ -0,0 +1,117 @@
+package repomap
+
+import (
+	"testing"
+
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/symbols"
+	"github.com/dwrtz/sink/internal/tokens"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// byteLoader is a vocabulary of single bytes, so every byte is one token
+// and counting needs no download
+type byteLoader struct{}
+
+func (byteLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int, 256)
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	return ranks, nil
+}
+
+func init() { tiktoken.SetBpeLoader(byteLoader{}) }
+
+func TestRank(t *testing.T) {
+	if !symbols.Supported("go") {
+		t.Skip("symbol extraction needs cgo")
+	}
+	files := []processor.FileInfo{
+		{RelPath: "README.md", Language: "markdown", Content: "Call NewStore to get a Store.\n"},
+		{RelPath: "c/other.go", Language: "go", Content: "package c\n\nfunc Helper(s *a.Store) { a.NewStore() }\n"},
+		{RelPath: "b/use.go", Language: "go", Content: "package b\n\nfunc Run() { _ = a.NewStore() }\n"},
+		{RelPath: "a/store.go", Language: "go", Content: "package a\n\ntype Store struct{}\n\nfunc NewStore() *Store { return &Store{} }\n"},
+	}
+
+	entries := Rank(files)
+	cases := []struct {
+		relPath string
+		score   float64
+	}{
+		// Store is mentioned by two other files and NewStore by three
+		{relPath: "a/store.go", score: 5},
+		// Ties are in path order
+		{relPath: "b/use.go", score: 0},
+		{relPath: "c/other.go", score: 0},
+		// Files without symbols come last
+		{relPath: "README.md", score: 0},
+	}
+	if len(entries) != len(cases) {
+		t.Fatalf("Rank() returned %d entries, want %d", len(entries), len(cases))
+	}
+	for i, tc := range cases {
+		if entries[i].RelPath != tc.relPath || entries[i].Score != tc.score {
+			t.Errorf("Rank()[%d] = %s with score %v, want %s with score %v", i, entries[i].RelPath, entries[i].Score, tc.relPath, tc.score)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	counter, err := tokens.NewCounter("cl100k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{
+		{RelPath: "b/big.go", Symbols: []symbols.Symbol{
+			{Name: "Server", Kind: "struct", Signature: "type Server struct", StartLine: 3, EndLine: 5},
+			{Name: "Start", Kind: "method", Signature: "func (s *Server) Start() error", StartLine: 7, EndLine: 9},
+		}},
+		{RelPath: "a/small.go", Symbols: []symbols.Symbol{
+			{Name: "New", Kind: "function", Signature: "func New() *Server", StartLine: 3, EndLine: 3},
+		}},
+		{RelPath: "main.go"},
+	}
+
+	cases := []struct {
+		name        string
+		maxTokens   int
+		want        string
+		wantOmitted int
+	}{
+		{
+			name:      "no limit",
+			maxTokens: 0,
+			want:      "./\n  main.go\na/\n  small.go\n    func New() *Server\nb/\n  big.go\n    type Server struct\n    func (s *Server) Start() error\n",
+		},
+		{
+			// b/big.go's symbols take more than the budget, so it is
+			// listed by name; a/small.go's fit but main.go does not
+			name:        "symbols dropped before files",
+			maxTokens:   len("b/\n  big.go\n") + len("a/\n  small.go\n    func New() *Server\n"),
+			want:        "a/\n  small.go\n    func New() *Server\nb/\n  big.go\n",
+			wantOmitted: 1,
+		},
+		{
+			name:        "nothing fits",
+			maxTokens:   3,
+			want:        "",
+			wantOmitted: 3,
+		},
+	}
+	for _, tc := range cases {
+		got, omitted, err := Build(entries, counter, tc.maxTokens)
+		if err != nil {
+			t.Fatalf("%s: Build() error: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Build() = %q, want %q", tc.name, got, tc.want)
+		}
+		if omitted != tc.wantOmitted {
+			t.Errorf("%s: Build() omitted %d, want %d", tc.name, omitted, tc.wantOmitted)
+		}
+		if tc.maxTokens > 0 && len(got) > tc.maxTokens {
+			t.Errorf("%s: Build() used %d tokens, more than %d", tc.name, len(got), tc.maxTokens)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+)
+
+// heartbeatInterval keeps idle event streams from being closed by proxies
+const heartbeatInterval = 30 * time.Second
+
+// liveEvent is sent to /events clients after each regeneration
+type liveEvent struct {
+	Event   string    `json:"-"`
+	Time    time.Time `json:"time"`
+	Files   int       `json:"files,omitempty"`
+	Changed []string  `json:"changed"`
+	Content string    `json:"content,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// broker fans events out to connected clients
+type broker struct {
+	mu      sync.Mutex
+	clients map[chan liveEvent]bool
+}
+
+func newBroker() *broker {
+	return &broker{clients: make(map[chan liveEvent]bool)}
+}
+
+func (b *broker) subscribe() chan liveEvent {
+	ch := make(chan liveEvent, 16)
+	b.mu.Lock()
+	b.clients[ch] = true
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan liveEvent) {
+	b.mu.Lock()
+	delete(b.clients, ch)
+	b.mu.Unlock()
+}
+
+// publish sends ev to every client, dropping it for clients too slow to
+// keep up rather than blocking regenerations
+func (b *broker) publish(ev liveEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Publish pushes a regeneration to /events clients. It matches
+// watcher.Config.Publish.
+func (s *Server) Publish(docs []generator.Document, changed []string, err error) {
+	ev := liveEvent{Event: "changed", Time: time.Now(), Changed: changed}
+	if err != nil {
+		ev.Event = "error"
+		ev.Error = err.Error()
+	}
+	if len(docs) > 0 {
+		ev.Files = docs[0].Files
+		ev.Content = docs[0].Content
+	}
+	s.events.publish(ev)
+}
+
+// handleEvents streams a "changed" event after each regeneration as
+// server-sent events. The regenerated document is included only if the
+// content query parameter is "true".
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	withContent := r.URL.Query().Get("content") == "true"
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-ch:
+			if !withContent {
+				ev.Content = ""
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				s.logger.Error("failed to encode event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dwrtz/sink/internal/analyzer"
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/metrics"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// contentTypes maps output formats to response content types
+var contentTypes = map[string]string{
+	"":         "text/markdown; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+	"plain":    "text/plain; charset=utf-8",
+	"xml":      "application/xml; charset=utf-8",
+	"json":     "application/json",
+	"jsonl":    "application/x-ndjson",
+	"messages": "application/json",
+	"chunks":   "application/x-ndjson",
+}
+
+type Config struct {
+	RootPath   string
+	RepoConfig *config.Config
+	Addr       string
+	// Watch serves /events, streaming the regenerations passed to Publish
+	Watch bool
+}
+
+// Server exposes generated context and codebase statistics over HTTP
+type Server struct {
+	config  Config
+	metrics *metrics.Registry
+	events  *broker
+	logger  *slog.Logger
+	// mu serializes scans so concurrent requests don't duplicate work on
+	// shared state such as the changelog manifest
+	mu sync.Mutex
+}
+
+func New(config Config) *Server {
+	return &Server{
+		config:  config,
+		metrics: metrics.NewRegistry(),
+		events:  newBroker(),
+		logger:  logging.For("serve"),
+	}
+}
+
+// Handler returns the HTTP handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", s.handleIndex)
+	mux.HandleFunc("/prompt", s.handlePrompt)
+	mux.HandleFunc("/files", s.handleFiles)
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.Handle("/metrics", s.metrics)
+	if s.config.Watch {
+		mux.HandleFunc("/events", s.handleEvents)
+	}
+	return mux
+}
+
+// ListenAndServe serves until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    s.config.Addr,
+		Handler: s.Handler(),
+		// End open event streams on shutdown instead of waiting for them
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("serving", "root", s.config.RootPath, "url", "http://"+s.config.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+// promptRequest is the optional JSON body of POST /prompt
+type promptRequest struct {
+	// Paths relative to the root to include, out of those the filters
+	// select
+	Files  []string `json:"files"`
+	Format string   `json:"format"`
+}
+
+// handlePrompt serves the generated document. The optional format query
+// parameter overrides the configured output format. POST requests may
+// narrow the files with a promptRequest body.
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req promptRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// A response is one whole document, and serving it records no
+	// changelog
+	cfg := *s.config.RepoConfig
+	cfg.SplitTokens = 0
+	cfg.Changelog = false
+	if req.Files != nil {
+		cfg.OnlyFiles = req.Files
+	}
+	format := r.URL.Query().Get("format")
+	if req.Format != "" {
+		format = req.Format
+	}
+	if format != "" {
+		cfg.Format = format
+		cfg.Outputs = nil
+	}
+	target := cfg.OutputTargets()[0]
+	cfg.Outputs = []config.OutputTarget{target}
+	if _, err := generator.NewFormatter(target.Format, &cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	start := time.Now()
+	docs, err := generator.Generate(&cfg, s.config.RootPath)
+	count := s.observe(start, &cfg, docs, err)
+	s.mu.Unlock()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	contentType, ok := contentTypes[target.Format]
+	if !ok || len(target.TemplatePaths()) > 0 {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Sink-Files", strconv.Itoa(docs[0].Files))
+	if count >= 0 {
+		w.Header().Set("X-Sink-Tokens", strconv.Itoa(count))
+	}
+	fmt.Fprint(w, docs[0].Content)
+}
+
+// fileEntry is the JSON representation of a file in /files
+type fileEntry struct {
+	Path     string    `json:"path"`
+	Language string    `json:"language"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Tokens   int       `json:"tokens,omitempty"`
+}
+
+// handleFiles lists the files that would be included in the prompt. Token
+// counts are included when the tokens query parameter is "true".
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	files, err := generator.ProcessFiles(s.config.RepoConfig, s.config.RootPath)
+	s.mu.Unlock()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	// ProcessFiles has already counted each file's tokens
+	showTokens := r.URL.Query().Get("tokens") == "true"
+
+	entries := make([]fileEntry, 0, len(files))
+	for _, file := range files {
+		entry := fileEntry{
+			Path:     file.RelPath,
+			Language: file.Language,
+			Size:     file.Size,
+			Modified: file.Modified,
+		}
+		if showTokens {
+			entry.Tokens = file.Tokens
+		}
+		entries = append(entries, entry)
+	}
+	s.writeJSON(w, entries)
+}
+
+// extensionStats is the JSON representation of an extension in /stats
+type extensionStats struct {
+	Files  int `json:"files"`
+	Tokens int `json:"tokens,omitempty"`
+}
+
+// statsResponse is the JSON representation of /stats
+type statsResponse struct {
+	TotalFiles  int                       `json:"total_files"`
+	TotalSize   int64                     `json:"total_size"`
+	TotalTokens int                       `json:"total_tokens,omitempty"`
+	Extensions  map[string]extensionStats `json:"extensions"`
+}
+
+// handleStats serves codebase statistics. Token counts are included when
+// show-tokens is configured or the tokens query parameter is "true".
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	files, err := generator.ProcessFiles(s.config.RepoConfig, s.config.RootPath)
+	s.mu.Unlock()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	a := analyzer.New()
+	stats, err := a.Analyze(files)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	if s.config.RepoConfig.ShowTokens || r.URL.Query().Get("tokens") == "true" {
+		for _, file := range files {
+			a.AddTokens(stats, file.Path, file.Tokens)
+		}
+	}
+
+	resp := statsResponse{
+		TotalFiles:  stats.TotalFiles,
+		TotalSize:   stats.TotalSize,
+		TotalTokens: stats.TotalTokens,
+		Extensions:  make(map[string]extensionStats),
+	}
+	for ext, count := range stats.Extensions {
+		resp.Extensions[ext] = extensionStats{Files: count, Tokens: stats.Tokens[ext]}
+	}
+	s.writeJSON(w, resp)
+}
+
+// observe records a prompt generation in the metrics registry and returns
+// its token count, or -1 if it couldn't be counted
+func (s *Server) observe(start time.Time, cfg *config.Config, docs []generator.Document, err error) int {
+	g := metrics.Generation{Duration: time.Since(start), Tokens: -1, Err: err}
+	if err == nil && len(docs) > 0 {
+		g.Files = docs[0].Files
+		if counter, cerr := tokens.NewCounter(cfg.TokenEncoding); cerr == nil {
+			if count, cerr := counter.Count(docs[0].Content); cerr == nil {
+				g.Tokens = count
+			}
+		}
+	}
+	s.metrics.Observe(g)
+	return g.Tokens
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Error("failed to write response", "error", err)
+	}
+}
+
+func (s *Server) fail(w http.ResponseWriter, err error) {
+	s.logger.Error("request failed", "error", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package symbols
+
+import "fmt"
+
+// Supported reports whether Extract handles language. Tree-sitter grammars
+// need cgo, so builds without it support no languages.
+func Supported(language string) bool {
+	return false
+}
+
+// Extract returns the symbols declared in content, in source order
+func Extract(content []byte, language string) ([]Symbol, error) {
+	return nil, fmt.Errorf("symbol extraction requires a build with cgo enabled")
+}
@@ -0,0 +1,35 @@
+// Package symbols extracts the functions, methods, types and classes declared
+// in source files using tree-sitter grammars.
+package symbols
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Symbol is a declaration found in a file
+type Symbol struct {
+	Name string
+	// function, method, type, struct, interface, class, enum or record
+	Kind string
+	// Enclosing class, or receiver type of a Go method
+	Parent string
+	// Declaration up to its body, on one line
+	Signature string
+	// 1-based lines spanned by the declaration
+	StartLine int
+	EndLine   int
+	// Byte offsets of the body, both 0 for declarations without one
+	BodyStart int
+	BodyEnd   int
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// signature collapses a declaration header onto one line and drops the
+// opening brace or colon of its body, or a terminating semicolon
+func signature(header string) string {
+	s := whitespace.ReplaceAllString(strings.TrimSpace(header), " ")
+	s = strings.TrimRight(s, "{:;")
+	return strings.TrimSpace(s)
+}
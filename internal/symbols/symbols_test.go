@@ -0,0 +1,63 @@
+//go:build cgo
+
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	cases := []struct {
+		name     string
+		language string
+		content  string
+		want     []string // Kind, Parent and Signature of each symbol
+	}{
+		{
+			name:     "go",
+			language: "go",
+			content:  "package x\n\ntype Server struct {\n\taddr string\n}\n\nfunc (s *Server) Start(ctx context.Context,\n\tport int) error {\n\treturn nil\n}\n\nfunc New() *Server { return nil }\n",
+			want: []string{
+				"struct  type Server struct",
+				"method Server func (s *Server) Start(ctx context.Context, port int) error",
+				"function  func New() *Server",
+			},
+		},
+		{
+			name:     "python",
+			language: "python",
+			content:  "class Cache(Base):\n    def get(self, key):\n        def helper():\n            pass\n        return None\n\nasync def fetch(url: str) -> bytes:\n    return b''\n",
+			want: []string{
+				"class  class Cache(Base)",
+				"method Cache def get(self, key)",
+				"function  async def fetch(url: str) -> bytes",
+			},
+		},
+		{
+			name:     "java",
+			language: "java",
+			content:  "public class Greeter implements Runnable {\n  public Greeter() {}\n  @Override\n  public void run() { System.out.println(\"{\"); }\n}\n",
+			want: []string{
+				"class  public class Greeter implements Runnable",
+				"method Greeter public Greeter()",
+				"method Greeter @Override public void run()",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		syms, err := Extract([]byte(tc.content), tc.language)
+		if err != nil {
+			t.Errorf("%s: Extract() error = %v", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, s := range syms {
+			got = append(got, s.Kind+" "+s.Parent+" "+s.Signature)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Extract() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
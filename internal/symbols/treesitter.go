@@ -0,0 +1,172 @@
+//go:build cgo
+
+package symbols
+
+import (
+	"fmt"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	golang "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// grammar maps the declaration node kinds of a language to symbol kinds
+type grammar struct {
+	language *sitter.Language
+	// Nodes declaring functions and methods; their bodies are not searched
+	functions map[string]string
+	// Nodes declaring types; their bodies are searched for members
+	types map[string]string
+}
+
+var grammars = map[string]grammar{
+	"go": {
+		language: sitter.NewLanguage(golang.Language()),
+		functions: map[string]string{
+			"function_declaration": "function",
+			"method_declaration":   "method",
+		},
+		types: map[string]string{
+			"type_spec": "type",
+		},
+	},
+	"python": {
+		language: sitter.NewLanguage(python.Language()),
+		functions: map[string]string{
+			"function_definition": "function",
+		},
+		types: map[string]string{
+			"class_definition": "class",
+		},
+	},
+	"java": {
+		language: sitter.NewLanguage(java.Language()),
+		functions: map[string]string{
+			"method_declaration":      "method",
+			"constructor_declaration": "method",
+		},
+		types: map[string]string{
+			"class_declaration":     "class",
+			"interface_declaration": "interface",
+			"enum_declaration":      "enum",
+			"record_declaration":    "record",
+		},
+	},
+}
+
+// Supported reports whether Extract handles language
+func Supported(language string) bool {
+	_, ok := grammars[language]
+	return ok
+}
+
+// Extract returns the symbols declared in content, in source order
+func Extract(content []byte, language string) ([]Symbol, error) {
+	g, ok := grammars[language]
+	if !ok {
+		return nil, fmt.Errorf("no grammar for language %s", language)
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(g.language); err != nil {
+		return nil, fmt.Errorf("failed to load %s grammar: %w", language, err)
+	}
+	tree := parser.Parse(content, nil)
+	if tree == nil {
+		return nil, fmt.Errorf("failed to parse %s source", language)
+	}
+	defer tree.Close()
+
+	e := &extractor{grammar: g, src: content}
+	e.walk(tree.RootNode(), "")
+	return e.symbols, nil
+}
+
+type extractor struct {
+	grammar
+	src     []byte
+	symbols []Symbol
+}
+
+func (e *extractor) walk(node *sitter.Node, parent string) {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child := node.NamedChild(i)
+		if kind, ok := e.functions[child.Kind()]; ok {
+			sym := e.symbol(child, kind, parent)
+			if sym.Kind == "function" && parent != "" {
+				sym.Kind = "method"
+			}
+			if receiver := child.ChildByFieldName("receiver"); receiver != nil {
+				sym.Parent = receiverType(receiver.Utf8Text(e.src))
+			}
+			e.symbols = append(e.symbols, sym)
+			continue
+		}
+		if kind, ok := e.types[child.Kind()]; ok {
+			sym := e.symbol(child, kind, parent)
+			if typ := child.ChildByFieldName("type"); typ != nil && kind == "type" {
+				// Go type specs: report structs and interfaces as such
+				switch typ.Kind() {
+				case "struct_type":
+					sym.Kind = "struct"
+				case "interface_type":
+					sym.Kind = "interface"
+				}
+				sym.Signature = "type " + signature(firstLine(child.Utf8Text(e.src)))
+			}
+			e.symbols = append(e.symbols, sym)
+			e.walk(child, sym.Name)
+			continue
+		}
+		e.walk(child, parent)
+	}
+}
+
+// symbol describes a declaration node
+func (e *extractor) symbol(node *sitter.Node, kind, parent string) Symbol {
+	sym := Symbol{
+		Kind:      kind,
+		Parent:    parent,
+		StartLine: int(node.StartPosition().Row) + 1,
+		EndLine:   int(node.EndPosition().Row) + 1,
+	}
+	if name := node.ChildByFieldName("name"); name != nil {
+		sym.Name = name.Utf8Text(e.src)
+	}
+
+	text := node.Utf8Text(e.src)
+	if body := node.ChildByFieldName("body"); body != nil {
+		sym.BodyStart, sym.BodyEnd = int(body.StartByte()), int(body.EndByte())
+		text = string(e.src[node.StartByte():body.StartByte()])
+	}
+	if _, ok := e.types[node.Kind()]; ok {
+		// Types are summarized by their first line
+		text = firstLine(text)
+	}
+	sym.Signature = signature(text)
+	return sym
+}
+
+// receiverType returns the type name of a Go method receiver such as
+// "(s *Server)"
+func receiverType(receiver string) string {
+	fields := strings.Fields(strings.Trim(receiver, "()"))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.TrimLeft(fields[len(fields)-1], "*")
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package tokens
+
+import "fmt"
+
+// chatMessages is the number of messages a generated prompt is wrapped in
+// when sent to a chat API: a system message and a user message
+const chatMessages = 2
+
+// chatOverheads holds approximate per-message and per-request token
+// overheads of each provider's chat format (role markers, separators and
+// reply priming)
+var chatOverheads = map[string]struct {
+	perMessage int
+	perRequest int
+}{
+	"openai":    {perMessage: 3, perRequest: 3},
+	"anthropic": {perMessage: 4, perRequest: 3},
+	"google":    {perMessage: 4, perRequest: 0},
+	"mistral":   {perMessage: 4, perRequest: 1},
+	"cohere":    {perMessage: 4, perRequest: 2},
+	"ollama":    {perMessage: 5, perRequest: 5},
+}
+
+// ChatOverhead returns the tokens a provider's chat format adds on top of
+// the message contents for a system+user request
+func ChatOverhead(provider string) (int, error) {
+	overhead, ok := chatOverheads[provider]
+	if !ok {
+		return 0, fmt.Errorf("unsupported provider for chat format: %s", provider)
+	}
+	return chatMessages*overhead.perMessage + overhead.perRequest, nil
+}
@@ -3,6 +3,8 @@ package tokens
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/pkoukk/tiktoken-go"
 )
@@ -10,12 +12,13 @@ import (
 // Counter handles token counting operations
 type Counter struct {
 	encoding string
+	tkm      *tiktoken.Tiktoken
 }
 
 // NewCounter creates a new token counter with the specified encoding
 func NewCounter(encoding string) (*Counter, error) {
 	// Validate encoding
-	if !isValidEncoding(encoding) {
+	if !IsValidEncoding(encoding) {
 		return nil, fmt.Errorf("invalid encoding: %s", encoding)
 	}
 
@@ -24,17 +27,54 @@ func NewCounter(encoding string) (*Counter, error) {
 	}, nil
 }
 
+// encoder returns the tiktoken encoder, loading it on first use
+func (c *Counter) encoder() (*tiktoken.Tiktoken, error) {
+	if c.tkm != nil {
+		return c.tkm, nil
+	}
+
+	tkm, err := tiktoken.GetEncoding(c.encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding: %w", err)
+	}
+	c.tkm = tkm
+	return tkm, nil
+}
+
 // Count returns the number of tokens in the given text
 func (c *Counter) Count(text string) (int, error) {
-	tkm, err := tiktoken.GetEncoding(c.encoding)
+	tkm, err := c.encoder()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get encoding: %w", err)
+		return 0, err
 	}
 
 	tokens := tkm.Encode(text, nil, nil)
 	return len(tokens), nil
 }
 
+// Truncate shortens text to at most maxTokens tokens, cutting at the last
+// complete line that fits
+func (c *Counter) Truncate(text string, maxTokens int) (string, error) {
+	tkm, err := c.encoder()
+	if err != nil {
+		return "", err
+	}
+
+	tokens := tkm.Encode(text, nil, nil)
+	if len(tokens) <= maxTokens {
+		return text, nil
+	}
+	if maxTokens <= 0 {
+		return "", nil
+	}
+
+	truncated := tkm.Decode(tokens[:maxTokens])
+	if i := strings.LastIndex(truncated, "\n"); i >= 0 {
+		truncated = truncated[:i+1]
+	}
+	return truncated, nil
+}
+
 // CountFiles counts tokens in multiple files and returns the total
 func (c *Counter) CountFiles(paths []string) (int, error) {
 	total := 0
@@ -52,34 +92,64 @@ func (c *Counter) CountFiles(paths []string) (int, error) {
 	return total, nil
 }
 
-// isValidEncoding checks if the encoding is supported
-func isValidEncoding(encoding string) bool {
+// IsValidEncoding checks if the encoding is supported
+func IsValidEncoding(encoding string) bool {
 	validEncodings := map[string]bool{
+		"o200k_base":  true,
 		"cl100k_base": true,
 		"p50k_base":   true,
+		"p50k_edit":   true,
 		"r50k_base":   true,
 	}
 	return validEncodings[encoding]
 }
 
-// EstimatePrice calculates the estimated price for the given number of tokens
-func (c *Counter) EstimatePrice(inputTokens, outputTokens int, model string) (float64, error) {
-	prices := map[string]struct {
-		input  float64
-		output float64
-	}{
-		"gpt-3.5-turbo": {input: 0.0015, output: 0.002},
-		"gpt-4":         {input: 0.03, output: 0.06},
-		"gpt-4-32k":     {input: 0.06, output: 0.12},
-	}
+// modelPrefixEncodings covers model families newer than tiktoken-go's own
+// table. Longer prefixes are matched first.
+var modelPrefixEncodings = map[string]string{
+	"gpt-5":      "o200k_base",
+	"gpt-4.1":    "o200k_base",
+	"gpt-4.5":    "o200k_base",
+	"gpt-4o":     "o200k_base",
+	"chatgpt-4o": "o200k_base",
+	"o1":         "o200k_base",
+	"o3":         "o200k_base",
+	"o4":         "o200k_base",
+	"gpt-4":      "cl100k_base",
+	"gpt-3.5":    "cl100k_base",
+	// Llama 3's tokenizer extends cl100k_base's vocabulary, so cl100k_base
+	// counts are a close upper bound
+	"llama3": "cl100k_base",
+}
 
-	modelPrices, ok := prices[model]
-	if !ok {
-		return 0, fmt.Errorf("unsupported model: %s", model)
+// EncodingForModel returns the encoding used by an OpenAI model, such as
+// o200k_base for gpt-4o, or the closest one for a local Llama 3 model. Dated
+// variants like gpt-4o-2024-08-06 resolve like their base model.
+func EncodingForModel(model string) (string, error) {
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[model]; ok && IsValidEncoding(encoding) {
+		return encoding, nil
 	}
 
-	inputCost := float64(inputTokens) * modelPrices.input / 1000
-	outputCost := float64(outputTokens) * modelPrices.output / 1000
-
-	return inputCost + outputCost, nil
+	prefixes := make([]string, 0, len(modelPrefixEncodings)+len(tiktoken.MODEL_PREFIX_TO_ENCODING))
+	encodings := make(map[string]string)
+	for _, table := range []map[string]string{tiktoken.MODEL_PREFIX_TO_ENCODING, modelPrefixEncodings} {
+		for prefix, encoding := range table {
+			if _, ok := encodings[prefix]; !ok {
+				prefixes = append(prefixes, prefix)
+			}
+			encodings[prefix] = encoding
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return encodings[prefix], nil
+		}
+	}
+	return "", fmt.Errorf("no known encoding for model %s", model)
 }
@@ -0,0 +1,39 @@
+package tokens
+
+import "testing"
+
+func TestEncodingForModel(t *testing.T) {
+	cases := []struct {
+		model   string
+		want    string
+		wantErr bool
+	}{
+		{model: "gpt-4o", want: "o200k_base"},
+		{model: "gpt-4o-2024-08-06", want: "o200k_base"},
+		{model: "gpt-4o-mini-2024-07-18", want: "o200k_base"},
+		{model: "gpt-4", want: "cl100k_base"},
+		{model: "gpt-4-0613", want: "cl100k_base"},
+		{model: "gpt-4.1-2025-04-14", want: "o200k_base"},
+		{model: "gpt-3.5-turbo-0125", want: "cl100k_base"},
+		{model: "o3-mini", want: "o200k_base"},
+		{model: "llama3.1:8b", want: "cl100k_base"},
+		{model: "claude-3-5-sonnet", wantErr: true},
+		{model: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := EncodingForModel(tc.model)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("EncodingForModel(%q) = %q, want an error", tc.model, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("EncodingForModel(%q) error: %v", tc.model, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", tc.model, got, tc.want)
+		}
+	}
+}
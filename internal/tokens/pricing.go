@@ -0,0 +1,133 @@
+package tokens
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed pricing.yaml
+var defaultPricing []byte
+
+// fetchTimeout bounds how long fetching a pricing catalog from a URL may take
+const fetchTimeout = 10 * time.Second
+
+// Price holds the rates of a model in USD per million tokens
+type Price struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+	// Context window in tokens, 0 if unknown
+	Context int `yaml:"context,omitempty"`
+	// Other names of the model, such as dated snapshots
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// Pricing maps providers to the prices of their models
+type Pricing map[string]map[string]Price
+
+// LoadPricing returns the built-in pricing catalog, with the entries of the
+// catalog at source, a file path or http(s) URL, taking precedence
+func LoadPricing(source string) (Pricing, error) {
+	var pricing Pricing
+	if err := yaml.Unmarshal(defaultPricing, &pricing); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in pricing: %w", err)
+	}
+	if source == "" {
+		return pricing, nil
+	}
+
+	data, err := readPricing(source)
+	if err != nil {
+		return nil, err
+	}
+	var overrides Pricing
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing %s: %w", source, err)
+	}
+	for provider, models := range overrides {
+		if pricing[provider] == nil {
+			pricing[provider] = make(map[string]Price)
+		}
+		for model, price := range models {
+			// Entries that only update rates keep the built-in context
+			// window and aliases
+			if existing, ok := pricing[provider][model]; ok {
+				if price.Context == 0 {
+					price.Context = existing.Context
+				}
+				if len(price.Aliases) == 0 {
+					price.Aliases = existing.Aliases
+				}
+			}
+			pricing[provider][model] = price
+		}
+	}
+	return pricing, nil
+}
+
+// readPricing reads a pricing catalog from a file or URL
+func readPricing(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pricing: %w", err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch pricing: %s returned %s", source, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing: %w", err)
+	}
+	return data, nil
+}
+
+// Lookup returns the price of a model by name or alias
+func (p Pricing) Lookup(provider, model string) (Price, bool) {
+	if price, ok := p[provider][model]; ok {
+		return price, true
+	}
+	for _, price := range p[provider] {
+		for _, alias := range price.Aliases {
+			if alias == model {
+				return price, true
+			}
+		}
+	}
+	return Price{}, false
+}
+
+// Has reports whether the catalog lists model for provider, by name or alias
+func (p Pricing) Has(provider, model string) bool {
+	_, ok := p.Lookup(provider, model)
+	return ok
+}
+
+// Estimate calculates the price in USD of sending inputTokens to a model and
+// receiving outputTokens back
+func (p Pricing) Estimate(provider, model string, inputTokens, outputTokens int) (float64, error) {
+	price, ok := p.Lookup(provider, model)
+	if !ok {
+		return 0, fmt.Errorf("unsupported model %s for provider %s", model, provider)
+	}
+
+	inputCost := float64(inputTokens) * price.Input / 1_000_000
+	outputCost := float64(outputTokens) * price.Output / 1_000_000
+
+	return inputCost + outputCost, nil
+}
@@ -0,0 +1,90 @@
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	pricing, err := LoadPricing("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		provider    string
+		model       string
+		want        bool
+		wantContext int
+	}{
+		{provider: "openai", model: "gpt-4o", want: true, wantContext: 128000},
+		{provider: "openai", model: "gpt-4o-2024-08-06", want: true, wantContext: 128000},
+		{provider: "openai", model: "gpt-4", want: true, wantContext: 8192},
+		{provider: "anthropic", model: "claude-3.5-sonnet", want: true, wantContext: 200000},
+		{provider: "ollama", model: "llama3:8b", want: true, wantContext: 8192},
+		{provider: "openai", model: "gpt-4o-2099-01-01", want: false},
+		{provider: "anthropic", model: "gpt-4o", want: false},
+		{provider: "nobody", model: "gpt-4o", want: false},
+	}
+	for _, tc := range cases {
+		price, ok := pricing.Lookup(tc.provider, tc.model)
+		if ok != tc.want {
+			t.Errorf("Lookup(%s, %s) found = %t, want %t", tc.provider, tc.model, ok, tc.want)
+			continue
+		}
+		if ok && price.Context != tc.wantContext {
+			t.Errorf("Lookup(%s, %s) context = %d, want %d", tc.provider, tc.model, price.Context, tc.wantContext)
+		}
+	}
+
+	if _, err := pricing.Estimate("openai", "gpt-9", 1000, 0); err == nil {
+		t.Error("Estimate() of an unknown model did not fail")
+	}
+}
+
+func TestLoadPricingOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	overrides := "openai:\n  gpt-4o: {input: 1.00, output: 4.00}\n  gpt-4: {input: 20.00, output: 40.00, context: 16384, aliases: [gpt-4-next]}\nmistral:\n  my-finetune: {input: 2.00, output: 6.00}\n"
+	if err := os.WriteFile(path, []byte(overrides), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pricing, err := LoadPricing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		provider    string
+		model       string
+		wantInput   float64
+		wantContext int
+	}{
+		// Rates only: the built-in context and aliases are kept
+		{provider: "openai", model: "gpt-4o", wantInput: 1.00, wantContext: 128000},
+		{provider: "openai", model: "gpt-4o-2024-08-06", wantInput: 1.00, wantContext: 128000},
+		// A full entry replaces them
+		{provider: "openai", model: "gpt-4", wantInput: 20.00, wantContext: 16384},
+		{provider: "openai", model: "gpt-4-next", wantInput: 20.00, wantContext: 16384},
+		// New models are added and the rest stay built-in
+		{provider: "mistral", model: "my-finetune", wantInput: 2.00},
+		{provider: "mistral", model: "codestral", wantInput: 0.30, wantContext: 262144},
+	}
+	for _, tc := range cases {
+		price, ok := pricing.Lookup(tc.provider, tc.model)
+		if !ok {
+			t.Errorf("Lookup(%s, %s) found nothing", tc.provider, tc.model)
+			continue
+		}
+		if price.Input != tc.wantInput || price.Context != tc.wantContext {
+			t.Errorf("Lookup(%s, %s) = input %v, context %d; want input %v, context %d", tc.provider, tc.model, price.Input, price.Context, tc.wantInput, tc.wantContext)
+		}
+	}
+	if pricing.Has("openai", "gpt-4-0613") {
+		t.Error("an override with aliases kept the built-in aliases")
+	}
+
+	if _, err := LoadPricing(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadPricing() of a missing file did not fail")
+	}
+}
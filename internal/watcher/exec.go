@@ -0,0 +1,93 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/hooks"
+)
+
+// recordChange remembers a changed path for the next Exec command, webhook
+// notification or Publish call
+func (s *Service) recordChange(path string) {
+	if s.config.Exec == "" && s.config.RepoConfig.NotifyWebhook == "" && s.config.Publish == nil {
+		return
+	}
+	rel, err := filepath.Rel(s.config.RootPath, path)
+	if err != nil {
+		rel = path
+	}
+	s.mu.Lock()
+	s.changed[filepath.ToSlash(rel)] = true
+	s.mu.Unlock()
+}
+
+// takeChanges returns the paths changed since the last call, sorted
+func (s *Service) takeChanges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := make([]string, 0, len(s.changed))
+	for path := range s.changed {
+		changed = append(changed, path)
+	}
+	s.changed = make(map[string]bool)
+	sort.Strings(changed)
+	return changed
+}
+
+// runExec runs the Exec command after a successful regeneration. {output}
+// and {changed} expand to the written output paths and the changed files,
+// quoted for the shell; SINK_OUTPUT and SINK_CHANGED hold the same lists
+// one path per line.
+func (s *Service) runExec(docs []generator.Document, changed []string) {
+	var outputs []string
+	for _, doc := range docs {
+		if doc.Written != "" {
+			outputs = append(outputs, doc.Written)
+		}
+	}
+
+	command := strings.NewReplacer(
+		"{output}", quoteAll(outputs),
+		"{changed}", quoteAll(changed),
+	).Replace(s.config.Exec)
+
+	cmd := hooks.Shell(command)
+	cmd.Dir = s.config.RootPath
+	cmd.Env = append(os.Environ(),
+		"SINK_OUTPUT="+strings.Join(outputs, "\n"),
+		"SINK_CHANGED="+strings.Join(changed, "\n"),
+	)
+	// Streamed documents own stdout
+	cmd.Stdout = s.stdout
+	if s.config.Stdout {
+		cmd.Stdout = os.Stderr
+	}
+	cmd.Stderr = os.Stderr
+
+	s.logger.Debug("running exec command", "command", command)
+	if err := cmd.Run(); err != nil {
+		s.logger.Error("exec command failed", "command", command, "error", err)
+	}
+}
+
+// quoteAll quotes each path for the shell and joins them with spaces
+func quoteAll(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = quote(path)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quote makes path a single shell word
+func quote(path string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + path + `"`
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
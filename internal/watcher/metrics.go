@@ -0,0 +1,52 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/metrics"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// serveMetrics exposes /metrics until ctx is cancelled
+func (s *Service) serveMetrics(ctx context.Context) {
+	s.logger.Info("serving metrics", "url", s.config.MetricsAddr+"/metrics")
+	if err := s.metrics.ListenAndServe(ctx, s.config.MetricsAddr); err != nil {
+		s.logger.Error("metrics server stopped", "error", err)
+	}
+}
+
+// observe records the outcome of a regeneration started at start
+func (s *Service) observe(start time.Time, docs []generator.Document, tokens int, err error) {
+	if s.metrics == nil {
+		return
+	}
+
+	g := metrics.Generation{Duration: time.Since(start), Tokens: -1, Err: err}
+	if err == nil {
+		g.Tokens = tokens
+		for _, doc := range docs {
+			g.Files = max(g.Files, doc.Files)
+		}
+	}
+	s.metrics.Observe(g)
+}
+
+// countTokens returns the total tokens of the documents, or -1 if they
+// can't be counted
+func (s *Service) countTokens(docs []generator.Document) int {
+	counter, err := tokens.NewCounter(s.config.RepoConfig.TokenEncoding)
+	if err != nil {
+		return -1
+	}
+	total := 0
+	for _, doc := range docs {
+		count, err := counter.Count(doc.Content)
+		if err != nil {
+			return -1
+		}
+		total += count
+	}
+	return total
+}
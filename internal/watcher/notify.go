@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+)
+
+// notifyTimeout bounds each webhook request so a slow receiver can't stall
+// the next regeneration for long
+const notifyTimeout = 10 * time.Second
+
+// notification is the JSON payload POSTed to the notify webhook
+type notification struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Root       string    `json:"root"`
+	Outputs    []string  `json:"outputs"`
+	Files      int       `json:"files"`
+	Tokens     int       `json:"tokens,omitempty"`
+	Changed    []string  `json:"changed"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// notify POSTs the outcome of a regeneration to the notify webhook.
+// Failures are logged; they never fail the regeneration.
+func (s *Service) notify(start time.Time, docs []generator.Document, tokens int, changed []string, err error) {
+	n := notification{
+		Event:      "regenerated",
+		Time:       time.Now(),
+		Root:       s.config.RootPath,
+		Outputs:    outputPaths(docs),
+		Changed:    changed,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		n.Event = "error"
+		n.Error = err.Error()
+	}
+	for _, doc := range docs {
+		n.Files = max(n.Files, doc.Files)
+	}
+	if tokens > 0 {
+		n.Tokens = tokens
+	}
+
+	if err := postJSON(s.config.RepoConfig.NotifyWebhook, n); err != nil {
+		s.logger.Warn("failed to notify webhook", "error", err)
+	}
+}
+
+// postJSON sends payload to url and checks for a 2xx response
+func postJSON(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sink")
+
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dwrtz/sink/internal/generator"
+)
+
+// rememberOutputs records the files the last regeneration wrote, so that the
+// events they cause don't trigger another one
+func (s *Service) rememberOutputs(docs []generator.Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, doc := range docs {
+		if doc.Written == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(doc.Written); err == nil {
+			s.outputs[abs] = true
+		}
+	}
+}
+
+// isOwnOutput reports whether path is a file sink writes: an output
+// written earlier, a configured output path or one of its split parts, or
+// the skipped files report
+func (s *Service) isOwnOutput(path string) bool {
+	// Event paths are relative when the watched root is
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	s.mu.Lock()
+	written := s.outputs[path]
+	s.mu.Unlock()
+	if written {
+		return true
+	}
+
+	cfg := s.config.RepoConfig
+	configured := []string{cfg.Output, cfg.SkippedReport}
+	for _, target := range cfg.Outputs {
+		configured = append(configured, target.Path)
+	}
+	for _, output := range configured {
+		// Templated paths are only known once written
+		if output == "" || strings.Contains(output, "{{") {
+			continue
+		}
+		abs, err := filepath.Abs(output)
+		if err != nil {
+			continue
+		}
+		if path == abs || isPartOf(path, abs) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPartOf reports whether path is a split part of output, such as
+// output.part2.md for output.md
+func isPartOf(path, output string) bool {
+	ext := filepath.Ext(output)
+	prefix := strings.TrimSuffix(output, ext) + ".part"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, ext) {
+		return false
+	}
+	n := strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext)
+	if n == "" {
+		return false
+	}
+	for _, r := range n {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package watcher
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// fileState is what polling compares to notice a changed file
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// scan records the size and modification time of every file in the
+// directories that would be watched
+func (s *Service) scan() map[string]fileState {
+	states := make(map[string]fileState)
+	filepath.WalkDir(s.config.RootPath, func(path string, d fs.DirEntry, err error) error {
+		// Files can vanish mid-scan; they show up as removed next time
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == ".sink" || !s.shouldWatchDirectory(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		states[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return states
+}
+
+// poll compares a fresh scan with the previous one and regenerates if a
+// file that would trigger regeneration on an event was added, modified or
+// removed
+func (s *Service) poll() {
+	current := s.scan()
+	var changed []string
+	for path, state := range current {
+		old, ok := s.polled[path]
+		if !ok || old.size != state.size || !old.modTime.Equal(state.modTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s.polled {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	s.polled = current
+
+	regenerate := false
+	for _, path := range changed {
+		if path == s.configPath {
+			s.logger.Info("config file changed, reloading")
+			if err := s.handleConfigChange(); err != nil {
+				s.logger.Error("failed to reload config", "error", err)
+			}
+			continue
+		}
+		if isTemporaryFile(path) || !s.shouldProcessFile(path) {
+			continue
+		}
+		s.logger.Info("file changed", "path", path)
+		s.recordChange(path)
+		regenerate = true
+	}
+	if regenerate {
+		// Generate logs failures
+		_ = s.triggerRegeneration()
+	}
+}
@@ -2,8 +2,10 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -15,6 +17,8 @@ import (
 	"github.com/dwrtz/sink/internal/config"
 	"github.com/dwrtz/sink/internal/filter"
 	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/logging"
+	"github.com/dwrtz/sink/internal/metrics"
 	"github.com/dwrtz/sink/internal/utils"
 	"github.com/fsnotify/fsnotify"
 )
@@ -28,25 +32,77 @@ type Config struct {
 	RootPath        string
 	RepoConfig      *config.Config
 	DebounceTimeout time.Duration
+	// Stream regenerated documents to stdout as NDJSON instead of writing files
+	Stdout bool
+	// Regenerate on a timer in addition to (or, with NoEvents, instead of)
+	// file system events
+	Interval time.Duration
+	NoEvents bool
+	// Serve Prometheus metrics on this address if set
+	MetricsAddr string
+	// Profile reapplied when the config file is reloaded
+	Profile string
+	// Reload loads the configuration again when the config file changes
+	// or on SIGHUP, reapplying command line overrides; defaults to
+	// loading it with Profile
+	Reload func() (*config.Config, error)
+	// Stop after the first regeneration triggered by a change or the
+	// interval
+	Once bool
+	// Shell command run after each successful regeneration
+	Exec string
+	// Publish receives the documents of each regeneration, with the
+	// files changed since the previous one, instead of having them
+	// written to files
+	Publish func(docs []generator.Document, changed []string, err error)
+	// Scan for changed files on this interval instead of using file
+	// system events, which never arrive on some network filesystems
+	Poll time.Duration
 }
 
 type Service struct {
 	config     Config
 	watcher    *fsnotify.Watcher
 	gitignorer *filter.GitignoreFilter
-	debouncer  *time.Timer
+	attributes *filter.AttributesFilter
+	debouncer  *time.Timer // nil until the first change
 	mu         sync.Mutex
+	// genMu keeps regenerations from overlapping
+	genMu sync.Mutex
+	// cycled is closed after the first regeneration with Once
+	cycled    chan struct{}
+	cycleOnce sync.Once
+	// changed holds paths changed since the last regeneration, for Exec,
+	// the notify webhook and Publish
+	changed map[string]bool
+	// outputs holds the absolute paths of files written by regenerations
+	outputs map[string]bool
+	// status is written to .sink/watch-status.json after each
+	// regeneration; guarded by genMu
+	status Status
+	// polled holds the files seen by the last scan when polling
+	polled     map[string]fileState
 	watched    map[string]*watchedPath
 	configPath string
 	reloading  bool
 	// Add a logger for better visibility
-	logger *log.Logger
+	logger *slog.Logger
+	// stdout receives streamed documents; streamMu serializes writes to it
+	stdout   io.Writer
+	streamMu sync.Mutex
+	// metrics is nil unless a metrics address is configured
+	metrics *metrics.Registry
 }
 
 func NewService(config Config) (*Service, error) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	// Without events, the watcher is never needed and may not be available
+	var watcher *fsnotify.Watcher
+	if !config.NoEvents && config.Poll <= 0 {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return nil, watcherError(err)
+		}
 	}
 
 	gitignorer, err := filter.NewFilter(filter.GitignoreConfig{
@@ -58,6 +114,11 @@ func NewService(config Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to create gitignore filter: %w", err)
 	}
 
+	attributes, err := filter.NewAttributesFilter(config.RootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gitattributes: %w", err)
+	}
+
 	// Check for config file in root directory only
 	configPath := ""
 	defaultConfigPath := filepath.Join(config.RootPath, "sink-config.yaml")
@@ -65,17 +126,31 @@ func NewService(config Config) (*Service, error) {
 		configPath = defaultConfigPath
 	}
 
-	// Create a logger that writes to stderr with timestamps
-	logger := log.New(os.Stderr, "[watcher] ", log.LstdFlags)
+	logger := logging.For("watcher")
+
+	var registry *metrics.Registry
+	if config.MetricsAddr != "" {
+		registry = metrics.NewRegistry()
+	}
 
 	return &Service{
 		config:     config,
 		watcher:    watcher,
 		gitignorer: gitignorer,
-		debouncer:  time.NewTimer(0),
+		attributes: attributes,
+		cycled:     make(chan struct{}),
+		changed:    make(map[string]bool),
+		outputs:    make(map[string]bool),
 		watched:    make(map[string]*watchedPath),
 		configPath: configPath,
 		logger:     logger,
+		stdout:     os.Stdout,
+		metrics:    registry,
+		status: Status{
+			PID:       os.Getpid(),
+			Root:      config.RootPath,
+			StartedAt: time.Now(),
+		},
 	}, nil
 }
 
@@ -85,26 +160,61 @@ func (s *Service) Watch() error {
 	defer cancel()
 
 	// Ensure cleanup
-	defer s.watcher.Close()
+	if s.watcher != nil {
+		defer s.watcher.Close()
+	}
 
-	// Initial setup
-	if err := s.reconfigureWatcher(); err != nil {
-		return fmt.Errorf("failed to configure initial watches: %w", err)
+	if s.metrics != nil {
+		go s.serveMetrics(ctx)
 	}
 
-	// Watch config file if it exists
-	if s.configPath != "" {
-		if err := s.watcher.Add(s.configPath); err != nil {
-			return fmt.Errorf("failed to add watch for config file: %w", err)
+	if s.watcher != nil {
+		// Initial setup
+		if err := s.reconfigureWatcher(); err != nil {
+			return fmt.Errorf("failed to configure initial watches: %w", err)
+		}
+
+		// Watch config file if it exists
+		if s.configPath != "" {
+			if err := s.watcher.Add(s.configPath); err != nil {
+				return fmt.Errorf("failed to add watch for config file: %w", err)
+			}
+			s.watched[s.configPath] = &watchedPath{path: s.configPath, dir: false}
+			s.logger.Debug("watching config file", "path", s.configPath)
+		}
+
+		// Log initial watch setup
+		s.logger.Info("starting file watcher", "root", s.config.RootPath, "watches", len(s.watched))
+		for path := range s.watched {
+			s.logger.Debug("watching", "path", path)
 		}
-		s.watched[s.configPath] = &watchedPath{path: s.configPath, dir: false}
-		s.logger.Printf("Added watch for config file: %s", s.configPath)
 	}
 
-	// Log initial watch setup
-	s.logger.Printf("Starting file watcher for root path: %s", s.config.RootPath)
-	for path := range s.watched {
-		s.logger.Printf("Watching: %s", path)
+	// Regenerate periodically if an interval is configured
+	var interval <-chan time.Time
+	if s.config.Interval > 0 {
+		intervalTicker := time.NewTicker(s.config.Interval)
+		defer intervalTicker.Stop()
+		interval = intervalTicker.C
+		s.logger.Info("regenerating periodically", "interval", s.config.Interval)
+	}
+
+	// Scan for changes instead of waiting for events if polling
+	var poll <-chan time.Time
+	if s.config.Poll > 0 {
+		s.polled = s.scan()
+		pollTicker := time.NewTicker(s.config.Poll)
+		defer pollTicker.Stop()
+		poll = pollTicker.C
+		s.logger.Info("polling for changes", "root", s.config.RootPath, "interval", s.config.Poll, "files", len(s.polled))
+	}
+
+	// Reload on SIGHUP and regenerate on SIGUSR1, for process supervisors
+	// and scripts
+	signals := make(chan os.Signal, 1)
+	if reloadSignal != nil {
+		signal.Notify(signals, reloadSignal, regenerateSignal)
+		defer signal.Stop(signals)
 	}
 
 	// Start a ticker to periodically log that the watcher is still alive
@@ -112,29 +222,58 @@ func (s *Service) Watch() error {
 	defer ticker.Stop()
 
 	// Process events
-	return s.processEvents(ctx, ticker)
+	return s.processEvents(ctx, ticker, interval, poll, signals)
 }
 
-func (s *Service) processEvents(ctx context.Context, ticker *time.Ticker) error {
+func (s *Service) processEvents(ctx context.Context, ticker *time.Ticker, interval, poll <-chan time.Time, signals <-chan os.Signal) error {
+	// Nil channels never deliver, so without a watcher only the timers run
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if s.watcher != nil {
+		events, errs = s.watcher.Events, s.watcher.Errors
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Println("Watcher shutting down...")
-			return ctx.Err()
+			s.logger.Info("watcher shutting down")
+			return nil
+
+		case <-s.cycled:
+			s.logger.Info("regenerated once, stopping")
+			return nil
 
 		case <-ticker.C:
-			s.logger.Println("Watcher is running...")
+			s.logger.Debug("watcher is running")
+
+		case <-interval:
+			s.logger.Debug("interval elapsed, regenerating")
+			s.regenerate()
+
+		case <-poll:
+			s.poll()
+
+		case sig := <-signals:
+			if sig == reloadSignal {
+				s.logger.Info("received signal, reloading config", "signal", sig.String())
+				if err := s.handleConfigChange(); err != nil {
+					s.logger.Error("failed to reload config", "error", err)
+				}
+				continue
+			}
+			s.logger.Info("received signal, regenerating", "signal", sig.String())
+			s.regenerate()
 
-		case event, ok := <-s.watcher.Events:
+		case event, ok := <-events:
 			if !ok {
 				return fmt.Errorf("watcher event channel closed")
 			}
-			s.logger.Printf("Received event: %s %s", event.Op.String(), event.Name)
+			s.logger.Debug("received event", "op", event.Op.String(), "path", event.Name)
 			if err := s.handleEvent(event); err != nil {
-				s.logger.Printf("Error handling event: %v", err)
+				s.logger.Error("failed to handle event", "path", event.Name, "error", err)
 			}
 
-		case err, ok := <-s.watcher.Errors:
+		case err, ok := <-errs:
 			if !ok {
 				return fmt.Errorf("watcher error channel closed")
 			}
@@ -147,34 +286,57 @@ func (s *Service) processEvents(ctx context.Context, ticker *time.Ticker) error
 
 // shouldProcessFile determines if a file should trigger a regeneration
 func (s *Service) shouldProcessFile(path string) bool {
+	// Writing the output must not trigger another regeneration
+	if s.isOwnOutput(path) {
+		s.logger.Debug("skipping sink's own output", "path", path)
+		return false
+	}
+
 	// Skip binary files
 	if utils.IsBinaryFile(path) {
-		s.logger.Printf("Skipping binary file: %s", path)
+		s.logger.Debug("skipping binary file", "path", path)
 		return false
 	}
 
 	// Convert to relative path for pattern matching
 	relPath, err := filepath.Rel(s.config.RootPath, path)
 	if err != nil {
-		s.logger.Printf("Error getting relative path for %s: %v", path, err)
+		s.logger.Warn("failed to get relative path", "path", path, "error", err)
 		return false
 	}
 
-	// Check gitignore patterns
+	// Check gitignore patterns; removed files are matched as files
 	ignored, err := s.gitignorer.IsIgnored(relPath)
+	if errors.Is(err, os.ErrNotExist) {
+		ignored, err = s.gitignorer.Matches(relPath, false), nil
+	}
 	if err != nil {
-		s.logger.Printf("Error checking if %s is ignored: %v", relPath, err)
+		s.logger.Warn("failed to check gitignore", "path", relPath, "error", err)
 		return false
 	}
 	if ignored {
-		s.logger.Printf("File %s is ignored by gitignore patterns", relPath)
+		s.logger.Debug("file is ignored by gitignore patterns", "path", relPath)
 		return false
 	}
 
+	// Check gitattributes linguist markers
+	if !s.config.RepoConfig.IncludeGenerated && s.attributes.IsLinguistExcluded(relPath) {
+		s.logger.Debug("file is marked generated or vendored in .gitattributes", "path", relPath)
+		return false
+	}
+
+	// Check per-language test file conventions
+	if s.config.RepoConfig.NoTests || s.config.RepoConfig.TestsOnly {
+		if filter.IsTestFile(relPath) != s.config.RepoConfig.TestsOnly {
+			s.logger.Debug("file excluded by test file selection", "path", relPath)
+			return false
+		}
+	}
+
 	// Check exclude patterns
 	if len(s.config.RepoConfig.ExcludePatterns) > 0 {
 		if filter.MatchesAny(relPath, s.config.RepoConfig.ExcludePatterns, s.config.RepoConfig.CaseSensitive) {
-			s.logger.Printf("File %s matches exclude pattern", relPath)
+			s.logger.Debug("file matches exclude pattern", "path", relPath)
 			return false
 		}
 	}
@@ -182,7 +344,7 @@ func (s *Service) shouldProcessFile(path string) bool {
 	// Check filter patterns if specified
 	if len(s.config.RepoConfig.FilterPatterns) > 0 {
 		if !filter.MatchesAny(relPath, s.config.RepoConfig.FilterPatterns, s.config.RepoConfig.CaseSensitive) {
-			s.logger.Printf("File %s does not match filter patterns", relPath)
+			s.logger.Debug("file does not match filter patterns", "path", relPath)
 			return false
 		}
 	}
@@ -193,14 +355,14 @@ func (s *Service) shouldProcessFile(path string) bool {
 func (s *Service) handleEvent(event fsnotify.Event) error {
 	// Skip temporary files and editor backup files
 	if isTemporaryFile(event.Name) {
-		s.logger.Printf("Skipping temporary file: %s", event.Name)
+		s.logger.Debug("skipping temporary file", "path", event.Name)
 		return nil
 	}
 
 	// Handle config file changes separately
 	if event.Name == s.configPath && !s.reloading {
 		if event.Op&fsnotify.Write == fsnotify.Write {
-			s.logger.Println("Config file changed, reloading...")
+			s.logger.Info("config file changed, reloading", "path", event.Name)
 			return s.handleConfigChange()
 		} else {
 			// Ignore CHMOD or other events on the config file
@@ -210,25 +372,29 @@ func (s *Service) handleEvent(event fsnotify.Event) error {
 
 	// Check if we should process this file
 	if !s.shouldProcessFile(event.Name) {
-		s.logger.Printf("Skipping event for filtered file: %s", event.Name)
+		s.logger.Debug("skipping event for filtered file", "path", event.Name)
 		return nil
 	}
 
+	if event.Op&fsnotify.Chmod != event.Op {
+		s.recordChange(event.Name)
+	}
+
 	switch {
 	case event.Op&fsnotify.Create == fsnotify.Create:
-		s.logger.Printf("File created: %s", event.Name)
+		s.logger.Info("file created", "path", event.Name)
 		return s.handleCreate(event.Name)
 	case event.Op&fsnotify.Remove == fsnotify.Remove:
-		s.logger.Printf("File removed: %s", event.Name)
+		s.logger.Info("file removed", "path", event.Name)
 		return s.handleRemove(event.Name)
 	case event.Op&fsnotify.Write == fsnotify.Write:
-		s.logger.Printf("File modified: %s", event.Name)
+		s.logger.Info("file modified", "path", event.Name)
 		return s.handleModify(event.Name)
 	case event.Op&fsnotify.Rename == fsnotify.Rename:
-		s.logger.Printf("File renamed: %s", event.Name)
+		s.logger.Info("file renamed", "path", event.Name)
 		return s.handleRename(event.Name)
 	case event.Op&fsnotify.Chmod == fsnotify.Chmod:
-		s.logger.Printf("File chmod: %s (ignoring)", event.Name)
+		s.logger.Debug("ignoring chmod", "path", event.Name)
 		return nil
 	}
 
@@ -265,7 +431,7 @@ func (s *Service) handleRemove(path string) error {
 	// Remove the watch for this path
 	if err := s.watcher.Remove(path); err != nil {
 		// Log but don't fail - the path might already be gone
-		log.Printf("Error removing watch for %s: %v", path, err)
+		s.logger.Debug("failed to remove watch", "path", path, "error", err)
 	}
 	delete(s.watched, path)
 
@@ -275,7 +441,7 @@ func (s *Service) handleRemove(path string) error {
 		for watchedPath := range s.watched {
 			if strings.HasPrefix(watchedPath, prefix) {
 				if err := s.watcher.Remove(watchedPath); err != nil {
-					log.Printf("Error removing watch for %s: %v", watchedPath, err)
+					s.logger.Debug("failed to remove watch", "path", watchedPath, "error", err)
 				}
 				delete(s.watched, watchedPath)
 			}
@@ -299,13 +465,20 @@ func (s *Service) handleConfigChange() error {
 	s.mu.Lock()
 	s.reloading = true
 
-	newConfig, err := config.LoadConfig("")
+	newConfig, err := s.loadConfig()
 	if err != nil {
 		s.mu.Unlock()
 		return fmt.Errorf("error reloading config: %w", err)
 	}
 	s.config.RepoConfig = newConfig
 
+	// Polling picks up new prune and exclude patterns on its next scan
+	if s.watcher == nil {
+		s.reloading = false
+		s.mu.Unlock()
+		return s.triggerRegeneration()
+	}
+
 	if err := s.reconfigureWatcher(); err != nil {
 		s.mu.Unlock()
 		return fmt.Errorf("error reconfiguring watcher: %w", err)
@@ -325,13 +498,27 @@ func (s *Service) handleConfigChange() error {
 	return s.triggerRegeneration()
 }
 
+// loadConfig loads the configuration for a reload. Without Reload, hooks are
+// only taken from the system and user config files.
+func (s *Service) loadConfig() (*config.Config, error) {
+	if s.config.Reload != nil {
+		return s.config.Reload()
+	}
+	return config.LoadConfig("", s.config.Profile, false)
+}
+
 func (s *Service) handleWatchError(err error) error {
 	// Determine if the error is critical
 	if isCriticalError(err) {
 		return err
 	}
+	// Changes may have been missed, so regenerate to be safe
+	if errors.Is(err, fsnotify.ErrEventOverflow) {
+		s.logger.Warn("file system events were dropped, regenerating", "error", err)
+		return s.triggerRegeneration()
+	}
 	// Log non-critical errors
-	log.Printf("Watch error: %v", err)
+	s.logger.Warn("watch error", "error", err)
 	return nil
 }
 
@@ -355,7 +542,7 @@ func (s *Service) addWatchRecursive(root string) error {
 		}
 
 		if info.IsDir() {
-			if info.Name() == ".git" {
+			if info.Name() == ".git" || info.Name() == ".sink" {
 				return filepath.SkipDir
 			}
 
@@ -364,6 +551,9 @@ func (s *Service) addWatchRecursive(root string) error {
 			}
 
 			if err := s.watcher.Add(path); err != nil {
+				if errors.Is(err, syscall.ENOSPC) {
+					return fmt.Errorf("inotify watch limit reached after %d directories; raise it with: sudo sysctl fs.inotify.max_user_watches=524288, or regenerate on a timer with --interval and --no-events: %w", len(s.watched), err)
+				}
 				return fmt.Errorf("failed to add watch for %s: %w", path, err)
 			}
 			s.watched[path] = &watchedPath{path: path, dir: true}
@@ -375,42 +565,107 @@ func (s *Service) addWatchRecursive(root string) error {
 
 func (s *Service) triggerRegeneration() error {
 	s.mu.Lock()
-	s.logger.Println("Triggering regeneration...")
+	defer s.mu.Unlock()
+	s.logger.Debug("triggering regeneration")
+
+	// Each change pushes the regeneration back by the debounce duration
+	if s.debouncer == nil {
+		s.debouncer = time.AfterFunc(s.config.DebounceTimeout, func() {
+			s.logger.Debug("debounce timeout reached, regenerating")
+			s.regenerate()
+		})
+		return nil
+	}
+	s.debouncer.Reset(s.config.DebounceTimeout)
+	return nil
+}
 
-	// Stop the timer first
-	if !s.debouncer.Stop() {
-		// Timer already fired, drain the channel
-		select {
-		case <-s.debouncer.C:
-		default:
-		}
+// regenerate runs a regeneration triggered by a change or the interval, and
+// with Once, signals that the single cycle is done
+func (s *Service) regenerate() {
+	// Generate logs failures
+	_ = s.Generate()
+	if s.config.Once {
+		s.cycleOnce.Do(func() { close(s.cycled) })
 	}
+}
 
-	// Now reset the timer to the configured debounce duration
-	s.debouncer.Reset(s.config.DebounceTimeout)
-	s.mu.Unlock()
+func (s *Service) Generate() error {
+	s.genMu.Lock()
+	defer s.genMu.Unlock()
+
+	start := time.Now()
+	changed := s.takeChanges()
+	docs, err := s.generate()
+	tokens := -1
+	if err == nil {
+		tokens = s.countTokens(docs)
+	}
+	s.observe(start, docs, tokens, err)
+	s.updateStatus(start, docs, tokens, err)
+	s.logGeneration(start, docs, err)
+	s.rememberOutputs(docs)
+	if err == nil && s.config.Exec != "" {
+		s.runExec(docs, changed)
+	}
+	if s.config.RepoConfig.NotifyWebhook != "" {
+		s.notify(start, docs, tokens, changed, err)
+	}
+	if s.config.Publish != nil {
+		s.config.Publish(docs, changed, err)
+	}
+	return err
+}
+
+// logGeneration logs the outcome and duration of a regeneration
+func (s *Service) logGeneration(start time.Time, docs []generator.Document, err error) {
+	duration := time.Since(start).Milliseconds()
+	if err != nil {
+		s.logger.Error("generation failed", "duration_ms", duration, "error", err)
+		return
+	}
 
-	// Spawn a goroutine to wait for the debounce to expire and then regenerate
-	go func() {
-		<-s.debouncer.C
-		s.logger.Println("Debounce timeout reached, regenerating...")
-		if err := s.Generate(); err != nil {
-			s.logger.Printf("Failed to regenerate: %v", err)
+	files := 0
+	for _, doc := range docs {
+		files = max(files, doc.Files)
+	}
+	s.logger.Info("generated", "duration_ms", duration, "files", files, "outputs", outputPaths(docs))
+}
+
+// outputPaths returns where each document went: the written path, or the
+// target path if it wasn't written to a file
+func outputPaths(docs []generator.Document) []string {
+	outputs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		output := doc.Target.Path
+		if doc.Written != "" {
+			output = doc.Written
 		}
-	}()
-	return nil
+		outputs = append(outputs, output)
+	}
+	return outputs
 }
 
-func (s *Service) Generate() error {
-	fmt.Println("Generating...")
-	return generator.RunGeneration(s.config.RepoConfig, s.config.RootPath)
+func (s *Service) generate() ([]generator.Document, error) {
+	if s.config.Stdout {
+		return s.streamDocuments()
+	}
+	s.logger.Debug("generating")
+	docs, err := generator.Generate(s.config.RepoConfig, s.config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.Publish != nil {
+		return docs, nil
+	}
+	return docs, generator.WriteDocuments(docs, s.config.RepoConfig, s.config.RootPath)
 }
 
 func (s *Service) shouldWatchDirectory(path string) bool {
 	// Convert absolute path to relative path from repo root
 	relPath, err := filepath.Rel(s.config.RootPath, path)
 	if err != nil {
-		s.logger.Printf("Error getting relative path for %s: %v", path, err)
+		s.logger.Warn("failed to get relative path", "path", path, "error", err)
 		return false
 	}
 
@@ -419,19 +674,26 @@ func (s *Service) shouldWatchDirectory(path string) bool {
 		return true
 	}
 
+	if len(s.config.RepoConfig.PrunePatterns) > 0 {
+		if filter.MatchesAny(relPath, s.config.RepoConfig.PrunePatterns, s.config.RepoConfig.CaseSensitive) {
+			s.logger.Debug("directory matches prune pattern", "path", relPath)
+			return false
+		}
+	}
+
 	ignored, err := s.gitignorer.IsIgnored(relPath)
 	if err != nil {
-		s.logger.Printf("Error checking if %s is ignored: %v", relPath, err)
+		s.logger.Warn("failed to check gitignore", "path", relPath, "error", err)
 		return false
 	}
 	if ignored {
-		s.logger.Printf("Directory %s is ignored by gitignore", relPath)
+		s.logger.Debug("directory is ignored by gitignore", "path", relPath)
 		return false
 	}
 
 	if len(s.config.RepoConfig.ExcludePatterns) > 0 {
 		if filter.MatchesAny(relPath, s.config.RepoConfig.ExcludePatterns, s.config.RepoConfig.CaseSensitive) {
-			s.logger.Printf("Directory %s matches exclude pattern", relPath)
+			s.logger.Debug("directory matches exclude pattern", "path", relPath)
 			return false
 		}
 	}
@@ -441,11 +703,18 @@ func (s *Service) shouldWatchDirectory(path string) bool {
 
 // Helper functions
 
+// isCriticalError reports whether the watcher ran out of resources and can
+// no longer be relied on to see changes
 func isCriticalError(err error) bool {
-	// TODO: Add logic to determine if an error is critical
-	// For example, permission errors or watcher resource exhaustion
-	fmt.Println("isCriticalError", err)
-	return false // Placeholder implementation
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// watcherError explains why the platform's file watcher could not start
+func watcherError(err error) error {
+	if errors.Is(err, syscall.EMFILE) {
+		return fmt.Errorf("failed to start file watcher: too many inotify instances; raise the limit with: sudo sysctl fs.inotify.max_user_instances=1024, or regenerate on a timer with --interval and --no-events: %w", err)
+	}
+	return fmt.Errorf("failed to start file watcher (regenerate on a timer with --interval and --no-events instead): %w", err)
 }
 
 func isTemporaryFile(path string) bool {
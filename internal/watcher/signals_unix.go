@@ -0,0 +1,15 @@
+//go:build !windows
+
+package watcher
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignal reloads the configuration and regenerateSignal forces a
+// regeneration
+var (
+	reloadSignal     os.Signal = syscall.SIGHUP
+	regenerateSignal os.Signal = syscall.SIGUSR1
+)
@@ -0,0 +1,12 @@
+//go:build windows
+
+package watcher
+
+import "os"
+
+// Windows has no SIGHUP or SIGUSR1 to deliver, so the watcher only
+// reacts to file changes there
+var (
+	reloadSignal     os.Signal
+	regenerateSignal os.Signal
+)
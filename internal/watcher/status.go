@@ -0,0 +1,96 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/manifest"
+)
+
+// Status describes a running watcher and its last regeneration. It is
+// written to .sink/watch-status.json after every regeneration.
+type Status struct {
+	PID       int       `json:"pid"`
+	Root      string    `json:"root"`
+	StartedAt time.Time `json:"started_at"`
+	// Time and duration of the last regeneration, successful or not
+	LastRun    time.Time `json:"last_run"`
+	DurationMs int64     `json:"duration_ms"`
+	// Files, tokens and outputs of the last successful regeneration
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Files       int        `json:"files"`
+	Tokens      int        `json:"tokens"`
+	Outputs     []string   `json:"outputs"`
+	// Regenerations counts every run and Failures the failed ones
+	Regenerations int        `json:"regenerations"`
+	Failures      int        `json:"failures"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+// StatusPath returns where a watcher of root writes its status
+func StatusPath(root string) string {
+	return filepath.Join(root, manifest.Dir, "watch-status.json")
+}
+
+// ReadStatus reads the status written by a watcher of root
+func ReadStatus(root string) (*Status, error) {
+	data, err := os.ReadFile(StatusPath(root))
+	if err != nil {
+		return nil, err
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StatusPath(root), err)
+	}
+	return &status, nil
+}
+
+// updateStatus records a regeneration started at start and writes the
+// status file. Callers hold genMu.
+func (s *Service) updateStatus(start time.Time, docs []generator.Document, tokens int, err error) {
+	now := time.Now()
+	st := &s.status
+	st.LastRun = start
+	st.DurationMs = now.Sub(start).Milliseconds()
+	st.Regenerations++
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+		st.LastErrorAt = &now
+	} else {
+		st.LastSuccess = &now
+		st.Files = 0
+		for _, doc := range docs {
+			st.Files = max(st.Files, doc.Files)
+		}
+		st.Tokens = max(tokens, 0)
+		st.Outputs = outputPaths(docs)
+	}
+
+	if err := s.writeStatus(); err != nil {
+		s.logger.Warn("failed to write status file", "error", err)
+	}
+}
+
+// writeStatus replaces the status file, renaming a temporary file into
+// place so readers never see it half written
+func (s *Service) writeStatus() error {
+	path := StatusPath(s.config.RootPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.status, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
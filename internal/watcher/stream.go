@@ -0,0 +1,52 @@
+package watcher
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dwrtz/sink/internal/generator"
+)
+
+// streamEvent is a single NDJSON event written to stdout in streaming mode
+type streamEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Output  string    `json:"output,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	Content string    `json:"content,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// streamDocuments regenerates all output targets and writes each document to
+// stdout as an NDJSON event instead of writing output files
+func (s *Service) streamDocuments() ([]generator.Document, error) {
+	docs, err := generator.Generate(s.config.RepoConfig, s.config.RootPath)
+	if err != nil {
+		s.emit(streamEvent{Event: "error", Time: time.Now(), Error: err.Error()})
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		s.emit(streamEvent{
+			Event:   "document",
+			Time:    time.Now(),
+			Output:  doc.Target.Path,
+			Format:  doc.Target.Format,
+			Content: doc.Content,
+		})
+	}
+	if err := generator.RecordChangelog(docs, s.config.RepoConfig, s.config.RootPath); err != nil {
+		return docs, err
+	}
+	return docs, nil
+}
+
+// emit writes a single event, serializing concurrent regenerations
+func (s *Service) emit(event streamEvent) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	if err := json.NewEncoder(s.stdout).Encode(event); err != nil {
+		s.logger.Error("failed to write stream event", "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package sink_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dwrtz/sink/pkg/sink"
+)
+
+func ExampleProcess() {
+	cfg := sink.DefaultConfig()
+	cfg.FilterPatterns = []string{"*.go"}
+	cfg.NoTests = true
+
+	files, err := sink.Process(sink.Options{Root: ".", Config: cfg})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		fmt.Printf("%s\t%s\t%d tokens\n", file.Path, file.Language, file.Tokens)
+	}
+}
+
+func ExampleGenerate() {
+	cfg := sink.DefaultConfig()
+	cfg.FilterPatterns = []string{"*.go"}
+	cfg.MaxTokens = 50000
+
+	result, err := sink.Generate(sink.Options{Root: ".", Config: cfg}, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d files, %d tokens\n", len(result.Files), result.Tokens)
+	for _, path := range result.Omitted {
+		fmt.Fprintf(os.Stderr, "omitted %s\n", path)
+	}
+}
+
+func ExampleAnalyze() {
+	stats, err := sink.Analyze(sink.Options{Root: "."}, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d files\n", stats.TotalFiles)
+}
@@ -0,0 +1,226 @@
+// Package sink turns a codebase into a single document for LLM prompts.
+// It is the library behind the sink command: the same filtering,
+// processing and formatting, without exec-ing the CLI.
+//
+//	cfg := sink.DefaultConfig()
+//	cfg.FilterPatterns = []string{"*.go"}
+//	result, err := sink.Generate(sink.Options{Root: ".", Config: cfg}, os.Stdout)
+//
+// Config is the CLI's configuration. The filtering, processing, ordering,
+// token encoding and budget, instructions, template and format settings
+// apply, as do Cache and the hooks. Settings about where and how output is
+// delivered do not: Generate renders only the first output target to the
+// writer it is given, so Output, the paths of Outputs, Confirm, Inject,
+// Clipboard and SplitTokens are ignored. So are Changelog and
+// ChangelogDiffs, which need the manifest the CLI keeps across runs; the
+// token report and limit settings ShowPrice, Strict and FailOverTokens,
+// which callers can apply to Result.Tokens; ReportSkipped, SkippedReport,
+// Quiet and the log settings; and NotifyWebhook, Export and Profiles,
+// which LoadConfig applies. WebAssembly plugins are only loaded by the CLI.
+package sink
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/dwrtz/sink/internal/analyzer"
+	"github.com/dwrtz/sink/internal/config"
+	"github.com/dwrtz/sink/internal/generator"
+	"github.com/dwrtz/sink/internal/processor"
+	"github.com/dwrtz/sink/internal/tokens"
+)
+
+// Config holds every setting of sink-config.yaml
+type Config = config.Config
+
+// OutputTarget describes one document rendered from a scan
+type OutputTarget = config.OutputTarget
+
+// Stats are the codebase statistics reported by Analyze
+type Stats = analyzer.Stats
+
+// DefaultConfig returns the settings used without a config file
+func DefaultConfig() *Config {
+	return config.DefaultConfig()
+}
+
+// LoadConfig loads the system, user and local config files like the CLI,
+// then the file at path if set, and applies the named profile if set. As
+// without --allow-hooks, hooks and NotifyWebhook are only taken from the
+// system and user config files; set PreHook and PostHook to run others.
+func LoadConfig(path, profile string) (*Config, error) {
+	return config.LoadConfig(path, profile, false)
+}
+
+// Options selects what Process, Generate and Analyze read
+type Options struct {
+	// Root is the directory to read; defaults to the current directory
+	Root string
+	// Config holds the filtering, processing and formatting settings;
+	// nil uses DefaultConfig
+	Config *Config
+}
+
+// resolve returns the absolute root and a copy of the config that never
+// prompts, prints progress or reads the changelog manifest, so callers'
+// settings are left untouched
+func (o Options) resolve() (string, *Config, error) {
+	root := o.Root
+	if root == "" {
+		root = "."
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve absolute path: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if o.Config != nil {
+		copied := *o.Config
+		cfg = &copied
+	}
+	cfg.Quiet = true
+	cfg.Confirm = false
+	cfg.Changelog = false
+	cfg.ChangelogDiffs = false
+	cfg.ReportSkipped = false
+	cfg.SkippedReport = ""
+	return abs, cfg, nil
+}
+
+// File is a file selected by the filters, after processing
+type File struct {
+	// Path relative to Root, with forward slashes
+	Path     string
+	Language string
+	Size     int64
+	Modified time.Time
+	// Content after redaction, outlining and comment stripping
+	Content string
+	// Tokens in Content, counted with the configured encoding
+	Tokens int
+}
+
+// Process returns the files that would be included, in output order
+func Process(opts Options) ([]File, error) {
+	root, cfg, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := generator.ProcessFiles(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+	return newFiles(infos), nil
+}
+
+func newFiles(infos []processor.FileInfo) []File {
+	files := make([]File, len(infos))
+	for i, info := range infos {
+		files[i] = File{
+			Path:     filepath.ToSlash(info.RelPath),
+			Language: info.Language,
+			Size:     info.Size,
+			Modified: info.Modified,
+			Content:  info.Content,
+			Tokens:   info.Tokens,
+		}
+	}
+	return files
+}
+
+// Result describes a generated document
+type Result struct {
+	// Files included in the document, counting truncated files
+	Files []File
+	// Tokens in the document, counted with the configured encoding
+	Tokens int
+	// Omitted lists the paths, relative to Root, of files dropped to fit
+	// MaxTokens
+	Omitted []string
+	// Truncated lists the files shortened to fit MaxTokens
+	Truncated []string
+}
+
+// Generate renders the first output target of the config and writes it to
+// w. Output paths, the clipboard, splitting and token limits are left to
+// the caller; Result has what is needed to apply them.
+func Generate(opts Options, w io.Writer) (*Result, error) {
+	root, cfg, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Outputs = []OutputTarget{cfg.OutputTargets()[0]}
+	cfg.SplitTokens = 0
+	cfg.Inject = false
+	cfg.Clipboard = false
+
+	docs, err := generator.Generate(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+	doc := docs[0]
+
+	counter, err := tokens.NewCounter(cfg.TokenEncoding)
+	if err != nil {
+		return nil, err
+	}
+	count, err := counter.Count(doc.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+
+	result := &Result{Files: newFiles(doc.Included), Tokens: count}
+	for _, omission := range doc.Omitted {
+		path := omission.Path
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		if omission.Truncated {
+			result.Truncated = append(result.Truncated, path)
+		} else {
+			result.Omitted = append(result.Omitted, path)
+		}
+	}
+
+	if _, err := io.WriteString(w, doc.Content); err != nil {
+		return nil, fmt.Errorf("failed to write document: %w", err)
+	}
+	return result, nil
+}
+
+// Analyze computes codebase statistics and, if w is not nil, writes the
+// report printed by sink analyze: a directory tree with file counts per
+// extension, the extension list and the language breakdown
+func Analyze(opts Options, w io.Writer) (*Stats, error) {
+	root, cfg, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := generator.ProcessFiles(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	a := analyzer.New()
+	stats, err := a.Analyze(infos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze codebase: %w", err)
+	}
+	if cfg.ShowTokens {
+		for _, info := range infos {
+			a.AddTokens(stats, info.Path, info.Tokens)
+		}
+	}
+
+	if w != nil {
+		fmt.Fprintln(w, a.FormatTree(stats, root))
+		fmt.Fprintf(w, "\nExtensions: %s\n", a.GetExtensionList(stats))
+		if languages := a.FormatLanguages(stats); languages != "" {
+			fmt.Fprintf(w, "\n%s\n", languages)
+		}
+	}
+	return stats, nil
+}